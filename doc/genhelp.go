@@ -120,6 +120,11 @@ func gendoc(cmd *cobra.Command) string {
 	err = t.Execute(rendered, cmd)
 	checkError(err)
 
+	// Generate entries for any subcommands directly after the parent command
+	for _, subcmd := range cmd.Commands() {
+		rendered.WriteString(gendoc(subcmd))
+	}
+
 	return rendered.String()
 }
 
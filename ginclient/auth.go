@@ -0,0 +1,397 @@
+package ginclient
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/G-Node/gin-cli/ginclient/config"
+	"github.com/G-Node/gin-cli/ginclient/log"
+	"github.com/G-Node/gin-cli/git"
+	"github.com/G-Node/gin-cli/web"
+	gogs "github.com/gogits/go-gogs-client"
+)
+
+// Functions for inspecting and managing the stored login credentials and for validating login tokens.
+
+// TokenLocation describes a credential store that holds a login token.
+type TokenLocation struct {
+	Store    string `json:"store"`
+	Location string `json:"location"`
+	// Encrypted is false for tokens stored in plain files.
+	Encrypted bool `json:"encrypted"`
+}
+
+// CredentialInfo describes where the credentials for a server and profile are stored.
+type CredentialInfo struct {
+	Profile string          `json:"profile"`
+	Server  string          `json:"server"`
+	Tokens  []TokenLocation `json:"tokens"`
+	// Path of the private key file. Empty if there is no key.
+	KeyFile string `json:"keyfile,omitempty"`
+}
+
+// CredentialsStatus returns the stored credentials of all profiles and servers, sorted by profile and server.
+// Servers without credentials are omitted.
+func CredentialsStatus() ([]CredentialInfo, error) {
+	profiles, err := config.ListProfiles()
+	if err != nil {
+		return nil, err
+	}
+	var aliases []string
+	for alias := range config.Read().Servers {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+
+	var infos []CredentialInfo
+	for _, profile := range profiles {
+		profpath, err := config.ProfilePath(profile, false)
+		if err != nil {
+			return nil, err
+		}
+		for _, alias := range aliases {
+			info := CredentialInfo{Profile: profile, Server: alias, Tokens: []TokenLocation{}}
+			for _, store := range web.CredentialStores() {
+				if store.Available() != nil || !store.Has(profile, alias) {
+					continue
+				}
+				info.Tokens = append(info.Tokens, TokenLocation{Store: store.Name(), Location: store.Location(profile, alias), Encrypted: store.Name() != web.FileStore})
+			}
+			keyfile := filepath.Join(profpath, fmt.Sprintf("%s.key", alias))
+			if pathExists(keyfile) {
+				info.KeyFile = keyfile
+			}
+			if len(info.Tokens) > 0 || info.KeyFile != "" {
+				infos = append(infos, info)
+			}
+		}
+	}
+	return infos, nil
+}
+
+// MigrateCredentials moves the tokens of all profiles and servers from other credential stores to the selected store.
+// It returns the credentials that were moved, with the locations they were moved from.
+func MigrateCredentials() ([]CredentialInfo, error) {
+	to, err := web.SelectedCredentialStore()
+	if err != nil {
+		return nil, err
+	}
+	infos, err := CredentialsStatus()
+	if err != nil {
+		return nil, err
+	}
+	var migrated []CredentialInfo
+	for _, info := range infos {
+		var moved []TokenLocation
+		for _, tokenloc := range info.Tokens {
+			from, err := web.GetCredentialStore(tokenloc.Store)
+			if err != nil {
+				return migrated, err
+			}
+			ok, err := web.MigrateToken(info.Profile, info.Server, from, to)
+			if err != nil {
+				return migrated, fmt.Errorf("failed to migrate token for %s (profile %s) from %s: %s", info.Server, info.Profile, tokenloc.Store, err)
+			}
+			if ok {
+				moved = append(moved, tokenloc)
+			}
+		}
+		if len(moved) > 0 {
+			info.Tokens = moved
+			migrated = append(migrated, info)
+		}
+	}
+	return migrated, nil
+}
+
+// GitCredential returns the credentials for a git request over HTTPS to the given protocol and host (with an optional port), as sent to a git credential helper.
+// The server is matched against the configured servers that use the HTTPS transport.
+// The server accepts the login token in place of the username, without checking the password, so a placeholder is returned for the password.
+// It returns an error if no matching server is configured or the user is not logged in.
+func GitCredential(protocol, host string) (string, string, error) {
+	for alias, srvcfg := range config.Read().Servers {
+		if !srvcfg.HTTPSTransport() || srvcfg.Web.Protocol != protocol {
+			continue
+		}
+		if host != srvcfg.Web.Host && host != fmt.Sprintf("%s:%d", srvcfg.Web.Host, srvcfg.Web.Port) {
+			continue
+		}
+		gincl := New(alias)
+		if err := gincl.LoadToken(); err != nil {
+			return "", "", fmt.Errorf("not logged into server '%s': %s", alias, err)
+		}
+		return gincl.Token, "x-token", nil
+	}
+	return "", "", fmt.Errorf("no server with HTTPS transport configured for %s://%s", protocol, host)
+}
+
+// TokenEnvVar is the environment variable that holds a login token for the default server.
+// It allows non-interactive use (e.g., CI jobs) without storing a login.
+const TokenEnvVar = "GIN_TOKEN"
+
+// UsesEnvToken returns true if the client uses the token from the TokenEnvVar environment variable, which applies to the default server only.
+func (gincl *Client) UsesEnvToken() bool {
+	return os.Getenv(TokenEnvVar) != "" && gincl.srvalias != "" && gincl.srvalias == config.Read().DefaultServer
+}
+
+// tokenCheckInterval is how long a successful token validation is trusted before the token is checked with the server again.
+const tokenCheckInterval = 10 * time.Minute
+
+// ErrTokenInvalid is returned by ValidateToken when the server rejects the token (e.g., because it expired or was revoked).
+var ErrTokenInvalid = fmt.Errorf("the login token is not valid: it may have expired or been revoked")
+
+// validated tokens of the current invocation
+var validTokens = make(map[string]bool)
+
+// tokenCheckPath returns the path of the file that records the last successful validation of the token for the server.
+func (gincl *Client) tokenCheckPath() (string, error) {
+	credpath, err := config.CredentialsPath(false)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(credpath, fmt.Sprintf("%s.token.checked", gincl.srvalias)), nil
+}
+
+// tokenHash identifies a token in the validation record without storing the token itself.
+func tokenHash(token string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(token)))
+}
+
+// recentlyValidated returns true if the token was validated successfully within the tokenCheckInterval.
+func (gincl *Client) recentlyValidated() bool {
+	checkpath, err := gincl.tokenCheckPath()
+	if err != nil {
+		return false
+	}
+	data, err := ioutil.ReadFile(checkpath)
+	if err != nil {
+		return false
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) != 2 || fields[0] != tokenHash(gincl.Token) {
+		return false
+	}
+	checked, err := time.Parse(time.RFC3339, fields[1])
+	return err == nil && time.Since(checked) < tokenCheckInterval && time.Since(checked) >= 0
+}
+
+// recordValidation writes the validation record for the token, or removes it if valid is false.
+func (gincl *Client) recordValidation(valid bool) {
+	checkpath, err := gincl.tokenCheckPath()
+	if err != nil {
+		return
+	}
+	if !valid {
+		os.Remove(checkpath)
+		return
+	}
+	record := fmt.Sprintf("%s %s\n", tokenHash(gincl.Token), time.Now().UTC().Format(time.RFC3339))
+	if err := ioutil.WriteFile(checkpath, []byte(record), 0600); err != nil {
+		log.Write("Failed to record token validation: %s", err)
+	}
+}
+
+// ValidateToken checks the token with the server.
+// ErrTokenInvalid is returned if the server rejects it.
+// Successful validations of stored tokens are cached for a few minutes, so that consecutive commands do not check the token again.
+// Tokens without a username (e.g., from the TokenEnvVar environment variable) are always checked once per invocation, and the username is set from the server response.
+func (gincl *Client) ValidateToken() error {
+	return gincl.validateToken(true)
+}
+
+// validateToken implements ValidateToken. If usecache is false, the token is checked with the server even if it was validated recently.
+func (gincl *Client) validateToken(usecache bool) error {
+	if gincl.Token == "" {
+		return fmt.Errorf("not logged in")
+	}
+	cachekey := gincl.srvalias + " " + tokenHash(gincl.Token)
+	if usecache && validTokens[cachekey] {
+		return nil
+	}
+	stored := gincl.Username != ""
+	if usecache && stored && gincl.recentlyValidated() {
+		log.Write("Token validated recently")
+		validTokens[cachekey] = true
+		return nil
+	}
+	log.Write("Validating token with server")
+	user, err := gincl.CurrentUser()
+	if err != nil {
+		if gerr, ok := err.(ginerror); ok && (strings.HasPrefix(gerr.UError, "401") || strings.HasPrefix(gerr.UError, "403")) {
+			if stored {
+				gincl.recordValidation(false)
+			}
+			return ErrTokenInvalid
+		}
+		return err
+	}
+	username := user.Login
+	if username == "" {
+		// older servers
+		username = user.UserName
+	}
+	if stored && username != gincl.Username {
+		log.Write("Token belongs to %s instead of %s", username, gincl.Username)
+		return ErrTokenInvalid
+	}
+	gincl.Username = username
+	validTokens[cachekey] = true
+	if stored {
+		gincl.recordValidation(true)
+	}
+	return nil
+}
+
+// LoginWithToken logs in with an existing token instead of a username and password, so that no new token is created on the server.
+// The token is validated with the server, which provides the username, and stored.
+// If makekey is true, a session key is created for the user, as with Login.
+func (gincl *Client) LoginWithToken(token string, makekey bool) error {
+	gincl.UserToken = web.UserToken{Token: strings.TrimSpace(token)}
+	if err := gincl.ValidateToken(); err != nil {
+		return err
+	}
+	log.Write("Login successful. Username: %s", gincl.Username)
+	if err := gincl.StoreToken(gincl.srvalias); err != nil {
+		return fmt.Errorf("Error while storing token: %s", err.Error())
+	}
+	gincl.recordValidation(true)
+	if !makekey {
+		return nil
+	}
+	return gincl.MakeSessionKey()
+}
+
+// Token states reported in ServerStatus
+const (
+	TokenValid     = "valid"
+	TokenInvalid   = "invalid"
+	TokenUnchecked = "unchecked"
+)
+
+// ServerStatus describes the login of the active profile on a configured server, as reported by the server.
+type ServerStatus struct {
+	Server    string `json:"server"`
+	Address   string `json:"address"`
+	Default   bool   `json:"default"`
+	Transport string `json:"transport"`
+	LoggedIn  bool   `json:"logged_in"`
+	Username  string `json:"username,omitempty"`
+	// TokenSource is the credential store that holds the token, or the TokenEnvVar environment variable.
+	TokenSource string `json:"token_source,omitempty"`
+	// TokenStored is the time of the login. It is nil for tokens from the environment and tokens stored by earlier versions.
+	TokenStored *time.Time `json:"token_stored,omitempty"`
+	TokenStatus string     `json:"token_status,omitempty"`
+	// KeyFile is the path of the session key. KeyFilePresent is false if the file does not exist (e.g., when logged in without a session key).
+	KeyFile        string `json:"key_file"`
+	KeyFilePresent bool   `json:"key_file_present"`
+	// KeyRegistered is true if the session key, or one of the user's own keys, is registered with the user's account. KeyTitle is the title of the registered key.
+	KeyRegistered bool   `json:"key_registered"`
+	KeyTitle      string `json:"key_title,omitempty"`
+	// HostKeyStatus is one of the states returned by git.CheckHostKey, or empty if the server could not be reached or uses the HTTPS transport.
+	HostKeyStatus      string `json:"host_key_status,omitempty"`
+	HostKeyFingerprint string `json:"host_key_fingerprint,omitempty"`
+	// Errors lists the checks that could not be performed.
+	Errors []string `json:"errors,omitempty"`
+}
+
+// GetServerStatus checks the login of the active profile on the server with the given alias.
+// The token is always validated with the server (the validation cache is not used), the registered keys are compared with the session key or the user's own keys, and, for servers that use the SSH transport, the host key of the git server is compared with the configured one.
+func GetServerStatus(alias string) ServerStatus {
+	conf := config.Read()
+	srvcfg := conf.Servers[alias]
+	status := ServerStatus{Server: alias, Address: srvcfg.Web.AddressStr(), Default: alias == conf.DefaultServer, Transport: config.TransportSSH}
+	if srvcfg.HTTPSTransport() {
+		status.Transport = config.TransportHTTPS
+	}
+	adderr := func(check string, err error) {
+		status.Errors = append(status.Errors, fmt.Sprintf("%s: %s", check, err))
+	}
+
+	if !srvcfg.HTTPSTransport() {
+		// the git server is not used with the HTTPS transport
+		if hoststatus, fingerprint, err := git.CheckHostKey(srvcfg.Git); err != nil {
+			adderr("host key", err)
+		} else {
+			status.HostKeyStatus, status.HostKeyFingerprint = hoststatus, fingerprint
+		}
+	}
+
+	gincl := New(alias)
+	if keyfile, err := gincl.sessionKeyPath(false); err == nil {
+		status.KeyFile = keyfile
+		status.KeyFilePresent = pathExists(keyfile)
+	}
+	if gincl.UsesEnvToken() {
+		gincl.UserToken = web.UserToken{Token: os.Getenv(TokenEnvVar)}
+		status.TokenSource = TokenEnvVar
+	} else {
+		profile, _ := config.ActiveProfile()
+		stores := web.TokenStores(profile, alias)
+		if len(stores) == 0 {
+			return status
+		}
+		status.TokenSource = stores[0]
+		if err := gincl.UserToken.LoadToken(alias); err != nil {
+			adderr("token", err)
+			return status
+		}
+		if !gincl.Stored.IsZero() {
+			stored := gincl.Stored
+			status.TokenStored = &stored
+		}
+	}
+	status.LoggedIn = true
+	status.Username = gincl.Username
+
+	err := gincl.validateToken(false)
+	switch {
+	case err == ErrTokenInvalid:
+		status.TokenStatus = TokenInvalid
+		return status
+	case err != nil:
+		status.TokenStatus = TokenUnchecked
+		adderr("token", err)
+		return status
+	}
+	status.TokenStatus = TokenValid
+	status.Username = gincl.Username
+
+	var key gogs.PublicKey
+	if status.KeyFilePresent {
+		key, err = gincl.RegisteredSessionKey()
+	} else if gincl.UserKeysConfigured() {
+		key, err = gincl.FindRegisteredKey()
+	} else {
+		err = fmt.Errorf("no session key and no keys configured for server '%s'", alias)
+	}
+	if err != nil {
+		log.Write("Key check failed: %s", err)
+		if !srvcfg.HTTPSTransport() {
+			adderr("key", err)
+		}
+	} else {
+		status.KeyRegistered = true
+		status.KeyTitle = key.Title
+	}
+	return status
+}
+
+// ServersStatus returns the status of the active profile on all configured servers, sorted by server alias (see GetServerStatus).
+func ServersStatus() []ServerStatus {
+	var aliases []string
+	for alias := range config.Read().Servers {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+	statuses := make([]ServerStatus, 0, len(aliases))
+	for _, alias := range aliases {
+		statuses = append(statuses, GetServerStatus(alias))
+	}
+	return statuses
+}
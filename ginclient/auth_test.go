@@ -0,0 +1,49 @@
+package ginclient
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/G-Node/gin-cli/web"
+)
+
+func TestValidateToken(t *testing.T) {
+	nrequests := 0
+	cleanup := testServer(t, "tokentest", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		nrequests++
+		if r.URL.Path != "/api/v1/user" || r.Header.Get("Authorization") != "token validtoken" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"id": 1, "login": "alice", "username": "alice"}`)
+	}))
+	defer cleanup()
+
+	gincl := New("tokentest")
+	gincl.UserToken = web.UserToken{Username: "alice", Token: "validtoken"}
+	if err := gincl.ValidateToken(); err != nil {
+		t.Fatalf("valid token rejected: %v", err)
+	}
+	if err := gincl.ValidateToken(); err != nil || nrequests != 1 {
+		t.Errorf("token validation not cached: %d requests, %v", nrequests, err)
+	}
+
+	// a new invocation reuses the recorded validation
+	validTokens = make(map[string]bool)
+	if err := gincl.ValidateToken(); err != nil || nrequests != 1 {
+		t.Errorf("recorded token validation not used: %d requests, %v", nrequests, err)
+	}
+
+	// a token without username gets the username from the server
+	gincl.UserToken = web.UserToken{Token: "validtoken"}
+	validTokens = make(map[string]bool)
+	if err := gincl.ValidateToken(); err != nil || gincl.Username != "alice" {
+		t.Errorf("username not set from server: %q, %v", gincl.Username, err)
+	}
+
+	gincl.UserToken = web.UserToken{Username: "alice", Token: "revokedtoken"}
+	if err := gincl.ValidateToken(); err != ErrTokenInvalid {
+		t.Errorf("expected ErrTokenInvalid for rejected token, got %v", err)
+	}
+}
@@ -6,7 +6,11 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
+	"strconv"
 	"testing"
 
 	"github.com/G-Node/gin-cli/ginclient/config"
@@ -37,6 +41,23 @@ func TestMain(m *testing.M) {
 	os.Exit(res)
 }
 
+// testServer starts a fake server with the given handler and configures it as the web server with the given alias.
+// The returned function stops the server and removes the configuration.
+func testServer(t *testing.T, alias string, handler http.Handler) func() {
+	srv := httptest.NewServer(handler)
+	srvurl, _ := url.Parse(srv.URL)
+	port, _ := strconv.Atoi(srvurl.Port())
+	srvcfg := config.ServerCfg{Web: config.WebCfg{Protocol: "http", Host: srvurl.Hostname(), Port: uint16(port)}}
+	if err := config.SetConfig("servers."+alias, srvcfg); err != nil {
+		srv.Close()
+		t.Fatalf("failed to configure test server: %v", err)
+	}
+	return func() {
+		srv.Close()
+		config.RmServerConf(alias)
+	}
+}
+
 // setupLocalRepo sets up a repository in a temporary directory with 'dir' type
 // remote in another temporary directory.
 func setupLocalRepoWithDirRemote(c *Client) (string, error) {
@@ -30,10 +30,11 @@ var (
 			Port:     443,
 		},
 		GitCfg{
-			Host:    "gin.g-node.org",
-			Port:    22,
-			User:    "git",
-			HostKey: "gin.g-node.org,141.84.41.219 ecdsa-sha2-nistp256 AAAAE2VjZHNhLXNoYTItbmlzdHAyNTYAAAAIbmlzdHAyNTYAAABBBE5IBgKP3nUryEFaACwY4N3jlqDx8Qw1xAxU2Xpt5V0p9RNefNnedVmnIBV6lA3n+9kT1OSbyqA/+SgsQ57nHo0=",
+			Host:      "gin.g-node.org",
+			Port:      22,
+			User:      "git",
+			HostKey:   "gin.g-node.org,141.84.41.219 ecdsa-sha2-nistp256 AAAAE2VjZHNhLXNoYTItbmlzdHAyNTYAAAAIbmlzdHAyNTYAAABBBE5IBgKP3nUryEFaACwY4N3jlqDx8Qw1xAxU2Xpt5V0p9RNefNnedVmnIBV6lA3n+9kT1OSbyqA/+SgsQ57nHo0=",
+			Transport: TransportSSH,
 		},
 	}
 
@@ -41,12 +42,18 @@ var (
 		// Binaries
 		"bin.git":          "git",
 		"bin.gitannex":     "git-annex",
-		"gin.gitannexpath": "",
+		"bin.gitannexpath": "",
 		"bin.ssh":          "ssh",
 		// Annex filters
 		"annex.minsize": "10M",
 		"servers.gin":   ginDefaultServer,
 		"defaultserver": "gin",
+		// Credential store
+		"credentials.store": "auto",
+		// Session keys
+		"keys.type":    "ed25519",
+		"keys.bits":    3072,
+		"keys.encrypt": false,
 	}
 
 	// configuration cache: used to avoid rereading during a single command invocation
@@ -75,8 +82,22 @@ type GitCfg struct {
 	Host    string
 	Port    uint16
 	HostKey string
+	// Offer the keys held by the SSH agent in addition to the session key and identity files.
+	UseAgent bool
+	// Private key files (or public key files of keys held by the agent or a hardware token) to offer in addition to the session key.
+	IdentityFiles []string
+	// Transport used for repository remotes: TransportSSH (default) or TransportHTTPS.
+	Transport string
 }
 
+// Git transports
+const (
+	// TransportSSH accesses repositories on the git server with SSH keys.
+	TransportSSH = "ssh"
+	// TransportHTTPS accesses repositories through the web server, authenticating with the login token.
+	TransportHTTPS = "https"
+)
+
 // AddressStr constructs a full address string from the configuration.
 // The string has the format ssh://User@Host:Port (e.g., ssh://git@gin.g-node.org:22)
 func (c GitCfg) AddressStr() string {
@@ -89,6 +110,20 @@ type ServerCfg struct {
 	Git GitCfg
 }
 
+// HTTPSTransport returns true if repositories are accessed through the web server instead of SSH.
+func (c ServerCfg) HTTPSTransport() bool {
+	return c.Git.Transport == TransportHTTPS
+}
+
+// RemoteAddressStr returns the address that repository paths are appended to for constructing remote URLs.
+// This is the git server address for the SSH transport (e.g., ssh://git@gin.g-node.org:22) and the web server address for the HTTPS transport (e.g., https://gin.g-node.org:443).
+func (c ServerCfg) RemoteAddressStr() string {
+	if c.HTTPSTransport() {
+		return c.Web.AddressStr()
+	}
+	return c.Git.AddressStr()
+}
+
 // BinCfg holds the paths to the external binaries that the client depends on.
 type BinCfg struct {
 	Git          string
@@ -103,15 +138,34 @@ type AnnexCfg struct {
 	MinSize string
 }
 
+// CredentialsCfg holds the configuration for storing login credentials.
+type CredentialsCfg struct {
+	// Name of the credential store: auto, keyring, encrypted-file, or file.
+	Store string
+}
+
+// KeysCfg holds the configuration for the session keys created on login.
+type KeysCfg struct {
+	// Key type: ed25519 or rsa.
+	Type string
+	// Key size in bits (RSA keys only).
+	Bits int
+	// Encrypt private keys with a passphrase.
+	Encrypt bool
+}
+
 // GinCliCfg holds the client configuration values.
 type GinCliCfg struct {
 	Servers       map[string]ServerCfg
 	DefaultServer string
 	Bin           BinCfg
 	Annex         AnnexCfg
+	Credentials   CredentialsCfg
+	Keys          KeysCfg
 }
 
 // Read loads in the configuration from the config file(s), merges any defined values into the default configuration, and returns a populated GinConfiguration struct.
+// Values are applied in increasing order of precedence: defaults, user config file, repository config file (annex settings only), GIN_* environment variables, and command line flags (see SetFlagOverride).
 // The configuration is cached. Subsequent reads reuse the already loaded configuration.
 func Read() GinCliCfg {
 	if set {
@@ -120,7 +174,9 @@ func Read() GinCliCfg {
 	viper.Reset()
 	viper.SetTypeByDefaultValue(true)
 
-	for k, v := range defaultConf {
+	// defaults are set per key, so that the keys of the default server that are not in the user file keep their default values
+	// instead of the whole server being replaced by the keys in the file
+	for k, v := range defaultSettings() {
 		viper.SetDefault(k, v)
 	}
 
@@ -150,6 +206,9 @@ func Read() GinCliCfg {
 	configuration.Annex.Exclude = viper.GetStringSlice("annex.exclude")
 	configuration.Annex.MinSize = viper.GetString("annex.minsize")
 
+	// environment variables and command line flags
+	applyOverrides(&configuration)
+
 	// if Bin.GitAnnex is set but Bin.GitAnnexPath is not, set the path
 	if configuration.Bin.GitAnnexPath == "" && configuration.Bin.GitAnnex != "" {
 		path, _ := filepath.Split(configuration.Bin.GitAnnex)
@@ -233,10 +292,13 @@ func SetDefaultServer(alias string) {
 }
 
 // Path returns the configuration path where configuration files should be stored.
-// If the GIN_CONFIG_DIR environment variable is set, its value is returned, otherwise the platform default is used.
+// If a directory was set with SetConfigDir, it is returned. Otherwise, if the GIN_CONFIG_DIR environment variable is set, its value is returned, otherwise the platform default is used.
 // If create is true and the directory does not exist, the full path is created.
 func Path(create bool) (string, error) {
-	confpath := os.Getenv("GIN_CONFIG_DIR")
+	confpath := configDir
+	if confpath == "" {
+		confpath = os.Getenv("GIN_CONFIG_DIR")
+	}
 	if confpath == "" {
 		confpath = configDirs.QueryFolders(configdir.Global)[0].Path
 	}
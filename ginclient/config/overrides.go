@@ -0,0 +1,224 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// Functions for overriding configuration values with environment variables and command line flags.
+// Overrides are not written to any file and only apply to the current invocation.
+
+// Scope values for overridden configuration values.
+const (
+	// EnvScope is used for values set through GIN_* environment variables.
+	EnvScope = "env"
+	// FlagScope is used for values set through command line flags.
+	FlagScope = "flag"
+)
+
+const envPrefix = "GIN_"
+
+// otherEnvVars lists the environment variables with the GIN_ prefix that do not correspond to configuration keys.
+var otherEnvVars = map[string]bool{
+	"GIN_CONFIG_DIR": true,
+	"GIN_LOG_DIR":    true,
+	"GIN_PROFILE":    true,
+	// passphrase for the encrypted credential store
+	"GIN_CREDENTIAL_PASSPHRASE": true,
+	// passphrase for encrypted session keys
+	"GIN_KEY_PASSPHRASE": true,
+	// login token for non-interactive use
+	"GIN_TOKEN": true,
+}
+
+var (
+	// configuration directory set with SetConfigDir; takes precedence over GIN_CONFIG_DIR
+	configDir string
+	// values set with SetFlagOverride, by key
+	flagOverrides = make(map[string]Override)
+)
+
+// Override is a configuration value set through an environment variable or a command line flag.
+type Override struct {
+	Key   string
+	Value string
+	// EnvScope or FlagScope.
+	Scope string
+	// The name of the environment variable or command line flag.
+	Source string
+}
+
+// EnvName returns the name of the environment variable that overrides a configuration key (e.g., GIN_ANNEX_MINSIZE for annex.minsize).
+func EnvName(key string) string {
+	return envPrefix + strings.ToUpper(strings.Replace(key, ".", "_", -1))
+}
+
+// envKey returns the configuration key for an environment variable name.
+// The second return value is false if the variable does not correspond to a known key.
+// Server aliases may contain underscores: the last two parts of a server variable are the section and field names.
+func envKey(name string) (string, bool) {
+	if !strings.HasPrefix(name, envPrefix) || otherEnvVars[name] {
+		return "", false
+	}
+	parts := strings.Split(strings.ToLower(strings.TrimPrefix(name, envPrefix)), "_")
+	var key string
+	if nparts := len(parts); parts[0] == "servers" && nparts >= 4 {
+		alias := strings.Join(parts[1:nparts-2], "_")
+		key = strings.Join([]string{"servers", alias, parts[nparts-2], parts[nparts-1]}, ".")
+	} else {
+		key = strings.Join(parts, ".")
+	}
+	if _, err := KeyType(key); err != nil {
+		return "", false
+	}
+	return key, true
+}
+
+// envOverrides returns the configuration values set through environment variables, sorted by key.
+func envOverrides() []Override {
+	var overrides []Override
+	for _, env := range os.Environ() {
+		split := strings.SplitN(env, "=", 2)
+		if len(split) != 2 {
+			continue
+		}
+		if key, ok := envKey(split[0]); ok {
+			overrides = append(overrides, Override{Key: key, Value: split[1], Scope: EnvScope, Source: split[0]})
+		}
+	}
+	sort.Slice(overrides, func(i, j int) bool { return overrides[i].Key < overrides[j].Key })
+	return overrides
+}
+
+// unknownEnvVars returns the names of environment variables with the GIN_ prefix that do not correspond to configuration keys.
+func unknownEnvVars() []string {
+	var names []string
+	for _, env := range os.Environ() {
+		name := strings.SplitN(env, "=", 2)[0]
+		if !strings.HasPrefix(name, envPrefix) || otherEnvVars[name] {
+			continue
+		}
+		if _, ok := envKey(name); !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Overrides returns all overridden configuration values in order of increasing precedence: environment variables first, then command line flags.
+func Overrides() []Override {
+	overrides := envOverrides()
+	keys := make([]string, 0, len(flagOverrides))
+	for key := range flagOverrides {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		overrides = append(overrides, flagOverrides[key])
+	}
+	return overrides
+}
+
+// SetFlagOverride sets the value of a configuration key for the current invocation, taking precedence over all other sources.
+// The flag name is used to report where the value comes from.
+func SetFlagOverride(key, value, flag string) error {
+	key = strings.ToLower(key)
+	if _, err := ParseValue(key, value); err != nil {
+		return err
+	}
+	flagOverrides[key] = Override{Key: key, Value: value, Scope: FlagScope, Source: flag}
+	// invalidate the read cache
+	set = false
+	return nil
+}
+
+// SetConfigDir sets the directory of the user configuration file for the current invocation, taking precedence over the GIN_CONFIG_DIR environment variable.
+func SetConfigDir(dir string) {
+	configDir = dir
+	set = false
+}
+
+// applyOverrides sets the overridden values in the configuration.
+// Invalid values are ignored with a warning.
+func applyOverrides(conf *GinCliCfg) {
+	for _, o := range Overrides() {
+		value, err := parseValue(o.Key, o.Value)
+		if err != nil {
+			fmt.Fprintf(color.Error, "%s %s (%s): value ignored\n", yellow("[warning]"), err, o.Source)
+			continue
+		}
+		setField(conf, o.Key, value)
+	}
+	if _, ok := conf.Servers[conf.DefaultServer]; !ok {
+		for _, o := range Overrides() {
+			if o.Key == "defaultserver" {
+				fmt.Fprintf(color.Error, "%s unknown server '%s' (%s): using 'gin'\n", yellow("[warning]"), o.Value, o.Source)
+				conf.DefaultServer = "gin"
+			}
+		}
+	}
+}
+
+// setField sets the configuration field for a key to a value parsed by parseValue.
+func setField(conf *GinCliCfg, key string, value interface{}) {
+	switch key {
+	case "bin.git":
+		conf.Bin.Git = value.(string)
+	case "bin.gitannex":
+		conf.Bin.GitAnnex = value.(string)
+	case "bin.gitannexpath":
+		conf.Bin.GitAnnexPath = value.(string)
+	case "bin.ssh":
+		conf.Bin.SSH = value.(string)
+	case "annex.minsize":
+		conf.Annex.MinSize = value.(string)
+	case "annex.exclude":
+		conf.Annex.Exclude = value.([]string)
+	case "defaultserver":
+		conf.DefaultServer = value.(string)
+	case "credentials.store":
+		conf.Credentials.Store = value.(string)
+	case "keys.type":
+		conf.Keys.Type = value.(string)
+	case "keys.bits":
+		conf.Keys.Bits = value.(int)
+	case "keys.encrypt":
+		conf.Keys.Encrypt = value.(bool)
+	default:
+		// servers.<alias>.<section>.<field>
+		parts := strings.Split(key, ".")
+		alias := parts[1]
+		if conf.Servers == nil {
+			conf.Servers = make(map[string]ServerCfg)
+		}
+		srvcfg := conf.Servers[alias]
+		switch parts[2] + "." + parts[3] {
+		case "web.protocol":
+			srvcfg.Web.Protocol = value.(string)
+		case "web.host":
+			srvcfg.Web.Host = value.(string)
+		case "web.port":
+			srvcfg.Web.Port = value.(uint16)
+		case "git.user":
+			srvcfg.Git.User = value.(string)
+		case "git.host":
+			srvcfg.Git.Host = value.(string)
+		case "git.port":
+			srvcfg.Git.Port = value.(uint16)
+		case "git.hostkey":
+			srvcfg.Git.HostKey = value.(string)
+		case "git.transport":
+			srvcfg.Git.Transport = value.(string)
+		case "git.useagent":
+			srvcfg.Git.UseAgent = value.(bool)
+		case "git.identityfiles":
+			srvcfg.Git.IdentityFiles = value.([]string)
+		}
+		conf.Servers[alias] = srvcfg
+	}
+}
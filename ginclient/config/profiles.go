@@ -0,0 +1,124 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+)
+
+// Functions for selecting the profile that determines which credentials (tokens and keys) are used.
+// Each profile holds at most one login per server, so multiple profiles allow a single machine to use different identities on the same server.
+// The credentials of the default profile are stored in the configuration directory; those of named profiles in the profiles/<name> subdirectory.
+
+// DefaultProfile is the name of the profile that is used when no other profile is selected.
+const DefaultProfile = "default"
+
+const profilesDirName = "profiles"
+
+var profileNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+var (
+	// profile set with SetFlagProfile
+	flagProfile string
+	// profile bound to the current repository, set with SetRepoProfile
+	repoProfile string
+)
+
+// CheckProfileName returns an error if the name is not a valid profile name.
+func CheckProfileName(name string) error {
+	if !profileNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid profile name '%s': must start with a letter or digit and contain only letters, digits, '.', '_', and '-'", name)
+	}
+	return nil
+}
+
+// SetFlagProfile selects the profile for the current invocation, taking precedence over the GIN_PROFILE environment variable and the repository binding.
+func SetFlagProfile(name string) error {
+	if err := CheckProfileName(name); err != nil {
+		return err
+	}
+	flagProfile = name
+	return nil
+}
+
+// SetRepoProfile sets the profile bound to the current repository.
+// It is used when neither the --profile flag nor the GIN_PROFILE environment variable are set.
+// An empty name removes the binding.
+func SetRepoProfile(name string) error {
+	if name == "" {
+		repoProfile = ""
+		return nil
+	}
+	if err := CheckProfileName(name); err != nil {
+		return err
+	}
+	repoProfile = name
+	return nil
+}
+
+// ActiveProfile returns the name of the selected profile and where the selection comes from: the --profile flag, the GIN_PROFILE environment variable, the repository binding, or the default.
+func ActiveProfile() (string, string) {
+	if flagProfile != "" {
+		return flagProfile, "--profile"
+	}
+	if envprofile := os.Getenv("GIN_PROFILE"); envprofile != "" {
+		if CheckProfileName(envprofile) == nil {
+			return envprofile, "GIN_PROFILE"
+		}
+	}
+	if repoProfile != "" {
+		return repoProfile, "repository"
+	}
+	return DefaultProfile, DefaultScope
+}
+
+// ProfilePath returns the directory where the credentials of a profile are stored.
+// If create is true and the directory does not exist, the full path is created.
+func ProfilePath(name string, create bool) (string, error) {
+	confpath, err := Path(create)
+	if err != nil {
+		return "", err
+	}
+	if name == DefaultProfile {
+		return confpath, nil
+	}
+	profpath := filepath.Join(confpath, profilesDirName, name)
+	if create {
+		if err = os.MkdirAll(profpath, 0700); err != nil {
+			return "", fmt.Errorf("could not create profile directory %s", profpath)
+		}
+	}
+	return profpath, nil
+}
+
+// CredentialsPath returns the directory where the credentials of the active profile are stored.
+// If create is true and the directory does not exist, the full path is created.
+func CredentialsPath(create bool) (string, error) {
+	name, _ := ActiveProfile()
+	return ProfilePath(name, create)
+}
+
+// ListProfiles returns the names of the default profile and all named profiles that have been created, sorted by name.
+func ListProfiles() ([]string, error) {
+	confpath, err := Path(false)
+	if err != nil {
+		return nil, err
+	}
+	profiles := []string{DefaultProfile}
+	entries, err := ioutil.ReadDir(filepath.Join(confpath, profilesDirName))
+	if os.IsNotExist(err) {
+		return profiles, nil
+	} else if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() && entry.Name() != DefaultProfile && CheckProfileName(entry.Name()) == nil {
+			profiles = append(profiles, entry.Name())
+		}
+	}
+	sort.Strings(profiles[1:])
+	return profiles, nil
+}
@@ -0,0 +1,536 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v2"
+)
+
+// Functions for reading, writing, and checking individual configuration keys.
+
+// ValueType describes how the value of a configuration key is parsed and checked.
+type ValueType string
+
+// Configuration value types
+const (
+	StringValue ValueType = "string"
+	ListValue   ValueType = "list"
+	SizeValue   ValueType = "size"
+	PortValue   ValueType = "port"
+	PathValue   ValueType = "path"
+	IntValue    ValueType = "int"
+	BoolValue   ValueType = "bool"
+	// PathListValue is a comma separated list of file paths.
+	PathListValue ValueType = "pathlist"
+)
+
+// Scope values identify the configuration files.
+const (
+	// GlobalScope is the user configuration file.
+	GlobalScope = "global"
+	// LocalScope is the configuration file in the root of the current repository, which can only hold annex settings.
+	LocalScope = "local"
+	// DefaultScope is used for values that are not set in any file.
+	DefaultScope = "default"
+)
+
+// keyTypes lists the known configuration keys.
+// Server keys are matched with any server alias in place of '*'.
+var keyTypes = map[string]ValueType{
+	"bin.git":                     PathValue,
+	"bin.gitannex":                PathValue,
+	"bin.gitannexpath":            PathValue,
+	"bin.ssh":                     PathValue,
+	"annex.minsize":               SizeValue,
+	"annex.exclude":               ListValue,
+	"defaultserver":               StringValue,
+	"credentials.store":           StringValue,
+	"keys.type":                   StringValue,
+	"keys.bits":                   IntValue,
+	"keys.encrypt":                BoolValue,
+	"servers.*.web.protocol":      StringValue,
+	"servers.*.web.host":          StringValue,
+	"servers.*.web.port":          PortValue,
+	"servers.*.git.user":          StringValue,
+	"servers.*.git.host":          StringValue,
+	"servers.*.git.port":          PortValue,
+	"servers.*.git.hostkey":       StringValue,
+	"servers.*.git.transport":     StringValue,
+	"servers.*.git.useagent":      BoolValue,
+	"servers.*.git.identityfiles": PathListValue,
+}
+
+var sizePattern = regexp.MustCompile(`(?i)^[0-9]+(\.[0-9]+)?\s*([kmgtp]i?)?b?$`)
+
+// KeyType returns the type of the value of a configuration key.
+// Keys are case insensitive. An error is returned for unknown keys.
+func KeyType(key string) (ValueType, error) {
+	key = strings.ToLower(key)
+	if vt, ok := keyTypes[key]; ok {
+		return vt, nil
+	}
+	parts := strings.Split(key, ".")
+	if len(parts) == 4 && parts[0] == "servers" {
+		if parts[1] == "dir" {
+			return "", fmt.Errorf("server alias 'dir' is not allowed (reserved word)")
+		}
+		if vt, ok := keyTypes[strings.Join([]string{"servers", "*", parts[2], parts[3]}, ".")]; ok {
+			return vt, nil
+		}
+	}
+	return "", fmt.Errorf("unknown configuration key '%s'", key)
+}
+
+// keyScopeAllowed returns true if the key can be set in the given scope.
+func keyScopeAllowed(key, scope string) bool {
+	return scope != LocalScope || strings.HasPrefix(strings.ToLower(key), "annex.")
+}
+
+// ParseValue parses and checks a value for a configuration key and returns it with the type that is written to the configuration file.
+func ParseValue(key, value string) (interface{}, error) {
+	parsed, err := parseValue(key, value)
+	if err != nil {
+		return nil, err
+	}
+	if strings.ToLower(key) == "defaultserver" {
+		if _, ok := Read().Servers[value]; !ok {
+			return nil, fmt.Errorf("unknown server '%s' for %s", value, key)
+		}
+	}
+	return parsed, nil
+}
+
+// parseValue parses and checks a value without reading the configuration.
+// Whether the default server exists is not checked.
+func parseValue(key, value string) (interface{}, error) {
+	vt, err := KeyType(key)
+	if err != nil {
+		return nil, err
+	}
+	key = strings.ToLower(key)
+	switch vt {
+	case SizeValue:
+		value = strings.TrimSpace(value)
+		if !sizePattern.MatchString(value) {
+			return nil, fmt.Errorf("invalid size '%s' for %s: expected a number with an optional unit (e.g., 10M, 500kB, 1G)", value, key)
+		}
+		return value, nil
+	case PortValue:
+		port, err := strconv.ParseUint(strings.TrimSpace(value), 10, 16)
+		if err != nil || port == 0 {
+			return nil, fmt.Errorf("invalid port '%s' for %s: expected a number between 1 and 65535", value, key)
+		}
+		return uint16(port), nil
+	case PathValue:
+		return parsePath(key, value)
+	case IntValue:
+		num, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid number '%s' for %s", value, key)
+		}
+		if key == "keys.bits" && (num < 2048 || num > 16384) {
+			return nil, fmt.Errorf("invalid key size %d for %s: expected a number between 2048 and 16384", num, key)
+		}
+		return num, nil
+	case BoolValue:
+		b, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid value '%s' for %s: expected true or false", value, key)
+		}
+		return b, nil
+	case ListValue:
+		var list []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		return list, nil
+	case PathListValue:
+		var list []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			path, err := ExpandHome(item)
+			if err != nil {
+				return nil, err
+			}
+			if path, err = filepath.Abs(path); err != nil {
+				return nil, err
+			}
+			if !pathExists(path) {
+				return nil, fmt.Errorf("invalid path for %s: '%s' does not exist", key, path)
+			}
+			list = append(list, path)
+		}
+		return list, nil
+	}
+	// string values
+	if strings.HasSuffix(key, ".web.protocol") && value != "http" && value != "https" {
+		return nil, fmt.Errorf("invalid protocol '%s' for %s: expected http or https", value, key)
+	}
+	if key == "credentials.store" {
+		switch value {
+		case "auto", "keyring", "encrypted-file", "file":
+		default:
+			return nil, fmt.Errorf("invalid credential store '%s' for %s: expected auto, keyring, encrypted-file, or file", value, key)
+		}
+	}
+	if strings.HasSuffix(key, ".git.transport") && value != TransportSSH && value != TransportHTTPS {
+		return nil, fmt.Errorf("invalid transport '%s' for %s: expected ssh or https", value, key)
+	}
+	if key == "keys.type" && value != "ed25519" && value != "rsa" {
+		return nil, fmt.Errorf("invalid key type '%s' for %s: expected ed25519 or rsa", value, key)
+	}
+	return value, nil
+}
+
+// ExpandHome replaces a leading '~' in a path with the home directory of the user.
+func ExpandHome(path string) (string, error) {
+	if !strings.HasPrefix(path, "~") {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~")), nil
+}
+
+// parsePath expands a leading '~' and checks that the path exists.
+// Executables given by name only are looked up in the system PATH.
+func parsePath(key, value string) (string, error) {
+	value, err := ExpandHome(strings.TrimSpace(value))
+	if err != nil || value == "" {
+		return value, err
+	}
+	if !strings.ContainsRune(value, filepath.Separator) && !strings.ContainsRune(value, '/') {
+		if _, err := exec.LookPath(value); err != nil {
+			return "", fmt.Errorf("invalid path for %s: '%s' not found in PATH", key, value)
+		}
+		return value, nil
+	}
+	value, err = filepath.Abs(value)
+	if err != nil {
+		return "", err
+	}
+	if !pathExists(value) {
+		return "", fmt.Errorf("invalid path for %s: '%s' does not exist", key, value)
+	}
+	return value, nil
+}
+
+// ScopeFile returns the path of the configuration file for a scope.
+func ScopeFile(scope string) (string, error) {
+	switch scope {
+	case GlobalScope:
+		confpath, err := Path(false)
+		return filepath.Join(confpath, defaultFileName), err
+	case LocalScope:
+		reporoot, err := findreporoot(".")
+		if err != nil {
+			return "", fmt.Errorf("local configuration is only available inside a repository")
+		}
+		return filepath.Join(reporoot, defaultFileName), nil
+	}
+	return "", fmt.Errorf("unknown configuration scope '%s'", scope)
+}
+
+// readScope reads the configuration file of a scope on its own.
+// A missing file results in an empty configuration.
+func readScope(scope string) (*viper.Viper, string, error) {
+	fname, err := ScopeFile(scope)
+	if err != nil {
+		return nil, "", err
+	}
+	v := viper.New()
+	v.SetConfigFile(fname)
+	if pathExists(fname) {
+		if err := v.ReadInConfig(); err != nil {
+			return nil, fname, fmt.Errorf("failed to read configuration file %s: %s", fname, err)
+		}
+	}
+	return v, fname, nil
+}
+
+// writeSettings writes the nested settings map to a configuration file.
+func writeSettings(fname string, settings map[string]interface{}) error {
+	data, err := yaml.Marshal(settings)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(fname), 0755); err != nil {
+		return fmt.Errorf("could not create config directory %s", filepath.Dir(fname))
+	}
+	return ioutil.WriteFile(fname, data, 0644)
+}
+
+// SetValue parses the value and writes it for the key in the configuration file of the scope.
+func SetValue(key, value, scope string) error {
+	key = strings.ToLower(key)
+	if !keyScopeAllowed(key, scope) {
+		return fmt.Errorf("'%s' cannot be set in the local configuration: only annex settings are read from repositories", key)
+	}
+	parsed, err := ParseValue(key, value)
+	if err != nil {
+		return err
+	}
+	v, fname, err := readScope(scope)
+	if err != nil {
+		return err
+	}
+	settings := v.AllSettings()
+	setNested(settings, strings.Split(key, "."), parsed)
+	if err = writeSettings(fname, settings); err != nil {
+		return err
+	}
+	// invalidate the read cache
+	set = false
+	return nil
+}
+
+// UnsetValue removes the key from the configuration file of the scope.
+// An error is returned if the key is not set in the file.
+func UnsetValue(key, scope string) error {
+	key = strings.ToLower(key)
+	v, fname, err := readScope(scope)
+	if err != nil {
+		return err
+	}
+	if !v.IsSet(key) {
+		return fmt.Errorf("'%s' is not set in %s", key, fname)
+	}
+	settings := v.AllSettings()
+	deleteNested(settings, strings.Split(key, "."))
+	if err = writeSettings(fname, settings); err != nil {
+		return err
+	}
+	set = false
+	return nil
+}
+
+func setNested(settings map[string]interface{}, keyparts []string, value interface{}) {
+	if len(keyparts) == 1 {
+		settings[keyparts[0]] = value
+		return
+	}
+	sub, ok := settings[keyparts[0]].(map[string]interface{})
+	if !ok {
+		sub = make(map[string]interface{})
+		settings[keyparts[0]] = sub
+	}
+	setNested(sub, keyparts[1:], value)
+}
+
+func deleteNested(settings map[string]interface{}, keyparts []string) {
+	if len(keyparts) == 1 {
+		delete(settings, keyparts[0])
+		return
+	}
+	sub, ok := settings[keyparts[0]].(map[string]interface{})
+	if !ok {
+		return
+	}
+	deleteNested(sub, keyparts[1:])
+	if len(sub) == 0 {
+		// remove empty sections
+		delete(settings, keyparts[0])
+	}
+}
+
+// Setting is a configuration value and the place it was read from.
+type Setting struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	// GlobalScope, LocalScope, EnvScope, FlagScope, or DefaultScope.
+	Scope string `json:"scope"`
+	// The file, environment variable, or command line flag the value was read from. Empty for default values.
+	Source string `json:"source,omitempty"`
+}
+
+// formatValue returns the string representation of a configuration value.
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case []interface{}:
+		items := make([]string, len(v))
+		for idx, item := range v {
+			items[idx] = fmt.Sprint(item)
+		}
+		return strings.Join(items, ",")
+	case []string:
+		return strings.Join(v, ",")
+	case nil:
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+// defaultSettings returns the flattened default configuration.
+func defaultSettings() map[string]interface{} {
+	// the default server is a struct: flatten it through its keys
+	flat := make(map[string]interface{})
+	for k, val := range defaultConf {
+		if k == "servers.gin" {
+			continue
+		}
+		flat[k] = val
+	}
+	srv := ginDefaultServer
+	flat["servers.gin.web.protocol"] = srv.Web.Protocol
+	flat["servers.gin.web.host"] = srv.Web.Host
+	flat["servers.gin.web.port"] = srv.Web.Port
+	flat["servers.gin.git.user"] = srv.Git.User
+	flat["servers.gin.git.host"] = srv.Git.Host
+	flat["servers.gin.git.port"] = srv.Git.Port
+	flat["servers.gin.git.hostkey"] = srv.Git.HostKey
+	flat["servers.gin.git.transport"] = srv.Git.Transport
+	flat["servers.gin.git.useagent"] = srv.Git.UseAgent
+	flat["servers.gin.git.identityfiles"] = srv.Git.IdentityFiles
+	return flat
+}
+
+// ListSettings returns the configuration values of a file scope, sorted by key.
+// With an empty scope, the effective values are returned along with the scope each value comes from.
+// Values are taken from, in increasing order of precedence, the defaults, the global file, the local file, environment variables, and command line flags.
+func ListSettings(scope string) ([]Setting, error) {
+	if scope != "" {
+		v, fname, err := readScope(scope)
+		if err != nil {
+			return nil, err
+		}
+		var settings []Setting
+		for _, key := range v.AllKeys() {
+			settings = append(settings, Setting{Key: key, Value: formatValue(v.Get(key)), Scope: scope, Source: fname})
+		}
+		sort.Slice(settings, func(i, j int) bool { return settings[i].Key < settings[j].Key })
+		return settings, nil
+	}
+
+	effective := make(map[string]Setting)
+	for key, val := range defaultSettings() {
+		effective[key] = Setting{Key: key, Value: formatValue(val), Scope: DefaultScope}
+	}
+	for _, sc := range []string{GlobalScope, LocalScope} {
+		v, fname, err := readScope(sc)
+		if err != nil {
+			if sc == LocalScope && fname == "" {
+				// not in a repository
+				continue
+			}
+			return nil, err
+		}
+		for _, key := range v.AllKeys() {
+			if !keyScopeAllowed(key, sc) {
+				// ignored when reading the configuration
+				continue
+			}
+			effective[key] = Setting{Key: key, Value: formatValue(v.Get(key)), Scope: sc, Source: fname}
+		}
+	}
+	// environment variables, then command line flags
+	for _, o := range Overrides() {
+		if _, err := parseValue(o.Key, o.Value); err != nil {
+			// invalid overrides are ignored when reading the configuration
+			continue
+		}
+		effective[o.Key] = Setting{Key: o.Key, Value: o.Value, Scope: o.Scope, Source: o.Source}
+	}
+	settings := make([]Setting, 0, len(effective))
+	for _, s := range effective {
+		settings = append(settings, s)
+	}
+	sort.Slice(settings, func(i, j int) bool { return settings[i].Key < settings[j].Key })
+	return settings, nil
+}
+
+// GetSetting returns the value of a key in a scope, or its effective value if the scope is empty.
+// The second return value is false if the key is not set.
+func GetSetting(key, scope string) (Setting, bool, error) {
+	key = strings.ToLower(key)
+	if _, err := KeyType(key); err != nil {
+		return Setting{}, false, err
+	}
+	settings, err := ListSettings(scope)
+	if err != nil {
+		return Setting{}, false, err
+	}
+	for _, s := range settings {
+		if s.Key == key {
+			return s, true, nil
+		}
+	}
+	return Setting{}, false, nil
+}
+
+// Problem describes an invalid entry in a configuration file or an invalid override.
+type Problem struct {
+	Key string `json:"key"`
+	// The file, environment variable, or command line flag that holds the entry.
+	Source  string `json:"source"`
+	Message string `json:"message"`
+}
+
+// Check reads the global and local configuration files and the overrides and reports unknown keys, invalid values, and keys that are not allowed in a scope.
+func Check() ([]Problem, error) {
+	var problems []Problem
+	for _, sc := range []string{GlobalScope, LocalScope} {
+		v, fname, err := readScope(sc)
+		if err != nil {
+			if sc == LocalScope && fname == "" {
+				// not in a repository
+				continue
+			}
+			problems = append(problems, Problem{Source: fname, Message: err.Error()})
+			continue
+		}
+		keys := v.AllKeys()
+		sort.Strings(keys)
+		for _, key := range keys {
+			if _, err := KeyType(key); err != nil {
+				problems = append(problems, Problem{Key: key, Source: fname, Message: err.Error()})
+				continue
+			}
+			if !keyScopeAllowed(key, sc) {
+				problems = append(problems, Problem{Key: key, Source: fname, Message: "only annex settings are read from the repository configuration; the key is ignored"})
+				continue
+			}
+			if key == "defaultserver" {
+				// checked against the configured servers below
+				continue
+			}
+			if _, err := ParseValue(key, formatValue(v.Get(key))); err != nil {
+				problems = append(problems, Problem{Key: key, Source: fname, Message: err.Error()})
+			}
+		}
+		if sc == GlobalScope && v.IsSet("defaultserver") {
+			alias := v.GetString("defaultserver")
+			if _, ok := v.GetStringMap("servers")[alias]; !ok && alias != "gin" {
+				problems = append(problems, Problem{Key: "defaultserver", Source: fname, Message: fmt.Sprintf("unknown server '%s'", alias)})
+			}
+		}
+	}
+	if envprofile := os.Getenv("GIN_PROFILE"); envprofile != "" {
+		if err := CheckProfileName(envprofile); err != nil {
+			problems = append(problems, Problem{Source: "GIN_PROFILE", Message: err.Error() + "; ignored"})
+		}
+	}
+	for _, name := range unknownEnvVars() {
+		problems = append(problems, Problem{Source: name, Message: "environment variable does not match a configuration key; ignored"})
+	}
+	for _, o := range Overrides() {
+		if _, err := ParseValue(o.Key, o.Value); err != nil {
+			problems = append(problems, Problem{Key: o.Key, Source: o.Source, Message: err.Error()})
+		}
+	}
+	return problems, nil
+}
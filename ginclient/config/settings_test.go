@@ -0,0 +1,180 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestKeyType(t *testing.T) {
+	valid := map[string]ValueType{
+		"annex.minsize":             SizeValue,
+		"Annex.Exclude":             ListValue,
+		"servers.myserver.web.port": PortValue,
+		"servers.gin.git.hostkey":   StringValue,
+		"bin.ssh":                   PathValue,
+	}
+	for key, expected := range valid {
+		vt, err := KeyType(key)
+		if err != nil {
+			t.Errorf("unexpected error for key %q: %v", key, err)
+		} else if vt != expected {
+			t.Errorf("wrong type for key %q: expected %s, got %s", key, expected, vt)
+		}
+	}
+	for _, key := range []string{"annex.minsze", "servers.gin.web", "servers.dir.web.port", "servers.gin.web.user", "bin"} {
+		if _, err := KeyType(key); err == nil {
+			t.Errorf("expected error for key %q", key)
+		}
+	}
+}
+
+func TestParseValue(t *testing.T) {
+	for _, size := range []string{"10M", "500kB", "1.5G", "100", "2MiB"} {
+		if _, err := ParseValue("annex.minsize", size); err != nil {
+			t.Errorf("unexpected error for size %q: %v", size, err)
+		}
+	}
+	for _, size := range []string{"", "M", "ten", "10Q", "-1M"} {
+		if _, err := ParseValue("annex.minsize", size); err == nil {
+			t.Errorf("expected error for size %q", size)
+		}
+	}
+
+	port, err := ParseValue("servers.gin.web.port", "8443")
+	if err != nil || port != uint16(8443) {
+		t.Errorf("failed to parse port: %v, %v", port, err)
+	}
+	for _, p := range []string{"0", "65536", "-1", "http"} {
+		if _, err := ParseValue("servers.gin.git.port", p); err == nil {
+			t.Errorf("expected error for port %q", p)
+		}
+	}
+
+	if _, err := ParseValue("servers.gin.web.protocol", "ftp"); err == nil {
+		t.Error("expected error for protocol ftp")
+	}
+
+	list, err := ParseValue("annex.exclude", "*.py, *.md,,")
+	if err != nil {
+		t.Fatalf("failed to parse list: %v", err)
+	}
+	if l := list.([]string); len(l) != 2 || l[0] != "*.py" || l[1] != "*.md" {
+		t.Errorf("wrong list value: %v", l)
+	}
+
+	if _, err := ParseValue("bin.ssh", "/nonexistent/path/to/ssh"); err == nil {
+		t.Error("expected error for nonexistent path")
+	}
+
+	keyfile, err := ioutil.TempFile("", "gin-test-key-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyfile.Close()
+	defer os.Remove(keyfile.Name())
+	paths, err := ParseValue("servers.gin.git.identityfiles", keyfile.Name()+", ")
+	if err != nil {
+		t.Fatalf("failed to parse path list: %v", err)
+	}
+	if l := paths.([]string); len(l) != 1 || l[0] != keyfile.Name() {
+		t.Errorf("wrong path list value: %v", l)
+	}
+	if _, err := ParseValue("servers.gin.git.identityfiles", keyfile.Name()+",/nonexistent/key"); err == nil {
+		t.Error("expected error for nonexistent identity file")
+	}
+	if _, err := ParseValue("servers.gin.git.useagent", "maybe"); err == nil {
+		t.Error("expected error for invalid bool")
+	}
+}
+
+func TestEnvKey(t *testing.T) {
+	valid := map[string]string{
+		"GIN_ANNEX_MINSIZE":            "annex.minsize",
+		"GIN_BIN_GITANNEXPATH":         "bin.gitannexpath",
+		"GIN_DEFAULTSERVER":            "defaultserver",
+		"GIN_SERVERS_TEST_WEB_HOST":    "servers.test.web.host",
+		"GIN_SERVERS_MY_TEST_GIT_PORT": "servers.my_test.git.port",
+	}
+	for name, expected := range valid {
+		if key, ok := envKey(name); !ok || key != expected {
+			t.Errorf("wrong key for %s: expected %q, got %q", name, expected, key)
+		}
+		if EnvName(expected) != name {
+			t.Errorf("wrong variable name for %s: expected %q, got %q", expected, name, EnvName(expected))
+		}
+	}
+	for _, name := range []string{"GIN_CONFIG_DIR", "GIN_LOG_DIR", "GIN_ANEX_MINSIZE", "GIN_SERVERS_WEB_HOST", "ANNEX_MINSIZE"} {
+		if key, ok := envKey(name); ok {
+			t.Errorf("unexpected key %q for %s", key, name)
+		}
+	}
+}
+
+func TestApplyOverrides(t *testing.T) {
+	os.Setenv("GIN_ANNEX_MINSIZE", "1G")
+	os.Setenv("GIN_SERVERS_GIN_WEB_PORT", "notaport")
+	defer os.Unsetenv("GIN_ANNEX_MINSIZE")
+	defer os.Unsetenv("GIN_SERVERS_GIN_WEB_PORT")
+	conf := GinCliCfg{Servers: map[string]ServerCfg{"gin": ginDefaultServer}, DefaultServer: "gin"}
+	conf.Annex.MinSize = "10M"
+	applyOverrides(&conf)
+	if conf.Annex.MinSize != "1G" {
+		t.Errorf("annex.minsize not overridden: %s", conf.Annex.MinSize)
+	}
+	if conf.Servers["gin"].Web.Port != ginDefaultServer.Web.Port {
+		t.Errorf("invalid port override applied: %d", conf.Servers["gin"].Web.Port)
+	}
+
+	flagOverrides["annex.minsize"] = Override{Key: "annex.minsize", Value: "2G", Scope: FlagScope, Source: "--test"}
+	defer delete(flagOverrides, "annex.minsize")
+	applyOverrides(&conf)
+	if conf.Annex.MinSize != "2G" {
+		t.Errorf("flag override does not take precedence over environment: %s", conf.Annex.MinSize)
+	}
+}
+
+func TestActiveProfile(t *testing.T) {
+	defer func() { flagProfile, repoProfile = "", "" }()
+	os.Unsetenv("GIN_PROFILE")
+	if name, _ := ActiveProfile(); name != DefaultProfile {
+		t.Errorf("expected default profile, got %q", name)
+	}
+	SetRepoProfile("lab")
+	if name, source := ActiveProfile(); name != "lab" || source != "repository" {
+		t.Errorf("expected repository profile, got %q (%s)", name, source)
+	}
+	os.Setenv("GIN_PROFILE", "bot")
+	defer os.Unsetenv("GIN_PROFILE")
+	if name, source := ActiveProfile(); name != "bot" || source != "GIN_PROFILE" {
+		t.Errorf("expected environment profile, got %q (%s)", name, source)
+	}
+	SetFlagProfile("personal")
+	if name, source := ActiveProfile(); name != "personal" || source != "--profile" {
+		t.Errorf("expected flag profile, got %q (%s)", name, source)
+	}
+	if err := SetFlagProfile("../escape"); err == nil {
+		t.Error("expected error for invalid profile name")
+	}
+}
+
+func TestReadPartialDefaultServer(t *testing.T) {
+	confdir, err := ioutil.TempDir("", "gin-config-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(confdir)
+	SetConfigDir(confdir)
+	defer SetConfigDir("")
+
+	if err = SetConfig("servers.gin.git.useagent", true); err != nil {
+		t.Fatalf("failed to set configuration value: %v", err)
+	}
+	srvcfg := Read().Servers["gin"]
+	if !srvcfg.Git.UseAgent {
+		t.Errorf("value from the configuration file not applied")
+	}
+	if srvcfg.Git.Host != ginDefaultServer.Git.Host || srvcfg.Git.Port != ginDefaultServer.Git.Port || srvcfg.Web != ginDefaultServer.Web {
+		t.Errorf("default server not completed from the defaults: %+v", srvcfg)
+	}
+}
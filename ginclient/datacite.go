@@ -0,0 +1,282 @@
+package ginclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/G-Node/gin-cli/ginclient/log"
+	"github.com/G-Node/gin-cli/web"
+	"gopkg.in/yaml.v2"
+)
+
+// Functions for creating and checking the DataCite metadata file (datacite.yml) used for DOI registration and for requesting DOIs from the server.
+
+// DataCiteFileName is the name of the metadata file that the server reads when registering a DOI for a repository.
+const DataCiteFileName = "datacite.yml"
+
+// DataCiteAuthor is an entry in the author list of a DataCite file.
+type DataCiteAuthor struct {
+	FirstName   string `yaml:"firstname"`
+	LastName    string `yaml:"lastname"`
+	Affiliation string `yaml:"affiliation,omitempty"`
+	ID          string `yaml:"id,omitempty"`
+}
+
+// DataCiteLicense is the license section of a DataCite file.
+type DataCiteLicense struct {
+	Name string `yaml:"name"`
+	URL  string `yaml:"url"`
+}
+
+// DataCiteReference is an entry in the reference list of a DataCite file.
+type DataCiteReference struct {
+	ID       string `yaml:"id,omitempty"`
+	RefType  string `yaml:"reftype"`
+	Citation string `yaml:"citation"`
+}
+
+// DataCite holds the contents of a DataCite file.
+type DataCite struct {
+	Authors         []DataCiteAuthor    `yaml:"authors"`
+	Title           string              `yaml:"title"`
+	Description     string              `yaml:"description"`
+	Keywords        []string            `yaml:"keywords"`
+	License         *DataCiteLicense    `yaml:"license"`
+	Funding         []string            `yaml:"funding,omitempty"`
+	References      []DataCiteReference `yaml:"references,omitempty"`
+	ResourceType    string              `yaml:"resourcetype"`
+	TemplateVersion string              `yaml:"templateversion"`
+}
+
+// dataciteSchema holds the bundled rules that a DataCite file is checked against.
+// The allowed values follow the DataCite Metadata Schema 4.1 as used by the GIN DOI service.
+var dataciteSchema = struct {
+	templateVersions []string
+	resourceTypes    []string
+	refTypes         []string
+	authorIDPrefixes []string
+	refIDPrefixes    []string
+}{
+	templateVersions: []string{"1.0", "1.1", "1.2"},
+	resourceTypes: []string{
+		"Audiovisual", "Collection", "DataPaper", "Dataset", "Event", "Image", "InteractiveResource",
+		"Model", "PhysicalObject", "Service", "Software", "Sound", "Text", "Workflow", "Other",
+	},
+	refTypes: []string{
+		"IsCitedBy", "Cites", "IsSupplementTo", "IsSupplementedBy", "IsContinuedBy", "Continues",
+		"IsDescribedBy", "Describes", "HasMetadata", "IsMetadataFor", "HasVersion", "IsVersionOf",
+		"IsNewVersionOf", "IsPreviousVersionOf", "IsPartOf", "HasPart", "IsReferencedBy", "References",
+		"IsDocumentedBy", "Documents", "IsCompiledBy", "Compiles", "IsVariantFormOf", "IsOriginalFormOf",
+		"IsIdenticalTo", "IsReviewedBy", "Reviews", "IsDerivedFrom", "IsSourceOf", "IsRequiredBy",
+		"Requires", "IsObsoletedBy", "Obsoletes",
+	},
+	authorIDPrefixes: []string{"orcid", "researcherid"},
+	refIDPrefixes:    []string{"doi", "arxiv", "pmid", "url"},
+}
+
+var orcidPattern = regexp.MustCompile(`^[0-9]{4}-[0-9]{4}-[0-9]{4}-[0-9]{3}[0-9X]$`)
+
+// NewDataCite returns a DataCite structure with the fields that do not depend on the repository filled with defaults.
+func NewDataCite() DataCite {
+	return DataCite{
+		License: &DataCiteLicense{
+			Name: "Creative Commons Attribution 4.0 International Public License",
+			URL:  "https://creativecommons.org/licenses/by/4.0/",
+		},
+		ResourceType:    "Dataset",
+		TemplateVersion: "1.2",
+	}
+}
+
+// Marshal returns the YAML encoding of the DataCite structure, preceded by a comment header.
+func (dc DataCite) Marshal() ([]byte, error) {
+	b, err := yaml.Marshal(dc)
+	if err != nil {
+		return nil, err
+	}
+	header := "# Metadata for DOI registration according to DataCite Metadata Schema 4.1.\n# For detailed field descriptions see https://gin.g-node.org/G-Node/Info/wiki/DOIfile\n\n"
+	return append([]byte(header), b...), nil
+}
+
+func inList(value string, list []string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// validORCID checks the format and the check digit (ISO 7064 11,2) of an ORCID identifier.
+func validORCID(id string) bool {
+	if !orcidPattern.MatchString(id) {
+		return false
+	}
+	digits := strings.Replace(id, "-", "", -1)
+	total := 0
+	for _, d := range digits[:15] {
+		total = (total + int(d-'0')) * 2
+	}
+	check := (12 - total%11) % 11
+	expected := "X"
+	if check < 10 {
+		expected = string('0' + rune(check))
+	}
+	return digits[15:] == expected
+}
+
+// splitPrefix splits an identifier of the form "prefix:value" and returns the lowercase prefix and the value.
+func splitPrefix(id string) (string, string) {
+	parts := strings.SplitN(id, ":", 2)
+	if len(parts) != 2 {
+		return "", id
+	}
+	return strings.ToLower(strings.TrimSpace(parts[0])), strings.TrimSpace(parts[1])
+}
+
+func validURL(str string) bool {
+	u, err := url.Parse(str)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// ValidateDataCite checks the contents of a DataCite file against the bundled schema.
+// It returns a list of the problems found. An empty list means the file is valid.
+func ValidateDataCite(data []byte) []string {
+	var problems []string
+	addProblem := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	var dc DataCite
+	if err := yaml.UnmarshalStrict(data, &dc); err != nil {
+		// Report unknown fields and syntax errors and stop
+		return []string{fmt.Sprintf("could not parse file: %s", err)}
+	}
+
+	if len(dc.Authors) == 0 {
+		addProblem("no authors specified")
+	}
+	for idx, author := range dc.Authors {
+		n := idx + 1
+		if strings.TrimSpace(author.LastName) == "" {
+			addProblem("author %d: last name is missing", n)
+		}
+		if strings.TrimSpace(author.FirstName) == "" {
+			addProblem("author %d: first name is missing", n)
+		}
+		if author.ID == "" {
+			continue
+		}
+		prefix, value := splitPrefix(author.ID)
+		switch {
+		case !inList(prefix, dataciteSchema.authorIDPrefixes):
+			addProblem("author %d: identifier '%s' must start with one of %s", n, author.ID, strings.Join(dataciteSchema.authorIDPrefixes, ", "))
+		case prefix == "orcid" && !validORCID(value):
+			addProblem("author %d: '%s' is not a valid ORCID", n, value)
+		}
+	}
+
+	if strings.TrimSpace(dc.Title) == "" {
+		addProblem("title is missing")
+	}
+	if strings.TrimSpace(dc.Description) == "" {
+		addProblem("description is missing")
+	}
+	if len(dc.Keywords) == 0 {
+		addProblem("no keywords specified")
+	}
+	for idx, kw := range dc.Keywords {
+		if strings.TrimSpace(kw) == "" {
+			addProblem("keyword %d is empty", idx+1)
+		}
+	}
+
+	if dc.License == nil {
+		addProblem("license is missing")
+	} else {
+		if strings.TrimSpace(dc.License.Name) == "" {
+			addProblem("license name is missing")
+		}
+		if !validURL(dc.License.URL) {
+			addProblem("license URL '%s' is not a valid web address", dc.License.URL)
+		}
+	}
+
+	for idx, ref := range dc.References {
+		n := idx + 1
+		if strings.TrimSpace(ref.Citation) == "" {
+			addProblem("reference %d: citation is missing", n)
+		}
+		if !inList(ref.RefType, dataciteSchema.refTypes) {
+			addProblem("reference %d: unknown reference type '%s'", n, ref.RefType)
+		}
+		if ref.ID == "" {
+			continue
+		}
+		prefix, value := splitPrefix(ref.ID)
+		switch {
+		case !inList(prefix, dataciteSchema.refIDPrefixes):
+			addProblem("reference %d: identifier '%s' must start with one of %s", n, ref.ID, strings.Join(dataciteSchema.refIDPrefixes, ", "))
+		case prefix == "url" && !validURL(value):
+			addProblem("reference %d: '%s' is not a valid web address", n, value)
+		}
+	}
+
+	if !inList(dc.ResourceType, dataciteSchema.resourceTypes) {
+		addProblem("unknown resource type '%s'", dc.ResourceType)
+	}
+	if !inList(dc.TemplateVersion, dataciteSchema.templateVersions) {
+		addProblem("unsupported template version '%s'", dc.TemplateVersion)
+	}
+
+	return problems
+}
+
+// DOIRequestStatus describes the state of a DOI request for a repository, as reported by the server.
+type DOIRequestStatus struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	DOI     string `json:"doi"`
+	URL     string `json:"url"`
+}
+
+// RequestDOI asks the server to register a DOI for the current state of a repository and returns the status of the request.
+// The repository must contain a valid DataCite file on the server and the logged in user must have administrative access to it.
+func (gincl *Client) RequestDOI(repoPath string) (DOIRequestStatus, error) {
+	fn := fmt.Sprintf("RequestDOI(%s)", repoPath)
+	log.Write("Requesting DOI")
+	var status DOIRequestStatus
+	res, err := gincl.Post(fmt.Sprintf("/api/v1/repos/%s/doi", repoPath), nil)
+	if err != nil {
+		return status, err // return error from Post() directly
+	}
+	switch code := res.StatusCode; {
+	case code == http.StatusForbidden:
+		return status, ginerror{UError: res.Status, Origin: fn, Description: fmt.Sprintf("you do not have permission to request a DOI for '%s'", repoPath)}
+	case code == http.StatusNotFound:
+		return status, ginerror{UError: res.Status, Origin: fn, Description: fmt.Sprintf("repository '%s' does not exist or the server does not support DOI requests", repoPath)}
+	case code == http.StatusUnprocessableEntity:
+		return status, ginerror{UError: res.Status, Origin: fn, Description: fmt.Sprintf("the %s file of the repository on the server is missing or invalid", DataCiteFileName)}
+	case code == http.StatusUnauthorized:
+		return status, ginerror{UError: res.Status, Origin: fn, Description: "authorisation failed"}
+	case code == http.StatusInternalServerError:
+		return status, ginerror{UError: res.Status, Origin: fn, Description: "server error"}
+	case code != http.StatusOK && code != http.StatusCreated && code != http.StatusAccepted:
+		return status, ginerror{UError: res.Status, Origin: fn} // Unexpected error
+	}
+	defer web.CloseRes(res.Body)
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return status, ginerror{UError: err.Error(), Origin: fn, Description: "failed to read response body"}
+	}
+	err = json.Unmarshal(b, &status)
+	if err != nil {
+		return status, ginerror{UError: err.Error(), Origin: fn, Description: "failed to parse response body"}
+	}
+	return status, nil
+}
@@ -0,0 +1,68 @@
+package ginclient
+
+import (
+	"strings"
+	"testing"
+)
+
+const validDataCite = `authors:
+  -
+    firstname: "Alice"
+    lastname: "Example"
+    affiliation: "G-Node"
+    id: "ORCID:0000-0002-1825-0097"
+title: "Example dataset"
+description: |
+  Recordings used in the example study.
+keywords:
+  - Neuroscience
+license:
+  name: "Creative Commons CC0 1.0 Public Domain Dedication"
+  url: "https://creativecommons.org/publicdomain/zero/1.0/"
+references:
+  -
+    id: "doi:10.1234/example"
+    reftype: "IsSupplementTo"
+    citation: "Example et al. (2019) An example study"
+resourcetype: Dataset
+templateversion: 1.2
+`
+
+func TestValidateDataCite(t *testing.T) {
+	if problems := ValidateDataCite([]byte(validDataCite)); len(problems) != 0 {
+		t.Fatalf("Valid file reported as invalid: %v", problems)
+	}
+
+	invalid := map[string]string{
+		"bad ORCID":          strings.Replace(validDataCite, "0000-0002-1825-0097", "0000-0002-1825-0098", 1),
+		"unknown reftype":    strings.Replace(validDataCite, "IsSupplementTo", "IsSupplement", 1),
+		"unknown field":      validDataCite + "subjects: []\n",
+		"missing title":      strings.Replace(validDataCite, `title: "Example dataset"`, "", 1),
+		"bad license URL":    strings.Replace(validDataCite, "https://creativecommons.org/publicdomain/zero/1.0/", "creativecommons", 1),
+		"unknown resource":   strings.Replace(validDataCite, "resourcetype: Dataset", "resourcetype: Data", 1),
+		"bad reference id":   strings.Replace(validDataCite, "doi:10.1234/example", "isbn:123", 1),
+		"no keywords":        strings.Replace(validDataCite, "  - Neuroscience\n", "", 1),
+		"unknown template":   strings.Replace(validDataCite, "templateversion: 1.2", "templateversion: 0.9", 1),
+		"missing first name": strings.Replace(validDataCite, `firstname: "Alice"`, "", 1),
+	}
+	for name, content := range invalid {
+		if problems := ValidateDataCite([]byte(content)); len(problems) == 0 {
+			t.Errorf("%s: invalid file reported as valid", name)
+		}
+	}
+}
+
+func TestNewDataCiteMarshal(t *testing.T) {
+	dc := NewDataCite()
+	dc.Title = "Example dataset"
+	dc.Description = "Recordings"
+	dc.Keywords = []string{"Neuroscience"}
+	dc.Authors = []DataCiteAuthor{{FirstName: "Alice", LastName: "Example"}}
+	b, err := dc.Marshal()
+	if err != nil {
+		t.Fatalf("Failed to marshal DataCite structure: %v", err)
+	}
+	if problems := ValidateDataCite(b); len(problems) != 0 {
+		t.Fatalf("Generated file is invalid: %v", problems)
+	}
+}
@@ -0,0 +1,273 @@
+package ginclient
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/G-Node/gin-cli/git"
+)
+
+// Functions for exporting a snapshot of the repository to an archive file.
+
+// ManifestFileName is the name of the checksum manifest added to the root of exported archives.
+const ManifestFileName = "manifest-sha256.txt"
+
+// ArchiveFormats lists the supported archive formats for exporting.
+var ArchiveFormats = []string{"zip", "tar.gz"}
+
+// archiveWriter adds entries to an archive file.
+type archiveWriter interface {
+	addFile(name string, mode os.FileMode, size int64, r io.Reader) error
+	addLink(name, target string) error
+	Close() error
+}
+
+type zipArchive struct {
+	zw      *zip.Writer
+	modtime time.Time
+}
+
+func (za *zipArchive) addFile(name string, mode os.FileMode, size int64, r io.Reader) error {
+	hdr := &zip.FileHeader{Name: name, Method: zip.Deflate}
+	hdr.SetModTime(za.modtime)
+	hdr.SetMode(mode)
+	w, err := za.zw.CreateHeader(hdr)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, r)
+	return err
+}
+
+func (za *zipArchive) addLink(name, target string) error {
+	return za.addFile(name, os.ModeSymlink|0777, int64(len(target)), strings.NewReader(target))
+}
+
+func (za *zipArchive) Close() error {
+	return za.zw.Close()
+}
+
+type tarArchive struct {
+	gzw     *gzip.Writer
+	tw      *tar.Writer
+	modtime time.Time
+}
+
+func (ta *tarArchive) addFile(name string, mode os.FileMode, size int64, r io.Reader) error {
+	hdr := &tar.Header{Name: name, Mode: int64(mode.Perm()), Size: size, ModTime: ta.modtime, Typeflag: tar.TypeReg}
+	if err := ta.tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := io.Copy(ta.tw, r)
+	return err
+}
+
+func (ta *tarArchive) addLink(name, target string) error {
+	hdr := &tar.Header{Name: name, Mode: 0777, ModTime: ta.modtime, Typeflag: tar.TypeSymlink, Linkname: target}
+	return ta.tw.WriteHeader(hdr)
+}
+
+func (ta *tarArchive) Close() error {
+	if err := ta.tw.Close(); err != nil {
+		return err
+	}
+	return ta.gzw.Close()
+}
+
+func newArchiveWriter(format string, out io.Writer, modtime time.Time) (archiveWriter, error) {
+	switch format {
+	case "zip":
+		return &zipArchive{zw: zip.NewWriter(out), modtime: modtime}, nil
+	case "tar.gz":
+		gzw := gzip.NewWriter(out)
+		return &tarArchive{gzw: gzw, tw: tar.NewWriter(gzw), modtime: modtime}, nil
+	default:
+		return nil, fmt.Errorf("unknown archive format '%s'", format)
+	}
+}
+
+// ExportArchive writes the files specified by paths, as they were at the given revision, to an archive in the given format.
+// All entries are placed under the directory prefix in the archive.
+// Only files for which the filter function returns true are exported. A nil filter exports all files.
+// Annexed content that is not available locally is downloaded when it is needed.
+// A checksum manifest (ManifestFileName) of all exported files is added to the archive.
+// The status of each file is sent on the expchan channel, which is closed when the function returns.
+func ExportArchive(revision string, paths []string, filter func(string) bool, format, prefix string, out io.Writer, expchan chan<- FileCheckoutStatus) {
+	defer close(expchan)
+	commit, err := resolveRevision(revision)
+	if err != nil {
+		expchan <- FileCheckoutStatus{Err: err}
+		return
+	}
+	archive, err := newArchiveWriter(format, out, commit.Date)
+	if err != nil {
+		expchan <- FileCheckoutStatus{Err: err}
+		return
+	}
+
+	files := writeRevision(commit.Hash, paths, filter, "sha256", archive, prefix, expchan)
+	var manifest bytes.Buffer
+	for _, f := range files {
+		fmt.Fprintf(&manifest, "%s  %s\n", f.Checksum, f.Name)
+	}
+	mstatus := FileCheckoutStatus{Filename: ManifestFileName, Type: "Manifest", Destination: path.Join(prefix, ManifestFileName)}
+	mstatus.Err = archive.addFile(mstatus.Destination, 0644, int64(manifest.Len()), &manifest)
+	expchan <- mstatus
+	if err = archive.Close(); err != nil {
+		expchan <- FileCheckoutStatus{Err: fmt.Errorf("failed to finalise archive: %s", err)}
+	}
+}
+
+// exportedFile records the path, size, and checksum of a file written by writeRevision.
+type exportedFile struct {
+	Name     string
+	Size     int64
+	Checksum string
+}
+
+// resolveRevision returns the commit that the revision (ID, tag, or branch) refers to.
+func resolveRevision(revision string) (git.GinCommit, error) {
+	commits, err := git.Log(1, revision, nil, true)
+	if err != nil {
+		return git.GinCommit{}, err
+	}
+	if len(commits) == 0 {
+		return git.GinCommit{}, fmt.Errorf("'%s' does not match a known version ID or name", revision)
+	}
+	return commits[0], nil
+}
+
+// newHash returns a new hash for the named checksum algorithm ("sha256" or "md5").
+func newHash(algorithm string) (hash.Hash, error) {
+	switch algorithm {
+	case "sha256":
+		return sha256.New(), nil
+	case "md5":
+		return md5.New(), nil
+	}
+	return nil, fmt.Errorf("unsupported checksum algorithm '%s'", algorithm)
+}
+
+// keyChecksum returns the checksum stored in an annex key if the key backend uses the given algorithm.
+// Keys have the form BACKEND-sSIZE--HASH[.EXT] (e.g., SHA256E-s1024--<hash>.dat).
+func keyChecksum(key, algorithm string) (string, bool) {
+	parts := strings.SplitN(key, "--", 2)
+	if len(parts) != 2 {
+		return "", false
+	}
+	backend := strings.SplitN(parts[0], "-", 2)[0]
+	var hashlen int
+	switch {
+	case algorithm == "sha256" && (backend == "SHA256" || backend == "SHA256E"):
+		hashlen = 64
+	case algorithm == "md5" && (backend == "MD5" || backend == "MD5E"):
+		hashlen = 32
+	default:
+		return "", false
+	}
+	if len(parts[1]) < hashlen {
+		return "", false
+	}
+	return strings.ToLower(parts[1][:hashlen]), true
+}
+
+// writeRevision writes the files specified by paths, as they were at the given commit, to the archive under the directory prefix.
+// Checksums are computed with the given algorithm while writing, except for annexed files whose key already contains the checksum.
+// The status of each file is sent on the expchan channel. The channel is not closed.
+// The list of written files (excluding symlinks) with their names relative to the prefix is returned.
+func writeRevision(commithash string, paths []string, filter func(string) bool, algorithm string, archive archiveWriter, prefix string, expchan chan<- FileCheckoutStatus) []exportedFile {
+	objects, err := git.LsTree(commithash, paths)
+	if err != nil {
+		expchan <- FileCheckoutStatus{Err: err}
+		return nil
+	}
+
+	var files []exportedFile
+	for _, obj := range objects {
+		if obj.Type != "blob" || (filter != nil && !filter(obj.Name)) {
+			continue
+		}
+		status := FileCheckoutStatus{Filename: obj.Name, Destination: path.Join(prefix, obj.Name)}
+		content, err := git.CatFileContents(commithash, obj.Name)
+		if err != nil {
+			status.Err = err
+			expchan <- status
+			continue
+		}
+		hasher, err := newHash(algorithm)
+		if err != nil {
+			status.Err = err
+			expchan <- status
+			return files
+		}
+		var checksum string
+		var size int64
+		if key, ok := annexPointerKey(content); ok {
+			status.Type = "Annex"
+			contentloc, err := annexContentPath(key)
+			if err != nil {
+				status.Err = err
+				expchan <- status
+				continue
+			}
+			var hashwriter io.Writer = hasher
+			if keysum, ok := keyChecksum(key, algorithm); ok {
+				// checksum known from the key: skip hashing
+				checksum = keysum
+				hashwriter = ioutil.Discard
+			}
+			size, status.Err = addContentFile(archive, status.Destination, contentloc, hashwriter)
+		} else if obj.Mode == "120000" {
+			// Plain symlink: no checksum entry
+			status.Type = "Link"
+			status.Err = archive.addLink(status.Destination, string(content))
+			expchan <- status
+			continue
+		} else if obj.Mode == "100755" || obj.Mode == "100644" {
+			status.Type = "Git"
+			var mode os.FileMode = 0644
+			if obj.Mode == "100755" {
+				mode = 0755
+			}
+			size = int64(len(content))
+			status.Err = archive.addFile(status.Destination, mode, size, io.TeeReader(bytes.NewReader(content), hasher))
+		} else {
+			status.Err = fmt.Errorf("Unexpected object found in tree: %s", obj.Name)
+		}
+		if status.Err == nil {
+			if checksum == "" {
+				checksum = hex.EncodeToString(hasher.Sum(nil))
+			}
+			files = append(files, exportedFile{Name: obj.Name, Size: size, Checksum: checksum})
+		}
+		expchan <- status
+	}
+	return files
+}
+
+// addContentFile streams the file at srcpath into the archive, updating the hash with its contents, and returns its size.
+func addContentFile(archive archiveWriter, name, srcpath string, hash io.Writer) (int64, error) {
+	srcfile, err := os.Open(srcpath)
+	if err != nil {
+		return 0, err
+	}
+	defer srcfile.Close()
+	info, err := srcfile.Stat()
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), archive.addFile(name, 0644, info.Size(), io.TeeReader(srcfile, hash))
+}
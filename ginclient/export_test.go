@@ -0,0 +1,137 @@
+package ginclient
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/G-Node/gin-cli/git"
+)
+
+// setupGitRepo creates a git repository (without annex) in a temporary directory, commits the given files, and changes into it.
+// It returns the path of the repository.
+func setupGitRepo(t *testing.T, files map[string]string) string {
+	repodir, err := ioutil.TempDir("", "gin-cli-test-export-")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %s", err)
+	}
+	os.Chdir(repodir)
+	if err = git.Init(false); err != nil {
+		t.Fatalf("Failed to initialise repository: %s", err)
+	}
+	git.ConfigSet("user.name", "testuser")
+	git.ConfigSet("user.email", "testuser@example.com")
+	for fname, content := range files {
+		os.MkdirAll(filepath.Dir(fname), 0755)
+		if err = ioutil.WriteFile(fname, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file %s: %s", fname, err)
+		}
+	}
+	for _, args := range [][]string{{"add", "."}, {"commit", "-m", "test files"}} {
+		cmd := git.Command(args...)
+		if _, stderr, err := cmd.OutputError(); err != nil {
+			t.Fatalf("git %s failed: %s", args[0], string(stderr))
+		}
+	}
+	return repodir
+}
+
+// readArchive returns the contents of the regular files in a zip or tar.gz archive.
+func readArchive(t *testing.T, format string, data []byte) map[string]string {
+	contents := make(map[string]string)
+	switch format {
+	case "zip":
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			t.Fatalf("Failed to read zip archive: %s", err)
+		}
+		for _, f := range zr.File {
+			r, err := f.Open()
+			if err != nil {
+				t.Fatalf("Failed to read %s from zip archive: %s", f.Name, err)
+			}
+			b, _ := ioutil.ReadAll(r)
+			r.Close()
+			contents[f.Name] = string(b)
+		}
+	case "tar.gz":
+		gzr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("Failed to read gzip stream: %s", err)
+		}
+		tr := tar.NewReader(gzr)
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("Failed to read tar archive: %s", err)
+			}
+			b, _ := ioutil.ReadAll(tr)
+			contents[hdr.Name] = string(b)
+		}
+	}
+	return contents
+}
+
+func TestExportArchive(t *testing.T) {
+	files := map[string]string{
+		"README.md":        "# Test dataset\n",
+		"data/values.csv":  "1,2,3\n",
+		"raw/recording.nd": "raw data",
+	}
+	repodir := setupGitRepo(t, files)
+	defer os.RemoveAll(repodir)
+
+	notraw := func(fpath string) bool { return !strings.HasPrefix(fpath, "raw/") }
+	for _, format := range ArchiveFormats {
+		var out bytes.Buffer
+		expchan := make(chan FileCheckoutStatus)
+		go ExportArchive("HEAD", nil, notraw, format, "test-HEAD", &out, expchan)
+		for status := range expchan {
+			if status.Err != nil {
+				t.Fatalf("[%s] Export of '%s' failed: %s", format, status.Filename, status.Err)
+			}
+		}
+
+		contents := readArchive(t, format, out.Bytes())
+		if len(contents) != 3 {
+			t.Errorf("[%s] Expected 2 files and the manifest in the archive, got %d", format, len(contents))
+		}
+		var manifest []string
+		for _, fname := range []string{"README.md", "data/values.csv"} {
+			content, ok := contents["test-HEAD/"+fname]
+			if !ok || content != files[fname] {
+				t.Errorf("[%s] Wrong content for %s: %q", format, fname, content)
+			}
+			manifest = append(manifest, fmt.Sprintf("%x  %s", sha256.Sum256([]byte(files[fname])), fname))
+		}
+		if _, ok := contents["test-HEAD/raw/recording.nd"]; ok {
+			t.Errorf("[%s] Filtered file exported", format)
+		}
+		expected := strings.Join(manifest, "\n") + "\n"
+		if m := contents["test-HEAD/"+ManifestFileName]; m != expected {
+			t.Errorf("[%s] Wrong manifest:\n%s\nexpected:\n%s", format, m, expected)
+		}
+	}
+
+	var out bytes.Buffer
+	expchan := make(chan FileCheckoutStatus)
+	go ExportArchive("nonexistent", nil, nil, "zip", "test", &out, expchan)
+	var failed bool
+	for status := range expchan {
+		failed = failed || status.Err != nil
+	}
+	if !failed {
+		t.Error("Export of unknown revision did not fail")
+	}
+}
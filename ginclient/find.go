@@ -0,0 +1,237 @@
+package ginclient
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/G-Node/gin-cli/git"
+)
+
+// Functions for querying the files of a repository by metadata and other properties.
+
+// FindOptions holds the predicates for finding files.
+// Files must match all of the specified predicates.
+type FindOptions struct {
+	// Metadata expressions of the form "field=glob".
+	Metadata []string
+	// Minimum and maximum file size (e.g., "100M" or "1G").
+	Larger  string
+	Smaller string
+	// Names of remotes that must (In) or must not (NotIn) hold the content of the file.
+	In    []string
+	NotIn []string
+	// File statuses (see FileStatus.Abbrev) to match, e.g., "NC" or "MD".
+	Status []string
+	// Only match files changed in commits after the given date.
+	ChangedSince string
+	// Glob patterns matched against the file name or the path.
+	Globs []string
+	// Restrict matches to annexed files (Annexed) or files stored in git (Git).
+	Annexed bool
+	Git     bool
+}
+
+// FoundFile is a file matched by FindFiles.
+type FoundFile struct {
+	Name    string `json:"filename"`
+	Key     string `json:"key,omitempty"`
+	Size    int64  `json:"size"`
+	Annexed bool   `json:"annexed"`
+}
+
+// annexOnly returns true if the options include predicates that only apply to annexed files.
+func (opts FindOptions) annexOnly() bool {
+	return opts.Annexed || len(opts.Metadata) > 0 || len(opts.In) > 0 || len(opts.NotIn) > 0
+}
+
+// matchingArgs translates the options to git annex matching options.
+func (opts FindOptions) matchingArgs() ([]string, error) {
+	var args []string
+	for _, expr := range opts.Metadata {
+		if !strings.Contains(expr, "=") {
+			return nil, fmt.Errorf("invalid metadata expression '%s': must be of the form field=value", expr)
+		}
+		args = append(args, fmt.Sprintf("--metadata=%s", expr))
+	}
+	if opts.Larger != "" {
+		args = append(args, fmt.Sprintf("--largerthan=%s", opts.Larger))
+	}
+	if opts.Smaller != "" {
+		args = append(args, fmt.Sprintf("--smallerthan=%s", opts.Smaller))
+	}
+	for _, remote := range opts.In {
+		args = append(args, fmt.Sprintf("--in=%s", remote))
+	}
+	for _, remote := range opts.NotIn {
+		args = append(args, "--not", fmt.Sprintf("--in=%s", remote))
+	}
+	return args, nil
+}
+
+// matchGlobs returns true if there are no patterns or any of the patterns matches the file name or the path.
+func matchGlobs(patterns []string, fpath string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if match, _ := filepath.Match(pattern, filepath.Base(fpath)); match {
+			return true
+		}
+		if match, _ := filepath.Match(pattern, fpath); match {
+			return true
+		}
+	}
+	return false
+}
+
+// parseSize parses a size with an optional unit suffix and returns it in bytes.
+// As in git-annex, decimal units (kB, MB, GB, TB, PB) are powers of 1000 and binary units (KiB, MiB, GiB, TiB, PiB) are powers of 1024.
+// Units are case insensitive and the trailing 'B' is optional (e.g., "100k", "2MiB", "1.5gb").
+func parseSize(size string) (int64, error) {
+	str := strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(size)), "B")
+	base := 1000.0
+	if strings.HasSuffix(str, "I") {
+		base = 1024
+		str = strings.TrimSuffix(str, "I")
+	}
+	exp := 0
+	if len(str) > 0 {
+		exp = strings.Index("KMGTP", str[len(str)-1:]) + 1
+		if exp > 0 {
+			str = str[:len(str)-1]
+		}
+	}
+	if base == 1024 && exp == 0 {
+		return 0, fmt.Errorf("invalid size '%s'", size)
+	}
+	value, err := strconv.ParseFloat(strings.TrimSpace(str), 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid size '%s'", size)
+	}
+	return int64(value * math.Pow(base, float64(exp))), nil
+}
+
+// gitFiles returns the files specified by paths that are stored in git (not annexed) and match the size predicates.
+func gitFiles(paths []string, annexed map[string]bool, opts FindOptions) ([]FoundFile, error) {
+	var larger, smaller int64 = -1, -1
+	var err error
+	if opts.Larger != "" {
+		if larger, err = parseSize(opts.Larger); err != nil {
+			return nil, err
+		}
+	}
+	if opts.Smaller != "" {
+		if smaller, err = parseSize(opts.Smaller); err != nil {
+			return nil, err
+		}
+	}
+	var files []FoundFile
+	lschan := make(chan string)
+	go git.LsFiles(append([]string{"--"}, paths...), lschan)
+	for fname := range lschan {
+		fname = filepath.Clean(fname)
+		if annexed[fname] {
+			continue
+		}
+		info, err := os.Lstat(fname)
+		if err != nil {
+			// deleted in the working tree
+			continue
+		}
+		size := info.Size()
+		if (larger >= 0 && size <= larger) || (smaller >= 0 && size >= smaller) {
+			continue
+		}
+		files = append(files, FoundFile{Name: fname, Size: size})
+	}
+	return files, nil
+}
+
+// FindFiles returns the files specified by paths that match all the predicates in opts, sorted by name.
+func FindFiles(paths []string, opts FindOptions) ([]FoundFile, error) {
+	if opts.Annexed && opts.Git {
+		return nil, fmt.Errorf("annexed and git files cannot be selected together")
+	}
+	if opts.Git && opts.annexOnly() {
+		return nil, fmt.Errorf("metadata and remote criteria only apply to annexed files")
+	}
+	paths, err := expandglobs(paths, false)
+	if err != nil {
+		return nil, err
+	}
+	matching, err := opts.matchingArgs()
+	if err != nil {
+		return nil, err
+	}
+
+	var files []FoundFile
+	if !opts.Git {
+		results, err := git.AnnexFindMatching(paths, matching)
+		if err != nil {
+			return nil, err
+		}
+		for _, res := range results {
+			size, _ := strconv.ParseInt(res.Bytesize, 10, 64)
+			files = append(files, FoundFile{Name: filepath.Clean(res.File), Key: res.Key, Size: size, Annexed: true})
+		}
+	}
+	if !opts.annexOnly() {
+		// all annexed files, to exclude them from the git file listing
+		annexed := make(map[string]bool)
+		results, err := git.AnnexFindMatching(paths, nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, res := range results {
+			annexed[filepath.Clean(res.File)] = true
+		}
+		gfiles, err := gitFiles(paths, annexed, opts)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, gfiles...)
+	}
+
+	var changed map[string]bool
+	if opts.ChangedSince != "" {
+		changedlist, err := git.LogChangedFiles(opts.ChangedSince, paths)
+		if err != nil {
+			return nil, err
+		}
+		changed = make(map[string]bool, len(changedlist))
+		for _, fname := range changedlist {
+			changed[filepath.Clean(fname)] = true
+		}
+	}
+	var statuses map[string]FileStatus
+	if len(opts.Status) > 0 {
+		gincl := New("")
+		if statuses, err = gincl.ListFiles(paths...); err != nil {
+			return nil, err
+		}
+	}
+
+	matched := files[:0]
+	for _, f := range files {
+		if !matchGlobs(opts.Globs, f.Name) {
+			continue
+		}
+		if changed != nil && !changed[f.Name] {
+			continue
+		}
+		if statuses != nil {
+			status, ok := statuses[f.Name]
+			if !ok || !inList(status.Abbrev(), opts.Status) {
+				continue
+			}
+		}
+		matched = append(matched, f)
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i].Name < matched[j].Name })
+	return matched, nil
+}
@@ -0,0 +1,65 @@
+package ginclient
+
+import "testing"
+
+func TestParseSize(t *testing.T) {
+	cases := []struct {
+		size  string
+		bytes int64
+	}{
+		{"0", 0},
+		{"512", 512},
+		{"100B", 100},
+		{"500k", 500000},
+		{"500kB", 500000},
+		{"1.5M", 1500000},
+		{"100MB", 100000000},
+		{"1gb", 1000000000},
+		{"2T", 2000000000000},
+		{"1KiB", 1024},
+		{"1kib", 1024},
+		{"2MiB", 2 * 1024 * 1024},
+		{"1.5GiB", 1536 * 1024 * 1024},
+		{"1Ti", 1024 * 1024 * 1024 * 1024},
+		{" 10 MB ", 10000000},
+	}
+	for _, c := range cases {
+		bytes, err := parseSize(c.size)
+		if err != nil {
+			t.Errorf("Failed to parse size '%s': %v", c.size, err)
+			continue
+		}
+		if bytes != c.bytes {
+			t.Errorf("Size '%s': expected %d bytes, got %d", c.size, c.bytes, bytes)
+		}
+	}
+
+	for _, size := range []string{"", "MB", "ten", "-1k", "1X", "1iB", "1.2.3M"} {
+		if _, err := parseSize(size); err == nil {
+			t.Errorf("Parsing invalid size '%s' should fail", size)
+		}
+	}
+}
+
+func TestMatchGlobs(t *testing.T) {
+	cases := []struct {
+		patterns []string
+		path     string
+		match    bool
+	}{
+		{nil, "data/recording.nix", true},
+		{[]string{"*.nix"}, "data/recording.nix", true},
+		{[]string{"*.nix"}, "recording.nix", true},
+		{[]string{"*.nix"}, "data/recording.h5", false},
+		{[]string{"*.h5", "*.nix"}, "data/recording.nix", true},
+		{[]string{"data/*.nix"}, "data/recording.nix", true},
+		{[]string{"data/*.nix"}, "data/sub/recording.nix", false},
+		{[]string{"data/*"}, "other/data/recording.nix", false},
+		{[]string{"rec?rding.*"}, "data/recording.nix", true},
+	}
+	for _, c := range cases {
+		if m := matchGlobs(c.patterns, c.path); m != c.match {
+			t.Errorf("Patterns %v matching '%s': expected %t, got %t", c.patterns, c.path, c.match, m)
+		}
+	}
+}
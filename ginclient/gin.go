@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"sort"
 
 	"net/http"
 
@@ -60,7 +61,12 @@ func (gincl *Client) GitAddress() string {
 	if gincl.srvalias == "" {
 		return ""
 	}
-	return config.Read().Servers[gincl.srvalias].Git.AddressStr()
+	return config.Read().Servers[gincl.srvalias].RemoteAddressStr()
+}
+
+// ServerAlias returns the alias of the server the client is configured for.
+func (gincl *Client) ServerAlias() string {
+	return gincl.srvalias
 }
 
 // WebAddress returns the full address string for the configured web server
@@ -98,6 +104,36 @@ func (gincl *Client) GetUserKeys() ([]gogs.PublicKey, error) {
 	return keys, nil
 }
 
+// CurrentUser requests the account of the user the token belongs to.
+func (gincl *Client) CurrentUser() (gogs.User, error) {
+	fn := "CurrentUser()"
+	var acc gogs.User
+	res, err := gincl.Get("/api/v1/user")
+	if err != nil {
+		return acc, err // return error from Get() directly
+	}
+	switch code := res.StatusCode; {
+	case code == http.StatusUnauthorized || code == http.StatusForbidden:
+		return acc, ginerror{UError: res.Status, Origin: fn, Description: "authorisation failed"}
+	case code == http.StatusInternalServerError:
+		return acc, ginerror{UError: res.Status, Origin: fn, Description: "server error"}
+	case code != http.StatusOK:
+		return acc, ginerror{UError: res.Status, Origin: fn} // Unexpected error
+	}
+
+	defer web.CloseRes(res.Body)
+
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return acc, ginerror{UError: err.Error(), Origin: fn, Description: "failed to read response body"}
+	}
+	err = json.Unmarshal(b, &acc)
+	if err != nil {
+		err = ginerror{UError: err.Error(), Origin: fn, Description: "failed to parse response body"}
+	}
+	return acc, err
+}
+
 // RequestAccount requests a specific account by name.
 func (gincl *Client) RequestAccount(name string) (gogs.User, error) {
 	fn := fmt.Sprintf("RequestAccount(%s)", name)
@@ -223,8 +259,20 @@ func (gincl *Client) DeletePubKeyByIdx(idx int) (string, error) {
 // Login requests a token from the auth server and stores the username and
 // token to file and adds them to the Client.
 // It also generates a key pair for the user for use in git commands.
-// (See also NewToken)
+// (See also NewToken and LoginToken)
 func (gincl *Client) Login(username, password, clientID string) error {
+	if err := gincl.LoginToken(username, password, clientID); err != nil {
+		return err
+	}
+
+	// Make keys
+	return gincl.MakeSessionKey()
+}
+
+// LoginToken requests a token from the auth server and stores the username and
+// token to file and adds them to the Client, without creating a session key.
+// Git commands then require one of the user's own keys (see FindRegisteredKey).
+func (gincl *Client) LoginToken(username, password, clientID string) error {
 	// retrieve user's active tokens
 	tokens, err := gincl.GetTokens(username, password)
 	if err != nil {
@@ -256,9 +304,7 @@ func (gincl *Client) Login(username, password, clientID string) error {
 	if err != nil {
 		return fmt.Errorf("Error while storing token: %s", err.Error())
 	}
-
-	// Make keys
-	return gincl.MakeSessionKey()
+	return nil
 }
 
 // GetTokens returns all the user's active access tokens from the GIN server.
@@ -293,55 +339,80 @@ func (gincl *Client) GetTokens(username, password string) ([]AccessToken, error)
 // NewToken requests a new user token from the GIN server and adds it to the
 // Client along with the username.
 func (gincl *Client) NewToken(username, password, clientID string) error {
-	fn := "NewToken()"
-	tokenCreate := &gogs.CreateAccessTokenOption{Name: clientID}
+	token, err := gincl.CreateToken(username, password, clientID)
+	if err != nil {
+		return err
+	}
+	gincl.Username = username
+	gincl.Token = token.Sha1
+	return nil
+}
+
+// CreateToken requests a new access token with the given name from the GIN server and returns it.
+// Unlike NewToken, it does not change the token of the Client.
+func (gincl *Client) CreateToken(username, password, name string) (AccessToken, error) {
+	fn := "CreateToken()"
+	tokenCreate := &gogs.CreateAccessTokenOption{Name: name}
 	address := fmt.Sprintf("/api/v1/users/%s/tokens", username)
 	res, err := gincl.PostBasicAuth(address, username, password, tokenCreate)
 	if err != nil {
-		return err // return error from PostBasicAuth directly
+		return AccessToken{}, err // return error from PostBasicAuth directly
 	}
 	switch code := res.StatusCode; {
 	case code == http.StatusInternalServerError:
-		return ginerror{UError: res.Status, Origin: fn, Description: "server error"}
+		return AccessToken{}, ginerror{UError: res.Status, Origin: fn, Description: "server error"}
 	case code == http.StatusUnauthorized:
-		return ginerror{UError: res.Status, Origin: fn, Description: "authorisation failed"}
+		return AccessToken{}, ginerror{UError: res.Status, Origin: fn, Description: "authorisation failed"}
 	case code != http.StatusCreated:
-		return ginerror{UError: res.Status, Origin: fn} // Unexpected error
+		return AccessToken{}, ginerror{UError: res.Status, Origin: fn} // Unexpected error
 	}
 	data, err := ioutil.ReadAll(res.Body)
 	if err != nil {
-		return err
+		return AccessToken{}, err
 	}
 	log.Write("Got response: %s", res.Status)
 	token := AccessToken{}
 	err = json.Unmarshal(data, &token)
 	if err != nil {
-		return ginerror{UError: err.Error(), Origin: fn, Description: "failed to parse response body"}
+		return AccessToken{}, ginerror{UError: err.Error(), Origin: fn, Description: "failed to parse response body"}
 	}
-	gincl.Username = username
-	gincl.Token = token.Sha1
-	return nil
+	return token, nil
 }
 
 // LoadToken calls the embedded UserToken.LoadToken function with the configured server alias.
+// For the default server, a token set in the TokenEnvVar environment variable takes precedence over the stored login and the username is requested from the server.
 func (gincl *Client) LoadToken() error {
+	if gincl.UsesEnvToken() {
+		envtoken := os.Getenv(TokenEnvVar)
+		if gincl.Token == envtoken && gincl.Username != "" {
+			return nil
+		}
+		log.Write("Using token from %s", TokenEnvVar)
+		gincl.UserToken = web.UserToken{Token: envtoken}
+		return gincl.ValidateToken()
+	}
 	return gincl.UserToken.LoadToken(gincl.srvalias)
 }
 
 // Logout logs out the currently logged in user in 3 steps:
-// 1. Remove the public key matching the current hostname from the server.
-// 2. Delete the private key file from the local machine.
+// 1. Remove the public session key from the server.
+// 2. Delete the private and public key files from the local machine.
 // 3. Delete the user token.
 func (gincl *Client) Logout() {
 	// 1. Delete public key
-	hostname, err := os.Hostname()
-	if err != nil {
-		log.Write("Could not retrieve hostname")
-		hostname = unknownhostname
+	keyfilepath, _ := gincl.sessionKeyPath(false)
+	pubkey, err := ioutil.ReadFile(keyfilepath + ".pub")
+	if err == nil {
+		err = gincl.DeletePubKeyByContent(string(pubkey))
+	} else {
+		// keys created by earlier versions have no public key file
+		hostname, herr := os.Hostname()
+		if herr != nil {
+			log.Write("Could not retrieve hostname")
+			hostname = unknownhostname
+		}
+		err = gincl.DeletePubKeyByTitle(sessionKeyTitle(gincl.Username, hostname))
 	}
-
-	currentkeyname := fmt.Sprintf("GIN Client: %s@%s", gincl.Username, hostname)
-	err = gincl.DeletePubKeyByTitle(currentkeyname)
 	if err != nil {
 		log.Write(err.Error())
 	}
@@ -354,11 +425,43 @@ func (gincl *Client) Logout() {
 	} else {
 		log.Write("Private key file deleted")
 	}
+	if keyfilepath != "" {
+		os.Remove(keyfilepath + ".pub")
+	}
 
 	err = web.DeleteToken(gincl.srvalias)
 	if err != nil {
 		log.Write("Error deleting token file")
 	}
+	gincl.recordValidation(false)
+}
+
+// ProfileConfigKey is the git configuration key that binds a repository to a profile.
+const ProfileConfigKey = "gin.profile"
+
+// SetRepoProfile binds the current repository to a profile, so that the credentials of the profile are used for all commands run in the repository.
+func SetRepoProfile(profile string) error {
+	if err := config.CheckProfileName(profile); err != nil {
+		return err
+	}
+	return git.ConfigSet(ProfileConfigKey, profile)
+}
+
+// UnsetRepoProfile removes the profile binding of the current repository.
+func UnsetRepoProfile() error {
+	return git.ConfigUnset(ProfileConfigKey)
+}
+
+// ProfileLogins returns the aliases of the servers for which a profile holds a login token, sorted by alias.
+func ProfileLogins(profile string) ([]string, error) {
+	var aliases []string
+	for alias := range config.Read().Servers {
+		if len(web.TokenStores(profile, alias)) > 0 {
+			aliases = append(aliases, alias)
+		}
+	}
+	sort.Strings(aliases)
+	return aliases, nil
 }
 
 // DefaultServer returns the alias of the configured default gin server.
@@ -0,0 +1,518 @@
+package ginclient
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/G-Node/gin-cli/ginclient/log"
+	"github.com/G-Node/gin-cli/git"
+)
+
+// Functions for importing large directory trees and lists of URLs into a repository in batches.
+
+// importStateFileName is the name of the file in the .git directory that keeps track of an unfinished import.
+const importStateFileName = "gin-import.json"
+
+// DefaultImportBatchSize is the number of files added and committed together when importing.
+const DefaultImportBatchSize = 1000
+
+// ImportOptions holds the settings for an import.
+type ImportOptions struct {
+	// Directory in the repository where imported files are placed, relative to the working directory.
+	Dest string
+	// How files are handled.
+	// For directories: "copy" (default), "move", or "hardlink".
+	// For URL lists: "fast" (default), "relaxed", or "download" (see git.AnnexAddURL).
+	Mode string
+	// Number of files per commit.
+	BatchSize int
+	// Discard the state of an unfinished import instead of resuming it.
+	Restart bool
+}
+
+// ImportStatus reports the progress of an import.
+// A status with Batch 0 is sent before the first batch to report the total number of files and the number already imported by a previous, interrupted run.
+type ImportStatus struct {
+	Batch    int   `json:"batch"`
+	NBatches int   `json:"nbatches"`
+	Files    int   `json:"files"`
+	Imported int   `json:"imported"`
+	Total    int   `json:"total"`
+	Err      error `json:"-"`
+}
+
+// importState is stored between batches so that an interrupted import can be resumed.
+type importState struct {
+	Source   string   `json:"source"`
+	Dest     string   `json:"dest"`
+	Mode     string   `json:"mode"`
+	Imported []string `json:"imported"`
+	// Sources of the batch that was being imported when the import was interrupted.
+	// Only the destinations of these files may be replaced when the import is resumed.
+	InProgress []string `json:"inprogress,omitempty"`
+}
+
+// importItem is a single file to import: the source (path relative to the source directory or URL) and its destination path in the repository.
+type importItem struct {
+	Source string
+	Dest   string
+}
+
+func importStatePath() (string, error) {
+	reporoot, err := git.FindRepoRoot(".")
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(reporoot, ".git", importStateFileName), nil
+}
+
+// loadImportState reads the state of an unfinished import and returns true along with it.
+// If there is no unfinished import or restart is true, a new state for the given source, destination, and mode is returned along with false.
+// An error is returned if the unfinished import does not match the given source, destination, and mode.
+func loadImportState(statepath, source, dest, mode string, restart bool) (importState, bool, error) {
+	newstate := importState{Source: source, Dest: dest, Mode: mode}
+	if restart {
+		return newstate, false, nil
+	}
+	data, err := ioutil.ReadFile(statepath)
+	if os.IsNotExist(err) {
+		return newstate, false, nil
+	} else if err != nil {
+		return newstate, false, err
+	}
+	var state importState
+	if err = json.Unmarshal(data, &state); err != nil {
+		return newstate, false, fmt.Errorf("failed to read state of unfinished import: %s", err)
+	}
+	if state.Source != source || state.Dest != dest || state.Mode != mode {
+		return newstate, false, fmt.Errorf("an unfinished import of '%s' into '%s' (%s) exists; resume it with the same arguments or start over with --restart", state.Source, state.Dest, state.Mode)
+	}
+	return state, true, nil
+}
+
+func (state importState) save(statepath string) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(statepath, data, 0644)
+}
+
+// absDest returns the absolute path of the import destination and checks that it is inside the repository.
+func absDest(dest string) (string, error) {
+	if dest == "" {
+		dest = "."
+	}
+	reporoot, err := git.FindRepoRoot(".")
+	if err != nil {
+		return "", err
+	}
+	absdest, err := filepath.Abs(dest)
+	if err != nil {
+		return "", err
+	}
+	if !pathInside(absdest, reporoot) {
+		return "", fmt.Errorf("destination '%s' is not inside the repository", dest)
+	}
+	return absdest, nil
+}
+
+// pathExists returns true if the path exists, including symlinks whose target does not exist (e.g., annexed files without content).
+func pathExists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+// pathInside returns true if path is dir or a path under dir.
+func pathInside(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// ImportDirectory adds all files under srcdir to the repository in batches of opts.BatchSize files, recording a commit for each batch.
+// The files are placed under opts.Dest, keeping the directory structure of srcdir, by copying, moving, or hard linking them (opts.Mode).
+// Hard linked files share their content with the annex, so the source files must not be modified afterwards.
+// If a previous import of the same directory was interrupted, only the files that were not committed are imported.
+// The status of each batch is sent on the impchan channel, which is closed when the function returns.
+func ImportDirectory(srcdir string, opts ImportOptions, impchan chan<- ImportStatus) {
+	defer close(impchan)
+	mode := opts.Mode
+	if mode == "" {
+		mode = "copy"
+	}
+	var place func(src, dest string) error
+	switch mode {
+	case "copy":
+		place = git.CopyFile
+	case "move":
+		place = os.Rename
+	case "hardlink":
+		place = os.Link
+	default:
+		impchan <- ImportStatus{Err: fmt.Errorf("unknown import mode '%s'", mode)}
+		return
+	}
+
+	abssrc, err := filepath.Abs(srcdir)
+	if err != nil {
+		impchan <- ImportStatus{Err: err}
+		return
+	}
+	if info, err := os.Stat(abssrc); err != nil || !info.IsDir() {
+		impchan <- ImportStatus{Err: fmt.Errorf("'%s' is not a directory", srcdir)}
+		return
+	}
+	reporoot, err := git.FindRepoRoot(".")
+	if err != nil {
+		impchan <- ImportStatus{Err: err}
+		return
+	}
+	if pathInside(abssrc, reporoot) || pathInside(reporoot, abssrc) {
+		impchan <- ImportStatus{Err: fmt.Errorf("source directory '%s' and repository must not contain each other", srcdir)}
+		return
+	}
+	absdest, err := absDest(opts.Dest)
+	if err != nil {
+		impchan <- ImportStatus{Err: err}
+		return
+	}
+
+	var items []importItem
+	walker := func(fpath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() {
+			log.Write("Skipping %s: not a regular file", fpath)
+			return nil
+		}
+		rel, _ := filepath.Rel(abssrc, fpath)
+		items = append(items, importItem{Source: rel, Dest: filepath.Join(absdest, rel)})
+		return nil
+	}
+	if err = filepath.Walk(abssrc, walker); err != nil {
+		impchan <- ImportStatus{Err: fmt.Errorf("failed to read source directory: %s", err)}
+		return
+	}
+
+	addBatch := func(batch []importItem, written map[string]bool) error {
+		destpaths, err := placeFiles(abssrc, batch, mode, place, written)
+		if err != nil {
+			return err
+		}
+		return annexAddPaths(destpaths)
+	}
+	runImport(abssrc, absdest, mode, items, opts, addBatch, impchan)
+}
+
+// ImportURLs registers the files listed in a CSV file in the annex by their URL, in batches of opts.BatchSize files, recording a commit for each batch.
+// Each line of the list contains a URL and, optionally, the path of the file relative to opts.Dest.
+// If the path is omitted, the last element of the URL path is used.
+// A first line with the column names "url" and "path" is skipped.
+// With the default mode ("fast"), the content is not downloaded (see git.AnnexAddURL).
+// If a previous import of the same list was interrupted, only the URLs that were not committed are imported.
+// The status of each batch is sent on the impchan channel, which is closed when the function returns.
+func ImportURLs(listfile string, opts ImportOptions, impchan chan<- ImportStatus) {
+	defer close(impchan)
+	mode := opts.Mode
+	if mode == "" {
+		mode = "fast"
+	}
+	abslist, err := filepath.Abs(listfile)
+	if err != nil {
+		impchan <- ImportStatus{Err: err}
+		return
+	}
+	absdest, err := absDest(opts.Dest)
+	if err != nil {
+		impchan <- ImportStatus{Err: err}
+		return
+	}
+	items, err := readURLList(abslist, absdest)
+	if err != nil {
+		impchan <- ImportStatus{Err: err}
+		return
+	}
+
+	addBatch := func(batch []importItem, written map[string]bool) error {
+		urls := make([]string, len(batch))
+		destpaths := make([]string, len(batch))
+		for idx, item := range batch {
+			urls[idx] = item.Source
+			destpaths[idx] = item.Dest
+			if written[item.Source] && pathExists(item.Dest) {
+				// left over from the interrupted batch
+				if err := os.Remove(item.Dest); err != nil {
+					return fmt.Errorf("failed to replace '%s': %s", item.Dest, err)
+				}
+			}
+			if err := os.MkdirAll(filepath.Dir(item.Dest), 0777); err != nil {
+				return err
+			}
+		}
+		addchan := make(chan git.RepoFileStatus)
+		go git.AnnexAddURL(urls, destpaths, mode, addchan)
+		return collectAddErrors(addchan)
+	}
+	runImport(abslist, absdest, mode, items, opts, addBatch, impchan)
+}
+
+// placeFiles copies, moves, or links (place) the files of a batch from the source directory to their destinations and returns the destination paths.
+// Destinations are only replaced if the source is in written, i.e., if the file was placed by an interrupted run of the same import.
+func placeFiles(abssrc string, batch []importItem, mode string, place func(src, dest string) error, written map[string]bool) ([]string, error) {
+	var destpaths []string
+	for _, item := range batch {
+		src := filepath.Join(abssrc, item.Source)
+		if written[item.Source] && pathExists(item.Dest) {
+			if mode == "move" && !pathExists(src) {
+				// moved before the interruption
+				destpaths = append(destpaths, item.Dest)
+				continue
+			}
+			// left over from the interrupted batch
+			if err := os.Remove(item.Dest); err != nil {
+				return nil, fmt.Errorf("failed to replace '%s': %s", item.Dest, err)
+			}
+		}
+		if err := os.MkdirAll(filepath.Dir(item.Dest), 0777); err != nil {
+			return nil, err
+		}
+		if err := place(src, item.Dest); err != nil {
+			return nil, fmt.Errorf("failed to import '%s': %s", item.Source, err)
+		}
+		destpaths = append(destpaths, item.Dest)
+	}
+	return destpaths, nil
+}
+
+// URLFileName returns the file name for content added from a URL, which is the last element of the URL path.
+func URLFileName(rawurl string) (string, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("'%s' is not a valid URL", rawurl)
+	}
+	fname := path.Base(u.Path)
+	if fname == "/" || fname == "." {
+		return "", fmt.Errorf("cannot determine a file name for '%s'", rawurl)
+	}
+	return fname, nil
+}
+
+// readURLList reads a CSV file of URLs and optional file paths and returns the items to import into the destination directory.
+func readURLList(listfile, absdest string) ([]importItem, error) {
+	f, err := os.Open(listfile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+
+	var items []importItem
+	for lineno := 1; ; lineno++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to read URL list: %s", err)
+		}
+		if lineno == 1 && strings.ToLower(record[0]) == "url" {
+			continue
+		}
+		u, err := url.Parse(record[0])
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("line %d: '%s' is not a valid URL", lineno, record[0])
+		}
+		var fname string
+		if len(record) > 1 && record[1] != "" {
+			fname = filepath.FromSlash(record[1])
+		} else if fname, err = URLFileName(record[0]); err != nil {
+			return nil, fmt.Errorf("line %d: %s; specify a path", lineno, err)
+		}
+		dest := filepath.Join(absdest, fname)
+		if !pathInside(dest, absdest) {
+			return nil, fmt.Errorf("line %d: path '%s' is outside the destination directory", lineno, fname)
+		}
+		items = append(items, importItem{Source: record[0], Dest: dest})
+	}
+	return items, nil
+}
+
+// annexAddPaths adds the paths to the repository (see git.AnnexAdd) and returns an error if any of them failed.
+func annexAddPaths(paths []string) error {
+	addchan := make(chan git.RepoFileStatus)
+	go git.AnnexAdd(paths, addchan)
+	return collectAddErrors(addchan)
+}
+
+func collectAddErrors(addchan <-chan git.RepoFileStatus) error {
+	var failed []string
+	var lasterr error
+	for stat := range addchan {
+		if stat.Err != nil {
+			failed = append(failed, stat.FileName)
+			lasterr = stat.Err
+		}
+	}
+	switch len(failed) {
+	case 0:
+		return nil
+	case 1:
+		return fmt.Errorf("failed to add '%s': %s", failed[0], lasterr)
+	default:
+		return fmt.Errorf("failed to add %d files", len(failed))
+	}
+}
+
+// checkStaged returns an error if changes other than those of the interrupted batch are staged, since the batches are committed with everything in the index.
+// The destinations of the sources in written may be staged by the interrupted batch.
+func checkStaged(items []importItem, written map[string]bool) error {
+	staged, err := git.StagedFiles()
+	if err != nil {
+		return err
+	}
+	if len(staged) == 0 {
+		return nil
+	}
+	reporoot, err := git.FindRepoRoot(".")
+	if err != nil {
+		return err
+	}
+	ours := make(map[string]bool, len(written))
+	for _, item := range items {
+		if written[item.Source] {
+			if rel, err := filepath.Rel(reporoot, item.Dest); err == nil {
+				ours[filepath.ToSlash(rel)] = true
+			}
+		}
+	}
+	for _, fname := range staged {
+		if !ours[fname] {
+			return fmt.Errorf("changes to '%s' are staged; commit or unstage them before importing", fname)
+		}
+	}
+	return nil
+}
+
+// runImport runs addBatch for each batch of items that was not imported previously and commits the changes after each batch.
+// The import state is saved before and after each batch and removed when all items have been imported.
+// Existing destinations are never replaced, except for those of the batch that was interrupted, which addBatch receives as the set of sources whose destinations were written by the import.
+func runImport(source, absdest, mode string, items []importItem, opts ImportOptions, addBatch func([]importItem, map[string]bool) error, impchan chan<- ImportStatus) {
+	statepath, err := importStatePath()
+	if err != nil {
+		impchan <- ImportStatus{Err: err}
+		return
+	}
+	state, _, err := loadImportState(statepath, source, absdest, mode, opts.Restart)
+	if err != nil {
+		impchan <- ImportStatus{Err: err}
+		return
+	}
+	done := make(map[string]bool, len(state.Imported))
+	for _, src := range state.Imported {
+		done[src] = true
+	}
+	written := make(map[string]bool, len(state.InProgress))
+	for _, src := range state.InProgress {
+		written[src] = true
+	}
+	if err = checkStaged(items, written); err != nil {
+		impchan <- ImportStatus{Err: err}
+		return
+	}
+	var pending []importItem
+	for _, item := range items {
+		if done[item.Source] {
+			continue
+		}
+		if !written[item.Source] && pathExists(item.Dest) {
+			impchan <- ImportStatus{Err: fmt.Errorf("'%s' already exists in the repository; refusing to overwrite", item.Dest)}
+			return
+		}
+		pending = append(pending, item)
+	}
+
+	batchsize := opts.BatchSize
+	if batchsize < 1 {
+		batchsize = DefaultImportBatchSize
+	}
+	nbatches := (len(pending) + batchsize - 1) / batchsize
+	imported := len(items) - len(pending)
+	total := len(items)
+	if err = state.save(statepath); err != nil {
+		impchan <- ImportStatus{Err: fmt.Errorf("failed to save import state: %s", err)}
+		return
+	}
+	impchan <- ImportStatus{NBatches: nbatches, Imported: imported, Total: total}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = unknownhostname
+	}
+	for bidx := 0; bidx < nbatches; bidx++ {
+		end := (bidx + 1) * batchsize
+		if end > len(pending) {
+			end = len(pending)
+		}
+		batch := pending[bidx*batchsize : end]
+		status := ImportStatus{Batch: bidx + 1, NBatches: nbatches, Files: len(batch), Imported: imported, Total: total}
+		// files of the interrupted batch that are in later batches stay recorded until they are imported
+		state.InProgress = nil
+		for _, item := range batch {
+			state.InProgress = append(state.InProgress, item.Source)
+		}
+		for _, item := range pending[end:] {
+			if written[item.Source] {
+				state.InProgress = append(state.InProgress, item.Source)
+			}
+		}
+		if err = state.save(statepath); err != nil {
+			status.Err = fmt.Errorf("failed to save import state: %s", err)
+			impchan <- status
+			return
+		}
+		if err = addBatch(batch, written); err != nil {
+			status.Err = err
+			impchan <- status
+			return
+		}
+		commitmsg := fmt.Sprintf("gin import from %s (batch %d/%d)\n\nSource: %s", hostname, bidx+1, nbatches, source)
+		if err = git.Commit(commitmsg); err != nil && err.Error() != "Nothing to commit" {
+			status.Err = err
+			impchan <- status
+			return
+		}
+		for _, item := range batch {
+			state.Imported = append(state.Imported, item.Source)
+			delete(written, item.Source)
+		}
+		state.InProgress = nil
+		if err = state.save(statepath); err != nil {
+			status.Err = fmt.Errorf("failed to save import state: %s", err)
+			impchan <- status
+			return
+		}
+		imported += len(batch)
+		status.Imported = imported
+		impchan <- status
+	}
+	if err = os.Remove(statepath); err != nil && !os.IsNotExist(err) {
+		log.Write("Failed to remove import state file: %s", err)
+	}
+}
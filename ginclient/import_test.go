@@ -0,0 +1,292 @@
+package ginclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/G-Node/gin-cli/git"
+)
+
+// fakeAddBatch returns a batch function for runImport that writes the source name into each destination file and adds it to git (without annex).
+// It fails the test if it would replace a file that was not written by the import.
+// If failAt is greater than zero, the batch function writes the first file of batch number failAt and then fails, simulating an interruption.
+func fakeAddBatch(t *testing.T, failAt int) func([]importItem, map[string]bool) error {
+	nbatch := 0
+	return func(batch []importItem, written map[string]bool) error {
+		nbatch++
+		for idx, item := range batch {
+			if pathExists(item.Dest) && !written[item.Source] {
+				t.Fatalf("Import would replace '%s', which was not written by the import", item.Dest)
+			}
+			os.MkdirAll(filepath.Dir(item.Dest), 0755)
+			if err := ioutil.WriteFile(item.Dest, []byte(item.Source), 0644); err != nil {
+				return err
+			}
+			if nbatch == failAt && idx == 0 {
+				return fmt.Errorf("interrupted")
+			}
+		}
+		cmd := git.Command("add", ".")
+		if _, stderr, err := cmd.OutputError(); err != nil {
+			return fmt.Errorf("git add failed: %s", string(stderr))
+		}
+		return nil
+	}
+}
+
+func testImportItems(absdest string, n int) []importItem {
+	items := make([]importItem, n)
+	for idx := range items {
+		src := fmt.Sprintf("file%02d", idx)
+		items[idx] = importItem{Source: src, Dest: filepath.Join(absdest, "data", src)}
+	}
+	return items
+}
+
+func collectImportStatus(impchan <-chan ImportStatus) ([]ImportStatus, error) {
+	var statuses []ImportStatus
+	var lasterr error
+	for status := range impchan {
+		statuses = append(statuses, status)
+		if status.Err != nil {
+			lasterr = status.Err
+		}
+	}
+	return statuses, lasterr
+}
+
+func runTestImport(items []importItem, absdest string, opts ImportOptions, addBatch func([]importItem, map[string]bool) error) ([]ImportStatus, error) {
+	impchan := make(chan ImportStatus)
+	go func() {
+		defer close(impchan)
+		runImport("source", absdest, "copy", items, opts, addBatch, impchan)
+	}()
+	return collectImportStatus(impchan)
+}
+
+func countCommits(t *testing.T) int {
+	cmd := git.Command("rev-list", "--count", "HEAD")
+	stdout, stderr, err := cmd.OutputError()
+	if err != nil {
+		t.Fatalf("git rev-list failed: %s", string(stderr))
+	}
+	var n int
+	fmt.Sscanf(string(stdout), "%d", &n)
+	return n
+}
+
+func readImportState(t *testing.T, repodir string) (importState, bool) {
+	var state importState
+	data, err := ioutil.ReadFile(filepath.Join(repodir, ".git", importStateFileName))
+	if os.IsNotExist(err) {
+		return state, false
+	} else if err != nil {
+		t.Fatalf("Failed to read import state: %s", err)
+	}
+	if err = json.Unmarshal(data, &state); err != nil {
+		t.Fatalf("Failed to parse import state: %s", err)
+	}
+	return state, true
+}
+
+func TestRunImportBatches(t *testing.T) {
+	repodir := setupGitRepo(t, map[string]string{"README.md": "readme"})
+	defer os.RemoveAll(repodir)
+
+	items := testImportItems(repodir, 5)
+	statuses, err := runTestImport(items, repodir, ImportOptions{BatchSize: 2}, fakeAddBatch(t, 0))
+	if err != nil {
+		t.Fatalf("Import failed: %s", err)
+	}
+	// initial status and one per batch
+	if len(statuses) != 4 {
+		t.Fatalf("Expected 4 status messages, got %d", len(statuses))
+	}
+	if s := statuses[0]; s.Batch != 0 || s.NBatches != 3 || s.Total != 5 || s.Imported != 0 {
+		t.Fatalf("Unexpected initial status: %+v", s)
+	}
+	for idx, s := range statuses[1:] {
+		if s.Batch != idx+1 || s.NBatches != 3 {
+			t.Fatalf("Unexpected status for batch %d: %+v", idx+1, s)
+		}
+	}
+	if s := statuses[3]; s.Files != 1 || s.Imported != 5 {
+		t.Fatalf("Unexpected status for last batch: %+v", s)
+	}
+	if n := countCommits(t); n != 4 {
+		t.Fatalf("Expected 4 commits (1 initial and 3 batches), got %d", n)
+	}
+	for _, item := range items {
+		if !pathExists(item.Dest) {
+			t.Fatalf("File '%s' was not imported", item.Dest)
+		}
+	}
+	if _, ok := readImportState(t, repodir); ok {
+		t.Fatalf("Import state file was not removed after a complete import")
+	}
+}
+
+func TestRunImportRefusesExisting(t *testing.T) {
+	repodir := setupGitRepo(t, map[string]string{"data/file03": "user data"})
+	defer os.RemoveAll(repodir)
+
+	items := testImportItems(repodir, 5)
+	_, err := runTestImport(items, repodir, ImportOptions{BatchSize: 2}, fakeAddBatch(t, 0))
+	if err == nil || !strings.Contains(err.Error(), "refusing to overwrite") {
+		t.Fatalf("Import of an existing file should fail: %v", err)
+	}
+	if n := countCommits(t); n != 1 {
+		t.Fatalf("Refused import created commits")
+	}
+	content, _ := ioutil.ReadFile(filepath.Join(repodir, "data", "file03"))
+	if string(content) != "user data" {
+		t.Fatalf("Existing file was modified: %q", string(content))
+	}
+}
+
+func TestRunImportRefusesStaged(t *testing.T) {
+	repodir := setupGitRepo(t, map[string]string{"README.md": "readme"})
+	defer os.RemoveAll(repodir)
+	gitAdd := func(fname string) {
+		cmd := git.Command("add", "--", fname)
+		if _, stderr, err := cmd.OutputError(); err != nil {
+			t.Fatalf("git add failed: %s", string(stderr))
+		}
+	}
+
+	// changes staged by the user would be committed with the first batch
+	ioutil.WriteFile(filepath.Join(repodir, "README.md"), []byte("staged change"), 0644)
+	gitAdd("README.md")
+	items := testImportItems(repodir, 3)
+	opts := ImportOptions{BatchSize: 2}
+	_, err := runTestImport(items, repodir, opts, fakeAddBatch(t, 0))
+	if err == nil || !strings.Contains(err.Error(), "changes to 'README.md' are staged") {
+		t.Fatalf("Import with staged user changes should fail: %v", err)
+	}
+	if n := countCommits(t); n != 1 {
+		t.Fatalf("Refused import created commits")
+	}
+	if staged, _ := git.StagedFiles(); strings.Join(staged, ",") != "README.md" {
+		t.Fatalf("Staged user changes were modified: %v", staged)
+	}
+	cmd := git.Command("commit", "-m", "user change")
+	if _, stderr, err := cmd.OutputError(); err != nil {
+		t.Fatalf("git commit failed: %s", string(stderr))
+	}
+
+	// files of the interrupted batch may already be staged when resuming
+	_, err = runTestImport(items, repodir, opts, fakeAddBatch(t, 1))
+	if err == nil {
+		t.Fatalf("Interrupted import should fail")
+	}
+	gitAdd(filepath.Join("data", "file00"))
+	if _, err = runTestImport(items, repodir, opts, fakeAddBatch(t, 0)); err != nil {
+		t.Fatalf("Resumed import with staged in-progress file failed: %s", err)
+	}
+	if n := countCommits(t); n != 4 {
+		t.Fatalf("Expected 4 commits (1 initial, 1 user and 2 batches), got %d", n)
+	}
+}
+
+func TestRunImportResume(t *testing.T) {
+	repodir := setupGitRepo(t, map[string]string{"README.md": "readme"})
+	defer os.RemoveAll(repodir)
+
+	items := testImportItems(repodir, 5)
+	opts := ImportOptions{BatchSize: 2}
+	// interrupted after writing the first file of the second batch
+	_, err := runTestImport(items, repodir, opts, fakeAddBatch(t, 2))
+	if err == nil {
+		t.Fatalf("Interrupted import should fail")
+	}
+	state, ok := readImportState(t, repodir)
+	if !ok {
+		t.Fatalf("Import state file missing after interrupted import")
+	}
+	if strings.Join(state.Imported, ",") != "file00,file01" {
+		t.Fatalf("Unexpected imported files in state: %v", state.Imported)
+	}
+	if strings.Join(state.InProgress, ",") != "file02,file03" {
+		t.Fatalf("Unexpected in-progress files in state: %v", state.InProgress)
+	}
+
+	// a file created by the user in the meantime must not be replaced
+	userfile := filepath.Join(repodir, "data", "file04")
+	ioutil.WriteFile(userfile, []byte("user data"), 0644)
+	_, err = runTestImport(items, repodir, opts, fakeAddBatch(t, 0))
+	if err == nil || !strings.Contains(err.Error(), "refusing to overwrite") {
+		t.Fatalf("Resumed import should refuse to replace a file it did not write: %v", err)
+	}
+	content, _ := ioutil.ReadFile(userfile)
+	if string(content) != "user data" {
+		t.Fatalf("File created by the user was modified: %q", string(content))
+	}
+	os.Remove(userfile)
+
+	// a different batch size keeps the unfinished files recorded until they are imported
+	opts.BatchSize = 1
+	statuses, err := runTestImport(items, repodir, opts, fakeAddBatch(t, 0))
+	if err != nil {
+		t.Fatalf("Resumed import failed: %s", err)
+	}
+	if s := statuses[0]; s.NBatches != 3 || s.Imported != 2 || s.Total != 5 {
+		t.Fatalf("Unexpected initial status of resumed import: %+v", s)
+	}
+	if n := countCommits(t); n != 5 {
+		t.Fatalf("Expected 5 commits (1 initial, 1 before and 3 after the interruption), got %d", n)
+	}
+	for _, item := range items {
+		content, _ := ioutil.ReadFile(item.Dest)
+		if string(content) != item.Source {
+			t.Fatalf("Unexpected content of '%s': %q", item.Dest, string(content))
+		}
+	}
+	if _, ok := readImportState(t, repodir); ok {
+		t.Fatalf("Import state file was not removed after resumed import completed")
+	}
+}
+
+func TestPlaceFilesResume(t *testing.T) {
+	srcdir, err := ioutil.TempDir("", "gin-cli-test-import-src-")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(srcdir)
+	destdir, err := ioutil.TempDir("", "gin-cli-test-import-dest-")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(destdir)
+
+	ioutil.WriteFile(filepath.Join(srcdir, "partial"), []byte("source"), 0644)
+	// partial: copied incompletely before the interruption
+	// moved: moved before the interruption (no source left)
+	ioutil.WriteFile(filepath.Join(destdir, "partial"), []byte("sou"), 0644)
+	ioutil.WriteFile(filepath.Join(destdir, "moved"), []byte("moved"), 0644)
+	batch := []importItem{
+		{Source: "partial", Dest: filepath.Join(destdir, "partial")},
+		{Source: "moved", Dest: filepath.Join(destdir, "moved")},
+	}
+	written := map[string]bool{"partial": true, "moved": true}
+	destpaths, err := placeFiles(srcdir, batch, "move", os.Rename, written)
+	if err != nil {
+		t.Fatalf("Placing files failed: %s", err)
+	}
+	if len(destpaths) != 2 {
+		t.Fatalf("Expected 2 destination paths, got %v", destpaths)
+	}
+	for fname, expected := range map[string]string{"partial": "source", "moved": "moved"} {
+		content, _ := ioutil.ReadFile(filepath.Join(destdir, fname))
+		if string(content) != expected {
+			t.Fatalf("Unexpected content of '%s': %q (expected %q)", fname, string(content), expected)
+		}
+	}
+	if pathExists(filepath.Join(srcdir, "partial")) {
+		t.Fatalf("Source file was not moved")
+	}
+}
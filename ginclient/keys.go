@@ -0,0 +1,349 @@
+package ginclient
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/G-Node/gin-cli/ginclient/config"
+	"github.com/G-Node/gin-cli/ginclient/log"
+	"github.com/G-Node/gin-cli/git"
+	gogs "github.com/gogits/go-gogs-client"
+)
+
+// Functions for managing the keys used for accessing the git server: the session keys that are created on login and the user's own keys (SSH agent and identity files).
+
+// KeyPassphraseEnvVar is the environment variable that holds the passphrase for encrypted session keys.
+const KeyPassphraseEnvVar = "GIN_KEY_PASSPHRASE"
+
+// PassphrasePrompt is used to ask for the passphrase of encrypted session keys when the KeyPassphraseEnvVar environment variable is not set.
+// If it is nil, the environment variable is required.
+var PassphrasePrompt func(prompt string) (string, error)
+
+// keyPassphrase returns the passphrase for encrypting or decrypting session keys.
+// If confirm is true and the passphrase is prompted for, it must be entered twice.
+func keyPassphrase(confirm bool) (string, error) {
+	if passphrase := os.Getenv(KeyPassphraseEnvVar); passphrase != "" {
+		return passphrase, nil
+	}
+	if PassphrasePrompt == nil {
+		return "", fmt.Errorf("no passphrase for the session key: set the %s environment variable", KeyPassphraseEnvVar)
+	}
+	passphrase, err := PassphrasePrompt("Session key passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if passphrase == "" {
+		return "", fmt.Errorf("no passphrase provided")
+	}
+	if confirm {
+		repeat, err := PassphrasePrompt("Repeat passphrase: ")
+		if err != nil {
+			return "", err
+		}
+		if repeat != passphrase {
+			return "", fmt.Errorf("passphrases do not match")
+		}
+	}
+	return passphrase, nil
+}
+
+// sessionKeyPath returns the path of the private session key file for the server in the credentials directory of the active profile.
+// The public key is stored next to it, with the .pub extension.
+func (gincl *Client) sessionKeyPath(create bool) (string, error) {
+	configpath, err := config.CredentialsPath(create)
+	if err != nil {
+		log.Write("Could not create config directory for private key")
+		return "", err
+	}
+	return filepath.Join(configpath, fmt.Sprintf("%s.key", gincl.srvalias)), nil
+}
+
+// newSessionKey creates a key pair with the configured type and size.
+// The private key is encrypted with a passphrase if encryption is enabled in the configuration.
+func newSessionKey() (*git.KeyPair, error) {
+	keyconf := config.Read().Keys
+	var passphrase string
+	if keyconf.Encrypt {
+		var err error
+		if passphrase, err = keyPassphrase(true); err != nil {
+			return nil, err
+		}
+	}
+	return git.MakeKeyPair(keyconf.Type, keyconf.Bits, passphrase)
+}
+
+// writeSessionKey writes the private key to the given path and the public key next to it.
+// Encrypted keys are added to the SSH agent, if one is running, so that git commands do not ask for the passphrase.
+func writeSessionKey(keyfilepath string, keyPair *git.KeyPair, description string) error {
+	if err := ioutil.WriteFile(keyfilepath, []byte(keyPair.Private), 0600); err != nil {
+		return err
+	}
+	pubkey := fmt.Sprintf("%s %s\n", strings.TrimSpace(keyPair.Public), description)
+	if err := ioutil.WriteFile(keyfilepath+".pub", []byte(pubkey), 0644); err != nil {
+		return err
+	}
+	if git.KeyEncrypted([]byte(keyPair.Private)) && git.AgentAvailable() {
+		if err := keyPair.AddToAgent(description, 0); err != nil {
+			log.Write("Failed to add session key to SSH agent: %s", err)
+		}
+	}
+	return nil
+}
+
+// SessionKeyEncrypted returns true if the session key for the server is protected by a passphrase.
+func (gincl *Client) SessionKeyEncrypted() bool {
+	keyfilepath, err := gincl.sessionKeyPath(false)
+	if err != nil {
+		return false
+	}
+	private, err := ioutil.ReadFile(keyfilepath)
+	return err == nil && git.KeyEncrypted(private)
+}
+
+// LoadSessionKey decrypts the session key for the server and adds it to the SSH agent.
+// The key is removed from the agent after lifetime seconds, or kept until the agent exits if lifetime is 0.
+func (gincl *Client) LoadSessionKey(lifetime uint32) error {
+	keyfilepath, err := gincl.sessionKeyPath(false)
+	if err != nil {
+		return err
+	}
+	private, err := ioutil.ReadFile(keyfilepath)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("no session key for server '%s': log in first", gincl.srvalias)
+	} else if err != nil {
+		return err
+	}
+	if !git.AgentAvailable() {
+		return fmt.Errorf("no SSH agent running (SSH_AUTH_SOCK is not set or the agent is not reachable)")
+	}
+	var passphrase string
+	if git.KeyEncrypted(private) {
+		if passphrase, err = keyPassphrase(false); err != nil {
+			return err
+		}
+	}
+	keyPair, err := git.ParseKeyPair(private, passphrase)
+	if err != nil {
+		return err
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = unknownhostname
+	}
+	return keyPair.AddToAgent(sessionKeyTitle(gincl.Username, hostname), lifetime)
+}
+
+// DeletePubKeyByContent removes the key that matches the given public key from the current user's authorised keys.
+// Only the key type and data are compared; the comment is ignored.
+func (gincl *Client) DeletePubKeyByContent(pubkey string) error {
+	fields := strings.Fields(pubkey)
+	if len(fields) < 2 {
+		return fmt.Errorf("invalid public key")
+	}
+	log.Write("Searching for key with content '%s %s'", fields[0], fields[1])
+	keys, err := gincl.GetUserKeys()
+	if err != nil {
+		log.Write("Error when getting user keys: %v", err)
+		return err
+	}
+	for _, key := range keys {
+		if git.SamePublicKey(key.Key, pubkey) {
+			return gincl.DeletePubKey(key.ID)
+		}
+	}
+	return fmt.Errorf("No key matching the session key")
+}
+
+// UserKeysConfigured returns true if the SSH agent or identity files are configured for the server, so that git commands can use the user's own keys instead of a session key.
+func (gincl *Client) UserKeysConfigured() bool {
+	gitconf := config.Read().Servers[gincl.srvalias].Git
+	return gitconf.UseAgent || len(gitconf.IdentityFiles) > 0
+}
+
+// UserPublicKeys returns the public keys of the user's own keys that are offered to the server: the keys held by the SSH agent, if agent use is enabled, and those of the configured identity files.
+// Identity files whose public key cannot be read are skipped.
+func (gincl *Client) UserPublicKeys() ([]string, error) {
+	gitconf := config.Read().Servers[gincl.srvalias].Git
+	var pubkeys []string
+	if gitconf.UseAgent {
+		agentkeys, err := git.AgentPublicKeys()
+		if err != nil {
+			return nil, err
+		}
+		pubkeys = append(pubkeys, agentkeys...)
+	}
+	for _, fname := range gitconf.IdentityFiles {
+		if fname, err := config.ExpandHome(fname); err == nil {
+			pubkey, err := git.PublicKeyFromFile(fname)
+			if err != nil {
+				log.Write(err.Error())
+				continue
+			}
+			pubkeys = append(pubkeys, pubkey)
+		}
+	}
+	return pubkeys, nil
+}
+
+// FindRegisteredKey returns the first key registered with the user's account on the server that matches one of the user's own keys (see UserPublicKeys).
+func (gincl *Client) FindRegisteredKey() (gogs.PublicKey, error) {
+	pubkeys, err := gincl.UserPublicKeys()
+	if err != nil {
+		return gogs.PublicKey{}, err
+	}
+	if len(pubkeys) == 0 {
+		return gogs.PublicKey{}, fmt.Errorf("no keys found in the SSH agent or the identity files configured for server '%s'", gincl.srvalias)
+	}
+	userkeys, err := gincl.GetUserKeys()
+	if err != nil {
+		return gogs.PublicKey{}, err
+	}
+	for _, key := range userkeys {
+		for _, pubkey := range pubkeys {
+			if git.SamePublicKey(key.Key, pubkey) {
+				return key, nil
+			}
+		}
+	}
+	return gogs.PublicKey{}, fmt.Errorf("none of your keys (%d found) is registered with your account on server '%s'", len(pubkeys), gincl.srvalias)
+}
+
+// RegisteredSessionKey returns the key registered with the user's account on the server that matches the session key.
+// Keys created by earlier versions, which have no public key file, are matched by title.
+func (gincl *Client) RegisteredSessionKey() (gogs.PublicKey, error) {
+	keyfilepath, err := gincl.sessionKeyPath(false)
+	if err != nil {
+		return gogs.PublicKey{}, err
+	}
+	if !pathExists(keyfilepath) {
+		return gogs.PublicKey{}, fmt.Errorf("no session key for server '%s'", gincl.srvalias)
+	}
+	pubkey, perr := ioutil.ReadFile(keyfilepath + ".pub")
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = unknownhostname
+	}
+	title := sessionKeyTitle(gincl.Username, hostname)
+	userkeys, err := gincl.GetUserKeys()
+	if err != nil {
+		return gogs.PublicKey{}, err
+	}
+	for _, key := range userkeys {
+		if perr == nil && git.SamePublicKey(key.Key, string(pubkey)) || perr != nil && key.Title == title {
+			return key, nil
+		}
+	}
+	return gogs.PublicKey{}, fmt.Errorf("the session key for server '%s' is not registered with your account", gincl.srvalias)
+}
+
+// RotateSessionKey replaces the session key for the server in 4 steps:
+// 1. Create a new key pair and add the public key to the server.
+// 2. Replace the local key files, keeping the old ones as a backup.
+// 3. Verify access to the git server with the new key, by listing the references of one of the user's repositories.
+// 4. Delete the old public key from the server and remove the backup.
+// If verification fails, the new key is removed from the server and the old key files are restored.
+// It returns the title of the new key.
+func (gincl *Client) RotateSessionKey() (string, error) {
+	keyfilepath, err := gincl.sessionKeyPath(true)
+	if err != nil {
+		return "", err
+	}
+	oldPrivate, err := ioutil.ReadFile(keyfilepath)
+	if os.IsNotExist(err) {
+		return "", fmt.Errorf("no session key for server '%s': log in first", gincl.srvalias)
+	} else if err != nil {
+		return "", err
+	}
+	// the public key is only needed for finding the old key on the server; it is read from the .pub file to avoid decrypting the private key
+	var oldPublic string
+	if pubBytes, perr := ioutil.ReadFile(keyfilepath + ".pub"); perr == nil {
+		oldPublic = string(pubBytes)
+	} else if oldKeyPair, perr := git.ParseKeyPair(oldPrivate, ""); perr == nil {
+		oldPublic = oldKeyPair.Public
+	}
+
+	// find a repository for verifying access before changing anything
+	repos, err := gincl.ListRepos(gincl.Username)
+	if err != nil {
+		return "", err
+	}
+	if len(repos) == 0 {
+		return "", fmt.Errorf("key rotation requires a repository owned by '%s' for verifying access with the new key", gincl.Username)
+	}
+	// the key is only used with the SSH transport, so access is verified through the git server regardless of the configured transport
+	remote := fmt.Sprintf("%s/%s", config.Read().Servers[gincl.srvalias].Git.AddressStr(), repos[0].FullName)
+
+	// 1. Create and upload the new key
+	keyPair, err := newSessionKey()
+	if err != nil {
+		return "", err
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		log.Write("Could not retrieve hostname")
+		hostname = unknownhostname
+	}
+	oldTitle := sessionKeyTitle(gincl.Username, hostname)
+	title := fmt.Sprintf("%s (rotated %s)", oldTitle, time.Now().Format("2006-01-02 15:04:05"))
+	pubkey := fmt.Sprintf("%s %s", strings.TrimSpace(keyPair.Public), title)
+	if err = gincl.AddKey(pubkey, title, false); err != nil {
+		return "", err
+	}
+
+	// 2. Replace the key files
+	backups := []string{keyfilepath, keyfilepath + ".pub"}
+	restore := func() {
+		for _, fname := range backups {
+			os.Rename(fname+".old", fname)
+		}
+	}
+	for _, fname := range backups {
+		if !pathExists(fname) {
+			continue
+		}
+		if err = os.Rename(fname, fname+".old"); err != nil {
+			restore()
+			gincl.DeletePubKeyByContent(pubkey)
+			return "", err
+		}
+	}
+	if err = writeSessionKey(keyfilepath, keyPair, title); err != nil {
+		restore()
+		gincl.DeletePubKeyByContent(pubkey)
+		return "", err
+	}
+
+	// 3. Verify access with the new key (the old key files are not used by git while they are renamed)
+	// the user's own keys are disabled, so that they cannot grant access instead of the new key
+	git.SessionKeysOnly = true
+	_, err = git.LsRemote(remote)
+	git.SessionKeysOnly = false
+	if err != nil {
+		log.Write("Access verification with the new key failed: %s", err)
+		restore()
+		if delerr := gincl.DeletePubKeyByContent(pubkey); delerr != nil {
+			log.Write("Failed to delete new key from server: %s", delerr)
+		}
+		return "", fmt.Errorf("failed to verify access to %s with the new key; the old key is still in use", remote)
+	}
+
+	// 4. Delete the old key from the server
+	if oldPublic != "" {
+		err = gincl.DeletePubKeyByContent(oldPublic)
+	} else {
+		err = gincl.DeletePubKeyByTitle(oldTitle)
+	}
+	if err != nil {
+		// the new key works: keep it and report the old key, which should be deleted manually
+		log.Write("Failed to delete old key from server: %s", err)
+		err = fmt.Errorf("the new key is in use, but the old key could not be deleted from the server (use 'gin keys --delete'): %s", err)
+	}
+	for _, fname := range backups {
+		os.Remove(fname + ".old")
+	}
+	return title, err
+}
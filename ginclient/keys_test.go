@@ -0,0 +1,117 @@
+package ginclient
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/G-Node/gin-cli/git"
+	"github.com/G-Node/gin-cli/web"
+	gogs "github.com/gogits/go-gogs-client"
+)
+
+// keyServer is a fake server that holds the SSH keys of a user and records the requests that change them.
+type keyServer struct {
+	keys    []gogs.PublicKey
+	nextID  int64
+	failAdd bool
+	ops     []string
+}
+
+func (srv *keyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/api/v1/user/keys":
+		json.NewEncoder(w).Encode(srv.keys)
+	case r.Method == http.MethodPost && r.URL.Path == "/api/v1/user/keys":
+		srv.ops = append(srv.ops, "add")
+		var key gogs.PublicKey
+		json.NewDecoder(r.Body).Decode(&key)
+		for _, k := range srv.keys {
+			// titles and keys must be unique
+			if k.Title == key.Title || git.SamePublicKey(k.Key, key.Key) {
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				return
+			}
+		}
+		if srv.failAdd {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		srv.nextID++
+		key.ID = srv.nextID
+		srv.keys = append(srv.keys, key)
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/api/v1/user/keys/"):
+		srv.ops = append(srv.ops, "delete")
+		id, _ := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, "/api/v1/user/keys/"), 10, 64)
+		for idx, k := range srv.keys {
+			if k.ID == id {
+				srv.keys = append(srv.keys[:idx], srv.keys[idx+1:]...)
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestMakeSessionKey(t *testing.T) {
+	keysrv := &keyServer{}
+	cleanup := testServer(t, "keytest", keysrv)
+	defer cleanup()
+
+	gincl := New("keytest")
+	gincl.UserToken = web.UserToken{Username: "alice", Token: "validtoken"}
+	keyfilepath, err := gincl.sessionKeyPath(true)
+	if err != nil {
+		t.Fatalf("failed to get session key path: %v", err)
+	}
+	readPublic := func() string {
+		pubkey, err := ioutil.ReadFile(keyfilepath + ".pub")
+		if err != nil {
+			t.Fatalf("failed to read public key file: %v", err)
+		}
+		return string(pubkey)
+	}
+
+	if err = gincl.MakeSessionKey(); err != nil {
+		t.Fatalf("failed to create session key: %v", err)
+	}
+	if len(keysrv.keys) != 1 || !git.SamePublicKey(keysrv.keys[0].Key, readPublic()) {
+		t.Fatalf("session key not registered: %+v", keysrv.keys)
+	}
+
+	// a new key replaces the previous one, which is only deleted after the new key has been added
+	keysrv.ops = nil
+	oldPublic := readPublic()
+	if err = gincl.MakeSessionKey(); err != nil {
+		t.Fatalf("failed to replace session key: %v", err)
+	}
+	if strings.Join(keysrv.ops, ",") != "add,delete" {
+		t.Errorf("expected the new key to be added before the old one is deleted, got %v", keysrv.ops)
+	}
+	newPublic := readPublic()
+	if git.SamePublicKey(oldPublic, newPublic) {
+		t.Fatalf("session key was not replaced")
+	}
+	if len(keysrv.keys) != 1 || !git.SamePublicKey(keysrv.keys[0].Key, newPublic) {
+		t.Fatalf("expected only the new session key on the server, got %+v", keysrv.keys)
+	}
+
+	// if the new key cannot be added, the previous key is kept on the server and locally
+	keysrv.failAdd = true
+	if err = gincl.MakeSessionKey(); err == nil {
+		t.Fatalf("session key creation should fail when the server rejects the key")
+	}
+	if len(keysrv.keys) != 1 || !git.SamePublicKey(keysrv.keys[0].Key, newPublic) {
+		t.Errorf("previous session key removed from the server: %+v", keysrv.keys)
+	}
+	if !git.SamePublicKey(readPublic(), newPublic) {
+		t.Errorf("previous session key files replaced after failure")
+	}
+}
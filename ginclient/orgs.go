@@ -0,0 +1,77 @@
+package ginclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/G-Node/gin-cli/ginclient/log"
+	"github.com/G-Node/gin-cli/web"
+	gogs "github.com/gogits/go-gogs-client"
+)
+
+// High level functions for querying organisations and their teams.
+
+// ListOrgs retrieves the organisations the logged in user is a member of.
+func (gincl *Client) ListOrgs() ([]gogs.Organization, error) {
+	fn := "ListOrgs()"
+	log.Write("Retrieving organisation list")
+	var orgs []gogs.Organization
+	res, err := gincl.Get("/api/v1/user/orgs")
+	if err != nil {
+		return nil, err // return error from Get() directly
+	}
+	switch code := res.StatusCode; {
+	case code == http.StatusUnauthorized:
+		return nil, ginerror{UError: res.Status, Origin: fn, Description: "authorisation failed"}
+	case code == http.StatusInternalServerError:
+		return nil, ginerror{UError: res.Status, Origin: fn, Description: "server error"}
+	case code != http.StatusOK:
+		return nil, ginerror{UError: res.Status, Origin: fn} // Unexpected error
+	}
+	defer web.CloseRes(res.Body)
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, ginerror{UError: err.Error(), Origin: fn, Description: "failed to read response body"}
+	}
+	err = json.Unmarshal(b, &orgs)
+	if err != nil {
+		return nil, ginerror{UError: err.Error(), Origin: fn, Description: "failed to parse response body"}
+	}
+	return orgs, nil
+}
+
+// ListTeams retrieves the teams of an organisation.
+// Only members of the organisation can list its teams.
+func (gincl *Client) ListTeams(org string) ([]gogs.Team, error) {
+	fn := fmt.Sprintf("ListTeams(%s)", org)
+	log.Write("Retrieving team list")
+	var teams []gogs.Team
+	res, err := gincl.Get(fmt.Sprintf("/api/v1/orgs/%s/teams", org))
+	if err != nil {
+		return nil, err // return error from Get() directly
+	}
+	switch code := res.StatusCode; {
+	case code == http.StatusForbidden:
+		return nil, ginerror{UError: res.Status, Origin: fn, Description: fmt.Sprintf("you are not a member of organisation '%s'", org)}
+	case code == http.StatusNotFound:
+		return nil, ginerror{UError: res.Status, Origin: fn, Description: fmt.Sprintf("organisation '%s' does not exist", org)}
+	case code == http.StatusUnauthorized:
+		return nil, ginerror{UError: res.Status, Origin: fn, Description: "authorisation failed"}
+	case code == http.StatusInternalServerError:
+		return nil, ginerror{UError: res.Status, Origin: fn, Description: "server error"}
+	case code != http.StatusOK:
+		return nil, ginerror{UError: res.Status, Origin: fn} // Unexpected error
+	}
+	defer web.CloseRes(res.Body)
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, ginerror{UError: err.Error(), Origin: fn, Description: "failed to read response body"}
+	}
+	err = json.Unmarshal(b, &teams)
+	if err != nil {
+		return nil, ginerror{UError: err.Error(), Origin: fn, Description: "failed to parse response body"}
+	}
+	return teams, nil
+}
@@ -0,0 +1,534 @@
+package ginclient
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/G-Node/gin-cli/ginclient/log"
+	"github.com/G-Node/gin-cli/git"
+	gogs "github.com/gogits/go-gogs-client"
+	"gopkg.in/yaml.v2"
+)
+
+// Functions for packaging a repository revision as a BagIt bag (RFC 8493) and/or an RO-Crate (1.1), and for validating such packages.
+
+const (
+	bagitFileName    = "bagit.txt"
+	bagInfoFileName  = "bag-info.txt"
+	bagPayloadDir    = "data"
+	roCrateFileName  = "ro-crate-metadata.json"
+	roCrateContext   = "https://w3id.org/ro/crate/1.1/context"
+	roCrateConformTo = "https://w3id.org/ro/crate/1.1"
+)
+
+// PackageOptions holds the options for creating a package with PackageRevision.
+type PackageOptions struct {
+	// BagIt creates a BagIt bag.
+	BagIt bool
+	// ROCrate creates an RO-Crate. If BagIt is also set, the crate is the payload of the bag.
+	ROCrate bool
+	// Algorithm is the checksum algorithm for manifests ("sha256" or "md5").
+	Algorithm string
+	// Repository holds the information of the repository on the server, if available.
+	Repository *gogs.Repository
+	// Name is used as the title of the package if neither the DataCite file nor the repository information is available.
+	Name string
+}
+
+// dirArchive writes archive entries as files into a directory.
+type dirArchive struct {
+	root    string
+	modtime time.Time
+}
+
+func (da *dirArchive) addFile(name string, mode os.FileMode, size int64, r io.Reader) error {
+	fpath := filepath.Join(da.root, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(fpath), 0777); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(fpath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode.Perm())
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Chtimes(fpath, da.modtime, da.modtime)
+}
+
+func (da *dirArchive) addLink(name, target string) error {
+	fpath := filepath.Join(da.root, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(fpath), 0777); err != nil {
+		return err
+	}
+	return os.Symlink(target, fpath)
+}
+
+func (da *dirArchive) Close() error {
+	return nil
+}
+
+// readDataCiteAt reads the DataCite file of the repository at the given revision.
+// It returns nil if the file does not exist or cannot be parsed.
+func readDataCiteAt(commithash string) *DataCite {
+	content, err := git.CatFileContents(commithash, DataCiteFileName)
+	if err != nil {
+		return nil
+	}
+	var dc DataCite
+	if err = yaml.Unmarshal(content, &dc); err != nil {
+		return nil
+	}
+	return &dc
+}
+
+// PackageRevision writes the files specified by paths, as they were at the given revision, into a new package directory at outdir.
+// Manifests are built from the annex keys where the key backend matches the checksum algorithm, without rehashing the content.
+// Metadata is taken from the DataCite file at the revision and from the repository information in the options.
+// The status of each file is sent on the pkgchan channel, which is closed when the function returns.
+// If any file fails, the package metadata is not written and outdir is removed, so that no incomplete package is left behind.
+func PackageRevision(revision string, paths []string, filter func(string) bool, outdir string, opts PackageOptions, pkgchan chan<- FileCheckoutStatus) {
+	defer close(pkgchan)
+	if !opts.BagIt && !opts.ROCrate {
+		pkgchan <- FileCheckoutStatus{Err: fmt.Errorf("no package type specified")}
+		return
+	}
+	commit, err := resolveRevision(revision)
+	if err != nil {
+		pkgchan <- FileCheckoutStatus{Err: err}
+		return
+	}
+	if _, err = os.Stat(outdir); err == nil {
+		pkgchan <- FileCheckoutStatus{Err: fmt.Errorf("output location '%s' already exists", outdir)}
+		return
+	}
+	if err = os.MkdirAll(outdir, 0777); err != nil {
+		pkgchan <- FileCheckoutStatus{Err: err}
+		return
+	}
+
+	archive := &dirArchive{root: outdir, modtime: commit.Date}
+	prefix := ""
+	if opts.BagIt {
+		prefix = bagPayloadDir
+	}
+	var files []exportedFile
+	filechan := make(chan FileCheckoutStatus)
+	go func() {
+		defer close(filechan)
+		files = writeRevision(commit.Hash, paths, filter, opts.Algorithm, archive, prefix, filechan)
+	}()
+	failed := false
+	for status := range filechan {
+		failed = failed || status.Err != nil
+		pkgchan <- status
+	}
+	dc := readDataCiteAt(commit.Hash)
+
+	if opts.ROCrate && !failed {
+		status := FileCheckoutStatus{Filename: roCrateFileName, Type: "Metadata", Destination: path.Join(prefix, roCrateFileName)}
+		var crate []byte
+		crate, status.Err = makeROCrate(files, opts, dc, commit)
+		if status.Err == nil {
+			status.Err = archive.addFile(status.Destination, 0644, int64(len(crate)), bytes.NewReader(crate))
+		}
+		if status.Err == nil && opts.BagIt {
+			// The crate metadata file is part of the bag payload
+			hasher, _ := newHash(opts.Algorithm)
+			hasher.Write(crate)
+			files = append(files, exportedFile{Name: roCrateFileName, Size: int64(len(crate)), Checksum: hex.EncodeToString(hasher.Sum(nil))})
+		}
+		pkgchan <- status
+		failed = status.Err != nil
+	}
+
+	if opts.BagIt && !failed {
+		err = writeBagFiles(outdir, files, opts, dc, commit)
+		pkgchan <- FileCheckoutStatus{Filename: bagitFileName, Type: "Metadata", Destination: bagitFileName, Err: err}
+		failed = err != nil
+	}
+	if failed {
+		// metadata describing only part of the files would make a valid looking package
+		if err = os.RemoveAll(outdir); err != nil {
+			log.Write("Failed to remove incomplete package: %s", err)
+		}
+	}
+}
+
+// bagPath encodes a payload path for a BagIt manifest line.
+func bagPath(name string) string {
+	name = strings.Replace(name, "%", "%25", -1)
+	name = strings.Replace(name, "\n", "%0A", -1)
+	name = strings.Replace(name, "\r", "%0D", -1)
+	return path.Join(bagPayloadDir, name)
+}
+
+// bagInfoValue collapses whitespace in a value so that it fits on a single bag-info line.
+func bagInfoValue(value string) string {
+	return strings.Join(strings.Fields(value), " ")
+}
+
+func writeBagFiles(outdir string, files []exportedFile, opts PackageOptions, dc *DataCite, commit git.GinCommit) error {
+	algorithm, repo := opts.Algorithm, opts.Repository
+	tagfiles := make(map[string][]byte)
+
+	tagfiles[bagitFileName] = []byte("BagIt-Version: 1.0\nTag-File-Character-Encoding: UTF-8\n")
+
+	var manifest bytes.Buffer
+	var octets int64
+	for _, f := range files {
+		fmt.Fprintf(&manifest, "%s  %s\n", f.Checksum, bagPath(f.Name))
+		octets += f.Size
+	}
+	manifestName := fmt.Sprintf("manifest-%s.txt", algorithm)
+	tagfiles[manifestName] = manifest.Bytes()
+
+	var info bytes.Buffer
+	addInfo := func(label, value string) {
+		if value = bagInfoValue(value); value != "" {
+			fmt.Fprintf(&info, "%s: %s\n", label, value)
+		}
+	}
+	if dc != nil {
+		addInfo("Title", dc.Title)
+		addInfo("External-Description", dc.Description)
+		for _, author := range dc.Authors {
+			addInfo("Author", fmt.Sprintf("%s %s", author.FirstName, author.LastName))
+		}
+		if len(dc.Authors) > 0 {
+			addInfo("Source-Organization", dc.Authors[0].Affiliation)
+		}
+		addInfo("Keywords", strings.Join(dc.Keywords, ", "))
+		if dc.License != nil {
+			addInfo("License", fmt.Sprintf("%s (%s)", dc.License.Name, dc.License.URL))
+		}
+	} else if repo != nil {
+		addInfo("Title", repo.FullName)
+		addInfo("External-Description", repo.Description)
+	} else {
+		addInfo("Title", opts.Name)
+	}
+	if repo != nil {
+		addInfo("External-Identifier", repo.HTMLURL)
+		if repo.Owner != nil {
+			addInfo("Contact-Name", repo.Owner.FullName)
+			addInfo("Contact-Email", repo.Owner.Email)
+		}
+	}
+	addInfo("Internal-Sender-Identifier", commit.Hash)
+	addInfo("Bagging-Date", time.Now().Format("2006-01-02"))
+	addInfo("Payload-Oxum", fmt.Sprintf("%d.%d", octets, len(files)))
+	addInfo("Bag-Software-Agent", "gin-cli")
+	tagfiles[bagInfoFileName] = info.Bytes()
+
+	var tagmanifest bytes.Buffer
+	for _, name := range []string{bagitFileName, bagInfoFileName, manifestName} {
+		content := tagfiles[name]
+		if err := ioutil.WriteFile(filepath.Join(outdir, name), content, 0644); err != nil {
+			return err
+		}
+		hasher, err := newHash(algorithm)
+		if err != nil {
+			return err
+		}
+		hasher.Write(content)
+		fmt.Fprintf(&tagmanifest, "%s  %s\n", hex.EncodeToString(hasher.Sum(nil)), name)
+	}
+	return ioutil.WriteFile(filepath.Join(outdir, fmt.Sprintf("tagmanifest-%s.txt", algorithm)), tagmanifest.Bytes(), 0644)
+}
+
+// roEntity is a JSON-LD entity in the RO-Crate metadata graph.
+type roEntity map[string]interface{}
+
+func roRef(id string) map[string]string {
+	return map[string]string{"@id": id}
+}
+
+func makeROCrate(files []exportedFile, opts PackageOptions, dc *DataCite, commit git.GinCommit) ([]byte, error) {
+	algorithm, repo := opts.Algorithm, opts.Repository
+	root := roEntity{
+		"@id":           "./",
+		"@type":         "Dataset",
+		"name":          opts.Name,
+		"datePublished": commit.Date.Format(time.RFC3339),
+		"version":       commit.Hash,
+	}
+	graph := []roEntity{
+		{
+			"@id":        roCrateFileName,
+			"@type":      "CreativeWork",
+			"conformsTo": roRef(roCrateConformTo),
+			"about":      roRef("./"),
+		},
+		root,
+	}
+
+	if repo != nil {
+		root["name"] = repo.FullName
+		root["description"] = repo.Description
+		root["url"] = repo.HTMLURL
+	}
+	if dc != nil {
+		root["name"] = dc.Title
+		root["description"] = strings.TrimSpace(dc.Description)
+		root["keywords"] = strings.Join(dc.Keywords, ", ")
+		var authors []map[string]string
+		for idx, author := range dc.Authors {
+			id := fmt.Sprintf("#author-%d", idx+1)
+			if prefix, value := splitPrefix(author.ID); prefix == "orcid" {
+				id = fmt.Sprintf("https://orcid.org/%s", value)
+			}
+			person := roEntity{"@id": id, "@type": "Person", "name": strings.TrimSpace(fmt.Sprintf("%s %s", author.FirstName, author.LastName))}
+			if author.Affiliation != "" {
+				person["affiliation"] = author.Affiliation
+			}
+			graph = append(graph, person)
+			authors = append(authors, roRef(id))
+		}
+		if len(authors) > 0 {
+			root["author"] = authors
+		}
+		if dc.License != nil && dc.License.URL != "" {
+			root["license"] = roRef(dc.License.URL)
+			graph = append(graph, roEntity{"@id": dc.License.URL, "@type": "CreativeWork", "name": dc.License.Name})
+		}
+	}
+
+	var parts []map[string]string
+	for _, f := range files {
+		parts = append(parts, roRef(f.Name))
+		graph = append(graph, roEntity{"@id": f.Name, "@type": "File", "contentSize": strconv.FormatInt(f.Size, 10), algorithm: f.Checksum})
+	}
+	root["hasPart"] = parts
+
+	return json.MarshalIndent(map[string]interface{}{"@context": roCrateContext, "@graph": graph}, "", "  ")
+}
+
+// fileChecksum computes the checksum of a file with the given algorithm.
+func fileChecksum(fpath, algorithm string) (string, error) {
+	hasher, err := newHash(algorithm)
+	if err != nil {
+		return "", err
+	}
+	f, err := os.Open(fpath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if _, err = io.Copy(hasher, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// readManifest reads a BagIt manifest file and returns a map of paths to checksums.
+func readManifest(fpath string) (map[string]string, error) {
+	f, err := os.Open(fpath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	entries := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.SplitN(strings.TrimSpace(scanner.Text()), " ", 2)
+		if len(fields) != 2 {
+			continue
+		}
+		name := strings.TrimSpace(fields[1])
+		name = strings.Replace(name, "%0A", "\n", -1)
+		name = strings.Replace(name, "%0D", "\r", -1)
+		name = strings.Replace(name, "%25", "%", -1)
+		entries[name] = strings.ToLower(fields[0])
+	}
+	return entries, scanner.Err()
+}
+
+// checkManifest verifies the checksums of the files listed in a manifest relative to dir and returns the problems found and the listed entries.
+func checkManifest(dir, manifestName, algorithm string) ([]string, map[string]string) {
+	var problems []string
+	if _, err := newHash(algorithm); err != nil {
+		return []string{fmt.Sprintf("%s: unsupported checksum algorithm '%s'", manifestName, algorithm)}, nil
+	}
+	entries, err := readManifest(filepath.Join(dir, manifestName))
+	if err != nil {
+		return []string{fmt.Sprintf("could not read %s: %s", manifestName, err)}, nil
+	}
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		checksum, err := fileChecksum(filepath.Join(dir, filepath.FromSlash(name)), algorithm)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: file '%s' is missing or unreadable", manifestName, name))
+			continue
+		}
+		if checksum != entries[name] {
+			problems = append(problems, fmt.Sprintf("%s: checksum mismatch for '%s'", manifestName, name))
+		}
+	}
+	return problems, entries
+}
+
+// validateBag checks a BagIt bag for completeness and correct checksums.
+func validateBag(dir string) []string {
+	var problems []string
+	bagit, err := ioutil.ReadFile(filepath.Join(dir, bagitFileName))
+	if err != nil || !bytes.Contains(bagit, []byte("BagIt-Version:")) || !bytes.Contains(bagit, []byte("Tag-File-Character-Encoding:")) {
+		problems = append(problems, fmt.Sprintf("%s is missing or incomplete", bagitFileName))
+	}
+
+	manifests, _ := filepath.Glob(filepath.Join(dir, "manifest-*.txt"))
+	if len(manifests) == 0 {
+		return append(problems, "no payload manifest found")
+	}
+	// entries of each readable payload manifest
+	payloadEntries := make(map[string]map[string]string)
+	for _, mpath := range manifests {
+		mname := filepath.Base(mpath)
+		algorithm := strings.TrimSuffix(strings.TrimPrefix(mname, "manifest-"), ".txt")
+		mproblems, entries := checkManifest(dir, mname, algorithm)
+		problems = append(problems, mproblems...)
+		if entries != nil {
+			payloadEntries[mname] = entries
+		}
+	}
+	mnames := make([]string, 0, len(payloadEntries))
+	for mname := range payloadEntries {
+		mnames = append(mnames, mname)
+	}
+	sort.Strings(mnames)
+	tagmanifests, _ := filepath.Glob(filepath.Join(dir, "tagmanifest-*.txt"))
+	for _, mpath := range tagmanifests {
+		mname := filepath.Base(mpath)
+		algorithm := strings.TrimSuffix(strings.TrimPrefix(mname, "tagmanifest-"), ".txt")
+		mproblems, _ := checkManifest(dir, mname, algorithm)
+		problems = append(problems, mproblems...)
+	}
+
+	// every payload file must be listed in every payload manifest
+	var octets, count int64
+	filepath.Walk(filepath.Join(dir, bagPayloadDir), func(fpath string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || info.Mode()&os.ModeSymlink != 0 {
+			return nil
+		}
+		rel, _ := filepath.Rel(dir, fpath)
+		rel = filepath.ToSlash(rel)
+		for _, mname := range mnames {
+			if _, ok := payloadEntries[mname][rel]; !ok {
+				problems = append(problems, fmt.Sprintf("payload file '%s' is not listed in %s", rel, mname))
+			}
+		}
+		octets += info.Size()
+		count++
+		return nil
+	})
+
+	if info, err := ioutil.ReadFile(filepath.Join(dir, bagInfoFileName)); err == nil {
+		for _, line := range strings.Split(string(info), "\n") {
+			if !strings.HasPrefix(line, "Payload-Oxum:") {
+				continue
+			}
+			oxum := strings.TrimSpace(strings.TrimPrefix(line, "Payload-Oxum:"))
+			if expected := fmt.Sprintf("%d.%d", octets, count); oxum != expected {
+				problems = append(problems, fmt.Sprintf("Payload-Oxum is %s but payload is %s", oxum, expected))
+			}
+		}
+	}
+	return problems
+}
+
+// validateROCrate checks the RO-Crate metadata file in dir for the required entities and verifies the files it lists.
+func validateROCrate(dir string) []string {
+	var problems []string
+	b, err := ioutil.ReadFile(filepath.Join(dir, roCrateFileName))
+	if err != nil {
+		return []string{fmt.Sprintf("could not read %s: %s", roCrateFileName, err)}
+	}
+	var crate struct {
+		Context interface{}              `json:"@context"`
+		Graph   []map[string]interface{} `json:"@graph"`
+	}
+	if err = json.Unmarshal(b, &crate); err != nil {
+		return []string{fmt.Sprintf("%s is not valid JSON: %s", roCrateFileName, err)}
+	}
+	if crate.Context == nil {
+		problems = append(problems, fmt.Sprintf("%s has no @context", roCrateFileName))
+	}
+	var hasDescriptor, hasRoot bool
+	for _, entity := range crate.Graph {
+		id, _ := entity["@id"].(string)
+		etype, _ := entity["@type"].(string)
+		switch {
+		case id == roCrateFileName:
+			hasDescriptor = true
+		case id == "./":
+			hasRoot = true
+			if name, _ := entity["name"].(string); name == "" {
+				problems = append(problems, "root dataset has no name")
+			}
+		case etype == "File" && !strings.Contains(id, "://") && !strings.HasPrefix(id, "#"):
+			fpath := filepath.Join(dir, filepath.FromSlash(id))
+			for _, algorithm := range []string{"sha256", "md5"} {
+				expected, ok := entity[algorithm].(string)
+				if !ok {
+					continue
+				}
+				checksum, err := fileChecksum(fpath, algorithm)
+				if err != nil {
+					problems = append(problems, fmt.Sprintf("file '%s' listed in %s is missing or unreadable", id, roCrateFileName))
+				} else if checksum != expected {
+					problems = append(problems, fmt.Sprintf("checksum mismatch for '%s'", id))
+				}
+				break
+			}
+		}
+	}
+	if !hasDescriptor {
+		problems = append(problems, fmt.Sprintf("metadata descriptor entity '%s' is missing", roCrateFileName))
+	}
+	if !hasRoot {
+		problems = append(problems, "root dataset entity './' is missing")
+	}
+	return problems
+}
+
+// ValidatePackage checks a package directory created by PackageRevision (or any other tool).
+// A BagIt bag is detected by its bagit.txt file and an RO-Crate by its metadata file, either at the top level or in the payload of a bag.
+// It returns the detected package types and the list of problems found.
+func ValidatePackage(dir string) ([]string, []string) {
+	var types, problems []string
+	if _, err := os.Stat(filepath.Join(dir, bagitFileName)); err == nil {
+		types = append(types, "BagIt")
+		problems = append(problems, validateBag(dir)...)
+	}
+	for _, cratedir := range []string{dir, filepath.Join(dir, bagPayloadDir)} {
+		if _, err := os.Stat(filepath.Join(cratedir, roCrateFileName)); err == nil {
+			types = append(types, "RO-Crate")
+			problems = append(problems, validateROCrate(cratedir)...)
+			break
+		}
+	}
+	if len(types) == 0 {
+		problems = append(problems, fmt.Sprintf("'%s' is neither a BagIt bag nor an RO-Crate", dir))
+	}
+	return types, problems
+}
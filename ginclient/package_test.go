@@ -0,0 +1,110 @@
+package ginclient
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// hasProblem returns true if one of the problems contains the given text.
+func hasProblem(problems []string, text string) bool {
+	for _, problem := range problems {
+		if strings.Contains(problem, text) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestPackageValidate(t *testing.T) {
+	files := map[string]string{
+		"README.md":       "# Test dataset\n",
+		"data/values.csv": "1,2,3\n",
+	}
+	repodir := setupGitRepo(t, files)
+	defer os.RemoveAll(repodir)
+	outdir, _ := ioutil.TempDir("", "gin-cli-test-package-")
+	os.RemoveAll(outdir)
+	defer os.RemoveAll(outdir)
+
+	pkgchan := make(chan FileCheckoutStatus)
+	opts := PackageOptions{BagIt: true, ROCrate: true, Algorithm: "sha256", Name: "test"}
+	go PackageRevision("HEAD", nil, nil, outdir, opts, pkgchan)
+	for status := range pkgchan {
+		if status.Err != nil {
+			t.Fatalf("Packaging of '%s' failed: %s", status.Filename, status.Err)
+		}
+	}
+
+	types, problems := ValidatePackage(outdir)
+	if len(types) != 2 {
+		t.Errorf("Expected BagIt and RO-Crate package, detected %v", types)
+	}
+	if len(problems) > 0 {
+		t.Fatalf("Valid package has problems: %v", problems)
+	}
+
+	// a second payload manifest that does not list all payload files
+	md5manifest := filepath.Join(outdir, "manifest-md5.txt")
+	ioutil.WriteFile(md5manifest, []byte("2b6a5e5a3e1c7c1b0b4c0e5f9a2c1d30  data/README.md\n"), 0644)
+	_, problems = ValidatePackage(outdir)
+	if !hasProblem(problems, "'data/data/values.csv' is not listed in manifest-md5.txt") {
+		t.Errorf("Incomplete second manifest not reported: %v", problems)
+	}
+	os.Remove(md5manifest)
+
+	sha1manifest := filepath.Join(outdir, "manifest-sha1.txt")
+	ioutil.WriteFile(sha1manifest, []byte("da39a3ee5e6b4b0d3255bfef95601890afd80709  data/README.md\n"), 0644)
+	_, problems = ValidatePackage(outdir)
+	if !hasProblem(problems, "unsupported checksum algorithm 'sha1'") {
+		t.Errorf("Unsupported manifest algorithm not reported: %v", problems)
+	}
+	os.Remove(sha1manifest)
+
+	ioutil.WriteFile(filepath.Join(outdir, "data", "data", "values.csv"), []byte("1,2,4\n"), 0644)
+	_, problems = ValidatePackage(outdir)
+	if !hasProblem(problems, "checksum mismatch for 'data/data/values.csv'") {
+		t.Errorf("Modified file not reported: %v", problems)
+	}
+
+	os.Remove(filepath.Join(outdir, "data", "README.md"))
+	_, problems = ValidatePackage(outdir)
+	if !hasProblem(problems, "file 'data/README.md' is missing") {
+		t.Errorf("Missing file not reported: %v", problems)
+	}
+}
+
+func TestPackageFailedFile(t *testing.T) {
+	files := map[string]string{
+		"README.md": "# Test dataset\n",
+		// annex pointer without content in the repository
+		"data/missing.bin": "/annex/objects/SHA256E-s5--0000000000000000000000000000000000000000000000000000000000000000.bin\n",
+	}
+	repodir := setupGitRepo(t, files)
+	defer os.RemoveAll(repodir)
+	outdir, _ := ioutil.TempDir("", "gin-cli-test-package-")
+	os.RemoveAll(outdir)
+	defer os.RemoveAll(outdir)
+
+	pkgchan := make(chan FileCheckoutStatus)
+	opts := PackageOptions{BagIt: true, ROCrate: true, Algorithm: "sha256", Name: "test"}
+	go PackageRevision("HEAD", nil, nil, outdir, opts, pkgchan)
+	var failed []string
+	for status := range pkgchan {
+		if status.Err != nil {
+			failed = append(failed, status.Filename)
+		}
+	}
+	if len(failed) != 1 || failed[0] != "data/missing.bin" {
+		t.Errorf("Expected only 'data/missing.bin' to fail, failed: %v", failed)
+	}
+
+	if _, err := os.Stat(outdir); !os.IsNotExist(err) {
+		t.Errorf("Incomplete package directory left behind: %v", err)
+	}
+	if _, problems := ValidatePackage(outdir); len(problems) == 0 {
+		t.Errorf("Incomplete package passes validation")
+	}
+}
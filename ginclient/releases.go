@@ -0,0 +1,118 @@
+package ginclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/G-Node/gin-cli/ginclient/log"
+	"github.com/G-Node/gin-cli/web"
+	gogs "github.com/gogits/go-gogs-client"
+)
+
+// High level functions for managing repository releases on the server.
+
+// CreateReleaseOption holds the information for creating a new release.
+type CreateReleaseOption struct {
+	TagName         string `json:"tag_name"`
+	TargetCommitish string `json:"target_commitish,omitempty"`
+	Name            string `json:"name,omitempty"`
+	Body            string `json:"body,omitempty"`
+	Draft           bool   `json:"draft"`
+	Prerelease      bool   `json:"prerelease"`
+}
+
+// ListReleases retrieves the releases of a repository.
+func (gincl *Client) ListReleases(repoPath string) ([]gogs.Release, error) {
+	fn := fmt.Sprintf("ListReleases(%s)", repoPath)
+	log.Write("Retrieving release list")
+	var releases []gogs.Release
+	res, err := gincl.Get(fmt.Sprintf("/api/v1/repos/%s/releases", repoPath))
+	if err != nil {
+		return nil, err // return error from Get() directly
+	}
+	switch code := res.StatusCode; {
+	case code == http.StatusNotFound:
+		return nil, ginerror{UError: res.Status, Origin: fn, Description: fmt.Sprintf("repository '%s' does not exist", repoPath)}
+	case code == http.StatusUnauthorized:
+		return nil, ginerror{UError: res.Status, Origin: fn, Description: "authorisation failed"}
+	case code == http.StatusInternalServerError:
+		return nil, ginerror{UError: res.Status, Origin: fn, Description: "server error"}
+	case code != http.StatusOK:
+		return nil, ginerror{UError: res.Status, Origin: fn} // Unexpected error
+	}
+	defer web.CloseRes(res.Body)
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, ginerror{UError: err.Error(), Origin: fn, Description: "failed to read response body"}
+	}
+	err = json.Unmarshal(b, &releases)
+	if err != nil {
+		return nil, ginerror{UError: err.Error(), Origin: fn, Description: "failed to parse response body"}
+	}
+	return releases, nil
+}
+
+// CreateRelease creates a new release for a repository and returns the new release information.
+// If the tag does not exist on the server, it is created at the target commit.
+func (gincl *Client) CreateRelease(repoPath string, opt CreateReleaseOption) (gogs.Release, error) {
+	fn := fmt.Sprintf("CreateRelease(%s, %s)", repoPath, opt.TagName)
+	log.Write("Creating release")
+	var release gogs.Release
+	res, err := gincl.Post(fmt.Sprintf("/api/v1/repos/%s/releases", repoPath), opt)
+	if err != nil {
+		return release, err // return error from Post() directly
+	}
+	switch code := res.StatusCode; {
+	case code == http.StatusForbidden:
+		return release, ginerror{UError: res.Status, Origin: fn, Description: fmt.Sprintf("you do not have permission to create releases for '%s'", repoPath)}
+	case code == http.StatusNotFound:
+		return release, ginerror{UError: res.Status, Origin: fn, Description: fmt.Sprintf("repository '%s' does not exist", repoPath)}
+	case code == http.StatusConflict:
+		return release, ginerror{UError: res.Status, Origin: fn, Description: fmt.Sprintf("a release for tag '%s' already exists", opt.TagName)}
+	case code == http.StatusUnprocessableEntity:
+		return release, ginerror{UError: res.Status, Origin: fn, Description: "invalid tag name or target"}
+	case code == http.StatusUnauthorized:
+		return release, ginerror{UError: res.Status, Origin: fn, Description: "authorisation failed"}
+	case code == http.StatusInternalServerError:
+		return release, ginerror{UError: res.Status, Origin: fn, Description: "server error"}
+	case code != http.StatusCreated && code != http.StatusOK:
+		return release, ginerror{UError: res.Status, Origin: fn} // Unexpected error
+	}
+	defer web.CloseRes(res.Body)
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return release, ginerror{UError: err.Error(), Origin: fn, Description: "failed to read response body"}
+	}
+	err = json.Unmarshal(b, &release)
+	if err != nil {
+		return release, ginerror{UError: err.Error(), Origin: fn, Description: "failed to parse response body"}
+	}
+	return release, nil
+}
+
+// DeleteRelease deletes the release with the given ID from a repository.
+// The tag the release refers to is not deleted.
+func (gincl *Client) DeleteRelease(repoPath string, id int64) error {
+	fn := fmt.Sprintf("DeleteRelease(%s, %d)", repoPath, id)
+	log.Write("Deleting release")
+	res, err := gincl.Delete(fmt.Sprintf("/api/v1/repos/%s/releases/%d", repoPath, id))
+	if err != nil {
+		return err // return error from Delete() directly
+	}
+	switch code := res.StatusCode; {
+	case code == http.StatusForbidden:
+		return ginerror{UError: res.Status, Origin: fn, Description: fmt.Sprintf("you do not have permission to delete releases of '%s'", repoPath)}
+	case code == http.StatusNotFound:
+		return ginerror{UError: res.Status, Origin: fn, Description: "release does not exist"}
+	case code == http.StatusUnauthorized:
+		return ginerror{UError: res.Status, Origin: fn, Description: "authorisation failed"}
+	case code == http.StatusInternalServerError:
+		return ginerror{UError: res.Status, Origin: fn, Description: "server error"}
+	case code != http.StatusNoContent:
+		return ginerror{UError: res.Status, Origin: fn} // Unexpected error
+	}
+	web.CloseRes(res.Body)
+	return nil
+}
@@ -5,12 +5,15 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"os"
 	"os/user"
 	"path"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/G-Node/gin-cli/ginclient/config"
 	"github.com/G-Node/gin-cli/ginclient/log"
@@ -82,11 +85,21 @@ func isAnnexPath(path string) bool {
 	return strings.Contains(path, "/annex/objects")
 }
 
-// MakeSessionKey creates a private+public key pair.
-// The private key is saved in the user's configuration directory, to be used for git commands.
-// The public key is added to the GIN server for the current logged in user.
+// sessionKeyTitle returns the title of the public key created by MakeSessionKey.
+// Keys of named profiles include the profile name, so that the keys of different profiles on the same machine can be told apart.
+func sessionKeyTitle(username, hostname string) string {
+	if profile, _ := config.ActiveProfile(); profile != config.DefaultProfile {
+		return fmt.Sprintf("GIN Client: %s@%s (%s)", username, hostname, profile)
+	}
+	return fmt.Sprintf("GIN Client: %s@%s", username, hostname)
+}
+
+// MakeSessionKey creates a private+public key pair with the configured type and size.
+// The private key is saved in the credentials directory of the active profile, to be used for git commands, and encrypted if key encryption is enabled.
+// The public key is added to the GIN server for the current logged in user, replacing the previous session key of the profile if there is one.
+// The previous key is only deleted from the server after the new key has been added and saved, so that a failure does not leave the profile without a working key.
 func (gincl *Client) MakeSessionKey() error {
-	keyPair, err := git.MakeKeyPair()
+	keyPair, err := newSessionKey()
 	if err != nil {
 		return err
 	}
@@ -96,21 +109,52 @@ func (gincl *Client) MakeSessionKey() error {
 		log.Write("Could not retrieve hostname")
 		hostname = unknownhostname
 	}
-	description := fmt.Sprintf("GIN Client: %s@%s", gincl.Username, hostname)
-	pubkey := fmt.Sprintf("%s %s", strings.TrimSpace(keyPair.Public), description)
-	err = gincl.AddKey(pubkey, description, true)
+	keyfilepath, err := gincl.sessionKeyPath(true)
 	if err != nil {
 		return err
 	}
-
-	configpath, err := config.Path(true)
+	description := sessionKeyTitle(gincl.Username, hostname)
+	oldPublic, perr := ioutil.ReadFile(keyfilepath + ".pub")
+	userkeys, err := gincl.GetUserKeys()
 	if err != nil {
-		log.Write("Could not create config directory for private key")
+		log.Write("Error when getting user keys: %v", err)
+	}
+	// the previous key is matched by content, or by title if it was created by an earlier version without a public key file
+	var oldKey *gogs.PublicKey
+	for idx, key := range userkeys {
+		if perr == nil && git.SamePublicKey(key.Key, string(oldPublic)) || perr != nil && key.Title == description {
+			oldKey = &userkeys[idx]
+			break
+		}
+	}
+	title := description
+	for _, key := range userkeys {
+		if key.Title != description {
+			continue
+		}
+		if oldKey != nil && key.ID == oldKey.ID {
+			// key titles must be unique; the new key gets a different title while both keys exist
+			title = fmt.Sprintf("%s (%s)", description, time.Now().Format("2006-01-02 15:04:05"))
+		} else if err = gincl.DeletePubKey(key.ID); err != nil {
+			// a stale key with the same title, whose private key is not available
+			log.Write("Failed to delete key with title '%s': %s", description, err)
+		}
+	}
+	pubkey := fmt.Sprintf("%s %s", strings.TrimSpace(keyPair.Public), title)
+	if err = gincl.AddKey(pubkey, title, false); err != nil {
 		return err
 	}
-	keyfilepath := filepath.Join(configpath, fmt.Sprintf("%s.key", gincl.srvalias))
-	ioutil.WriteFile(keyfilepath, []byte(keyPair.Private), 0600)
-
+	if err = writeSessionKey(keyfilepath, keyPair, title); err != nil {
+		if delerr := gincl.DeletePubKeyByContent(pubkey); delerr != nil {
+			log.Write("Failed to delete new key from server: %s", delerr)
+		}
+		return err
+	}
+	if oldKey != nil {
+		if err = gincl.DeletePubKey(oldKey.ID); err != nil {
+			log.Write("Failed to delete previous session key from server: %s", err)
+		}
+	}
 	return nil
 }
 
@@ -150,16 +194,27 @@ func (gincl *Client) GetRepo(repoPath string) (gogs.Repository, error) {
 func (gincl *Client) ListRepos(user string) ([]gogs.Repository, error) {
 	fn := fmt.Sprintf("ListRepos(%s)", user)
 	log.Write("Retrieving repo list")
+	return gincl.getRepoList(fn, fmt.Sprintf("/api/v1/users/%s/repos", user), fmt.Sprintf("user '%s' does not exist", user))
+}
+
+// ListOrgRepos gets a list of the repositories owned by an organisation that the logged in user can access.
+func (gincl *Client) ListOrgRepos(org string) ([]gogs.Repository, error) {
+	fn := fmt.Sprintf("ListOrgRepos(%s)", org)
+	log.Write("Retrieving organisation repo list")
+	return gincl.getRepoList(fn, fmt.Sprintf("/api/v1/orgs/%s/repos", org), fmt.Sprintf("organisation '%s' does not exist", org))
+}
+
+// getRepoList performs the request for a repository listing at the given address.
+// The notfound message is used as the error description when the server responds with 404.
+func (gincl *Client) getRepoList(fn, address, notfound string) ([]gogs.Repository, error) {
 	var repoList []gogs.Repository
-	var res *http.Response
-	var err error
-	res, err = gincl.Get(fmt.Sprintf("/api/v1/users/%s/repos", user))
+	res, err := gincl.Get(address)
 	if err != nil {
 		return nil, err // return error from Get() directly
 	}
 	switch code := res.StatusCode; {
 	case code == http.StatusNotFound:
-		return nil, ginerror{UError: res.Status, Origin: fn, Description: fmt.Sprintf("user '%s' does not exist", user)}
+		return nil, ginerror{UError: res.Status, Origin: fn, Description: notfound}
 	case code == http.StatusUnauthorized:
 		return nil, ginerror{UError: res.Status, Origin: fn, Description: "authorisation failed"}
 	case code == http.StatusInternalServerError:
@@ -179,19 +234,95 @@ func (gincl *Client) ListRepos(user string) ([]gogs.Repository, error) {
 	return repoList, nil
 }
 
+// searchPageSize is the number of results requested per page when searching.
+// Servers may cap the page size, so this should not exceed the server's maximum (50 for Gogs).
+const searchPageSize = 50
+
+// SearchRepos searches the server for repositories matching the query that are accessible to the logged in user.
+// If ownerID is not zero, only repositories owned by the user or organisation with the given ID are returned.
+// All pages of results are retrieved.
+func (gincl *Client) SearchRepos(query string, ownerID int64) ([]gogs.Repository, error) {
+	fn := fmt.Sprintf("SearchRepos(%s, %d)", query, ownerID)
+	log.Write("Searching repositories")
+	var results []gogs.Repository
+	for page := 1; ; page++ {
+		params := url.Values{}
+		params.Set("q", query)
+		params.Set("limit", strconv.Itoa(searchPageSize))
+		params.Set("page", strconv.Itoa(page))
+		if ownerID != 0 {
+			params.Set("uid", strconv.FormatInt(ownerID, 10))
+		}
+		res, err := gincl.Get(fmt.Sprintf("/api/v1/repos/search?%s", params.Encode()))
+		if err != nil {
+			return nil, err // return error from Get() directly
+		}
+		switch code := res.StatusCode; {
+		case code == http.StatusUnprocessableEntity:
+			return nil, ginerror{UError: res.Status, Origin: fn, Description: "invalid search query"}
+		case code == http.StatusUnauthorized:
+			return nil, ginerror{UError: res.Status, Origin: fn, Description: "authorisation failed"}
+		case code == http.StatusInternalServerError:
+			return nil, ginerror{UError: res.Status, Origin: fn, Description: "server error"}
+		case code != http.StatusOK:
+			return nil, ginerror{UError: res.Status, Origin: fn} // Unexpected error
+		}
+		b, err := ioutil.ReadAll(res.Body)
+		web.CloseRes(res.Body)
+		if err != nil {
+			return nil, ginerror{UError: err.Error(), Origin: fn, Description: "failed to read response body"}
+		}
+		var searchres struct {
+			Data []gogs.Repository `json:"data"`
+			OK   bool              `json:"ok"`
+		}
+		err = json.Unmarshal(b, &searchres)
+		if err != nil {
+			return nil, ginerror{UError: err.Error(), Origin: fn, Description: "failed to parse response body"}
+		}
+		if !searchres.OK {
+			return nil, ginerror{UError: string(b), Origin: fn, Description: "search failed"}
+		}
+		results = append(results, searchres.Data...)
+		if len(searchres.Data) < searchPageSize {
+			// last page
+			break
+		}
+	}
+	log.Write("Search returned %d results", len(results))
+	return results, nil
+}
+
 // CreateRepo creates a repository on the server.
 func (gincl *Client) CreateRepo(name, description string) error {
-	fn := fmt.Sprintf("CreateRepo(name)")
+	fn := fmt.Sprintf("CreateRepo(%s)", name)
 	log.Write("Creating repository")
+	return gincl.postNewRepo(fn, "/api/v1/user/repos", name, description)
+}
+
+// CreateOrgRepo creates a repository on the server, owned by the given organisation.
+// The logged in user must be an owner of the organisation or a member of a team with repository creation rights.
+func (gincl *Client) CreateOrgRepo(org, name, description string) error {
+	fn := fmt.Sprintf("CreateOrgRepo(%s, %s)", org, name)
+	log.Write("Creating organisation repository")
+	return gincl.postNewRepo(fn, fmt.Sprintf("/api/v1/org/%s/repos", org), name, description)
+}
+
+// postNewRepo performs the request for creating a new (private) repository at the given address.
+func (gincl *Client) postNewRepo(fn, address, name, description string) error {
 	newrepo := gogs.CreateRepoOption{Name: name, Description: description, Private: true}
 	log.Write("Name: %s :: Description: %s", name, description)
-	res, err := gincl.Post("/api/v1/user/repos", newrepo)
+	res, err := gincl.Post(address, newrepo)
 	if err != nil {
 		return err // return error from Post() directly
 	}
 	switch code := res.StatusCode; {
 	case code == http.StatusUnprocessableEntity:
 		return ginerror{UError: res.Status, Origin: fn, Description: "invalid repository name or repository with the same name already exists"}
+	case code == http.StatusForbidden:
+		return ginerror{UError: res.Status, Origin: fn, Description: "you do not have permission to create repositories for this owner"}
+	case code == http.StatusNotFound:
+		return ginerror{UError: res.Status, Origin: fn, Description: "repository owner does not exist"}
 	case code == http.StatusUnauthorized:
 		return ginerror{UError: res.Status, Origin: fn, Description: "authorisation failed"}
 	case code == http.StatusInternalServerError:
@@ -229,6 +360,215 @@ func (gincl *Client) DelRepo(name string) error {
 	return nil
 }
 
+// ForkRepo creates a fork of a repository and returns the information of the new repository.
+// The fork is owned by the logged in user, or by the given organisation if org is not empty.
+func (gincl *Client) ForkRepo(repoPath, org string) (gogs.Repository, error) {
+	fn := fmt.Sprintf("ForkRepo(%s, %s)", repoPath, org)
+	log.Write("Forking repository")
+	var repo gogs.Repository
+	forkopt := struct {
+		Organization string `json:"organization,omitempty"`
+	}{org}
+	res, err := gincl.Post(fmt.Sprintf("/api/v1/repos/%s/forks", repoPath), forkopt)
+	if err != nil {
+		return repo, err // return error from Post() directly
+	}
+	switch code := res.StatusCode; {
+	case code == http.StatusForbidden:
+		return repo, ginerror{UError: res.Status, Origin: fn, Description: "you do not have permission to create repositories for this owner"}
+	case code == http.StatusNotFound:
+		return repo, ginerror{UError: res.Status, Origin: fn, Description: fmt.Sprintf("repository '%s' does not exist", repoPath)}
+	case code == http.StatusConflict, code == http.StatusUnprocessableEntity:
+		return repo, ginerror{UError: res.Status, Origin: fn, Description: "a fork or repository with the same name already exists"}
+	case code == http.StatusUnauthorized:
+		return repo, ginerror{UError: res.Status, Origin: fn, Description: "authorisation failed"}
+	case code == http.StatusInternalServerError:
+		return repo, ginerror{UError: res.Status, Origin: fn, Description: "server error"}
+	case code != http.StatusOK && code != http.StatusCreated && code != http.StatusAccepted:
+		return repo, ginerror{UError: res.Status, Origin: fn} // Unexpected error
+	}
+	defer web.CloseRes(res.Body)
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return repo, ginerror{UError: err.Error(), Origin: fn, Description: "failed to read response body"}
+	}
+	err = json.Unmarshal(b, &repo)
+	if err != nil {
+		return repo, ginerror{UError: err.Error(), Origin: fn, Description: "failed to parse response body"}
+	}
+	return repo, nil
+}
+
+// TransferRepo moves a repository to a new owner (user or organisation) and returns the information of the moved repository.
+// Transferring a repository requires administrative access to it.
+func (gincl *Client) TransferRepo(repoPath, newOwner string) (gogs.Repository, error) {
+	fn := fmt.Sprintf("TransferRepo(%s, %s)", repoPath, newOwner)
+	log.Write("Transferring repository")
+	var repo gogs.Repository
+	transferopt := struct {
+		NewOwner string `json:"new_owner"`
+	}{newOwner}
+	res, err := gincl.Post(fmt.Sprintf("/api/v1/repos/%s/transfer", repoPath), transferopt)
+	if err != nil {
+		return repo, err // return error from Post() directly
+	}
+	switch code := res.StatusCode; {
+	case code == http.StatusForbidden:
+		return repo, ginerror{UError: res.Status, Origin: fn, Description: fmt.Sprintf("you do not have permission to transfer '%s' to '%s'", repoPath, newOwner)}
+	case code == http.StatusNotFound:
+		return repo, ginerror{UError: res.Status, Origin: fn, Description: fmt.Sprintf("repository '%s' or owner '%s' does not exist", repoPath, newOwner)}
+	case code == http.StatusConflict, code == http.StatusUnprocessableEntity:
+		return repo, ginerror{UError: res.Status, Origin: fn, Description: fmt.Sprintf("'%s' already has a repository with the same name", newOwner)}
+	case code == http.StatusUnauthorized:
+		return repo, ginerror{UError: res.Status, Origin: fn, Description: "authorisation failed"}
+	case code == http.StatusInternalServerError:
+		return repo, ginerror{UError: res.Status, Origin: fn, Description: "server error"}
+	case code != http.StatusOK && code != http.StatusCreated && code != http.StatusAccepted:
+		return repo, ginerror{UError: res.Status, Origin: fn} // Unexpected error
+	}
+	defer web.CloseRes(res.Body)
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return repo, ginerror{UError: err.Error(), Origin: fn, Description: "failed to read response body"}
+	}
+	err = json.Unmarshal(b, &repo)
+	if err != nil {
+		return repo, ginerror{UError: err.Error(), Origin: fn, Description: "failed to parse response body"}
+	}
+	return repo, nil
+}
+
+// EditRepoOption holds the repository settings to change with EditRepo.
+// Fields that are nil are left unchanged on the server.
+type EditRepoOption struct {
+	Name        *string `json:"name,omitempty"`
+	Description *string `json:"description,omitempty"`
+	Website     *string `json:"website,omitempty"`
+	Private     *bool   `json:"private,omitempty"`
+}
+
+// EditRepo changes the settings of a repository on the server and returns the updated repository information.
+func (gincl *Client) EditRepo(repoPath string, opt EditRepoOption) (gogs.Repository, error) {
+	fn := fmt.Sprintf("EditRepo(%s)", repoPath)
+	log.Write("Editing repository settings")
+	var repo gogs.Repository
+	res, err := gincl.Patch(fmt.Sprintf("/api/v1/repos/%s", repoPath), opt)
+	if err != nil {
+		return repo, err // return error from Patch() directly
+	}
+	switch code := res.StatusCode; {
+	case code == http.StatusForbidden:
+		return repo, ginerror{UError: res.Status, Origin: fn, Description: fmt.Sprintf("you do not have permission to change the settings of '%s'", repoPath)}
+	case code == http.StatusNotFound:
+		return repo, ginerror{UError: res.Status, Origin: fn, Description: fmt.Sprintf("repository '%s' does not exist", repoPath)}
+	case code == http.StatusUnprocessableEntity:
+		return repo, ginerror{UError: res.Status, Origin: fn, Description: "invalid repository name or repository with the same name already exists"}
+	case code == http.StatusUnauthorized:
+		return repo, ginerror{UError: res.Status, Origin: fn, Description: "authorisation failed"}
+	case code == http.StatusInternalServerError:
+		return repo, ginerror{UError: res.Status, Origin: fn, Description: "server error"}
+	case code != http.StatusOK:
+		return repo, ginerror{UError: res.Status, Origin: fn} // Unexpected error
+	}
+	defer web.CloseRes(res.Body)
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return repo, ginerror{UError: err.Error(), Origin: fn, Description: "failed to read response body"}
+	}
+	err = json.Unmarshal(b, &repo)
+	if err != nil {
+		return repo, ginerror{UError: err.Error(), Origin: fn, Description: "failed to parse response body"}
+	}
+	return repo, nil
+}
+
+// ListCollaborators retrieves the list of users that have been given access to a repository.
+func (gincl *Client) ListCollaborators(repoPath string) ([]gogs.Collaborator, error) {
+	fn := fmt.Sprintf("ListCollaborators(%s)", repoPath)
+	log.Write("Retrieving collaborator list")
+	var collaborators []gogs.Collaborator
+	res, err := gincl.Get(fmt.Sprintf("/api/v1/repos/%s/collaborators", repoPath))
+	if err != nil {
+		return nil, err // return error from Get() directly
+	}
+	switch code := res.StatusCode; {
+	case code == http.StatusForbidden:
+		return nil, ginerror{UError: res.Status, Origin: fn, Description: fmt.Sprintf("you do not have permission to view the collaborators of '%s'", repoPath)}
+	case code == http.StatusNotFound:
+		return nil, ginerror{UError: res.Status, Origin: fn, Description: fmt.Sprintf("repository '%s' does not exist", repoPath)}
+	case code == http.StatusUnauthorized:
+		return nil, ginerror{UError: res.Status, Origin: fn, Description: "authorisation failed"}
+	case code == http.StatusInternalServerError:
+		return nil, ginerror{UError: res.Status, Origin: fn, Description: "server error"}
+	case code != http.StatusOK:
+		return nil, ginerror{UError: res.Status, Origin: fn} // Unexpected error
+	}
+	defer web.CloseRes(res.Body)
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, ginerror{UError: err.Error(), Origin: fn, Description: "failed to read response body"}
+	}
+	err = json.Unmarshal(b, &collaborators)
+	if err != nil {
+		return nil, ginerror{UError: err.Error(), Origin: fn, Description: "failed to parse response body"}
+	}
+	return collaborators, nil
+}
+
+// AddCollaborator gives a user access to a repository with the given permission level (read, write, or admin).
+// If the user is already a collaborator, their permission level is updated.
+func (gincl *Client) AddCollaborator(repoPath, username, permission string) error {
+	fn := fmt.Sprintf("AddCollaborator(%s, %s, %s)", repoPath, username, permission)
+	log.Write("Adding collaborator")
+	opt := gogs.AddCollaboratorOption{Permission: &permission}
+	res, err := gincl.Put(fmt.Sprintf("/api/v1/repos/%s/collaborators/%s", repoPath, username), opt)
+	if err != nil {
+		return err // return error from Put() directly
+	}
+	switch code := res.StatusCode; {
+	case code == http.StatusForbidden:
+		return ginerror{UError: res.Status, Origin: fn, Description: fmt.Sprintf("you do not have permission to manage the collaborators of '%s'", repoPath)}
+	case code == http.StatusNotFound:
+		return ginerror{UError: res.Status, Origin: fn, Description: fmt.Sprintf("repository '%s' or user '%s' does not exist", repoPath, username)}
+	case code == http.StatusUnprocessableEntity:
+		return ginerror{UError: res.Status, Origin: fn, Description: fmt.Sprintf("user '%s' cannot be added as a collaborator to '%s'", username, repoPath)}
+	case code == http.StatusUnauthorized:
+		return ginerror{UError: res.Status, Origin: fn, Description: "authorisation failed"}
+	case code == http.StatusInternalServerError:
+		return ginerror{UError: res.Status, Origin: fn, Description: "server error"}
+	case code != http.StatusNoContent:
+		return ginerror{UError: res.Status, Origin: fn} // Unexpected error
+	}
+	web.CloseRes(res.Body)
+	log.Write("Collaborator added")
+	return nil
+}
+
+// RemoveCollaborator revokes a user's access to a repository.
+func (gincl *Client) RemoveCollaborator(repoPath, username string) error {
+	fn := fmt.Sprintf("RemoveCollaborator(%s, %s)", repoPath, username)
+	log.Write("Removing collaborator")
+	res, err := gincl.Delete(fmt.Sprintf("/api/v1/repos/%s/collaborators/%s", repoPath, username))
+	if err != nil {
+		return err // return error from Delete() directly
+	}
+	switch code := res.StatusCode; {
+	case code == http.StatusForbidden:
+		return ginerror{UError: res.Status, Origin: fn, Description: fmt.Sprintf("you do not have permission to manage the collaborators of '%s'", repoPath)}
+	case code == http.StatusNotFound:
+		return ginerror{UError: res.Status, Origin: fn, Description: fmt.Sprintf("repository '%s' or user '%s' does not exist", repoPath, username)}
+	case code == http.StatusUnauthorized:
+		return ginerror{UError: res.Status, Origin: fn, Description: "authorisation failed"}
+	case code == http.StatusInternalServerError:
+		return ginerror{UError: res.Status, Origin: fn, Description: "server error"}
+	case code != http.StatusNoContent:
+		return ginerror{UError: res.Status, Origin: fn} // Unexpected error
+	}
+	web.CloseRes(res.Body)
+	log.Write("Collaborator removed")
+	return nil
+}
+
 // Add updates the index with the changes in the files specified by 'paths'.
 // The status channel 'addchan' is closed when this function returns.
 func Add(paths []string, addchan chan<- git.RepoFileStatus) {
@@ -299,6 +639,12 @@ func (gincl *Client) Upload(paths []string, remotes []string, uploadchan chan<-
 		uploadchan <- git.RepoFileStatus{Err: fmt.Errorf("failed to validate remote configuration (no configured remotes?)")}
 	}
 
+	if _, err = git.CurrentBranch(); err != nil {
+		// e.g., after checking out a tagged version with 'gin get --tag'
+		uploadchan <- git.RepoFileStatus{Err: fmt.Errorf("no branch is checked out: switch to a branch (e.g., 'git checkout master') before uploading changes")}
+		return
+	}
+
 	for _, remote := range remotes {
 		if _, ok := confremotes[remote]; !ok {
 			uploadchan <- git.RepoFileStatus{FileName: remote, Err: fmt.Errorf("unknown remote name '%s': skipping", remote)}
@@ -310,6 +656,9 @@ func (gincl *Client) Upload(paths []string, remotes []string, uploadchan chan<-
 		for stat := range gitpushchan {
 			uploadchan <- stat
 		}
+		if err = git.PushTags(remote); err != nil {
+			uploadchan <- git.RepoFileStatus{FileName: remote, Err: err}
+		}
 
 		annexpushchan := make(chan git.RepoFileStatus)
 		go git.AnnexPush(paths, remote, annexpushchan)
@@ -534,6 +883,35 @@ func RemoveRemote(remote string) error {
 	return err
 }
 
+// DefaultRemoteRepoPath returns the server alias and the repository path (owner/name) of the default remote of the current repository.
+// An error is returned if the default remote is not a repository on one of the configured servers.
+func DefaultRemoteRepoPath() (string, string, error) {
+	defremote, err := DefaultRemote()
+	if err != nil {
+		return "", "", err
+	}
+	remotes, err := git.RemoteShow()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to determine configured remotes")
+	}
+	remoteurl, ok := remotes[defremote]
+	if !ok {
+		return "", "", fmt.Errorf("no such remote: %s", defremote)
+	}
+	conf := config.Read()
+	for alias, srvcfg := range conf.Servers {
+		// remotes may have been added with either transport
+		for _, prefix := range []string{srvcfg.Git.AddressStr() + "/", srvcfg.Web.AddressStr() + "/"} {
+			if !strings.HasPrefix(remoteurl, prefix) {
+				continue
+			}
+			repopath := strings.TrimSuffix(strings.TrimPrefix(remoteurl, prefix), ".git")
+			return alias, repopath, nil
+		}
+	}
+	return "", "", fmt.Errorf("default remote '%s' (%s) is not a repository on a configured server", defremote, remoteurl)
+}
+
 // CheckoutVersion checks out all files specified by paths from the revision with the specified commithash.
 func CheckoutVersion(commithash string, paths []string) error {
 	err := git.Checkout(commithash, paths)
@@ -544,6 +922,42 @@ func CheckoutVersion(commithash string, paths []string) error {
 	return git.AnnexFsck(paths)
 }
 
+// annexPointerKey determines whether the contents of a git blob is a pointer to annexed content (an unlocked pointer file or a symlink target) and returns the annex key.
+func annexPointerKey(content []byte) (string, bool) {
+	// heuristic check for annexed pointer file:
+	// - check if the first 255 bytes of the file (or the entire
+	// contents if smaller) contain the string /annex/objects
+	maxpathidx := 255
+	if len(content) < maxpathidx {
+		maxpathidx = len(content)
+	}
+	if !isAnnexPath(string(content[:maxpathidx])) {
+		return "", false
+	}
+	// strip any newlines from the end of the path
+	keypath := strings.TrimSpace(string(content))
+	_, key := path.Split(keypath)
+	return key, true
+}
+
+// annexContentPath returns the location of the content for an annex key in the local repository.
+// If the content is not available locally, it is downloaded first.
+func annexContentPath(key string) (string, error) {
+	contentloc, err := git.AnnexContentLocation(key)
+	if err == nil {
+		return contentloc, nil
+	}
+	getchan := make(chan git.RepoFileStatus)
+	go git.AnnexGetKey(key, getchan)
+	for range getchan {
+	}
+	contentloc, err = git.AnnexContentLocation(key)
+	if err != nil {
+		return "", fmt.Errorf("Annexed content is not available locally")
+	}
+	return contentloc, nil
+}
+
 // CheckoutFileCopies checks out copies of files specified by path from the revision with the specified commithash.
 // The checked out files are stored in the location specified by outpath.
 // The timestamp of the revision is appended to the original filenames (before the extension).
@@ -576,33 +990,14 @@ func CheckoutFileCopies(commithash string, paths []string, outpath string, suffi
 				return
 			}
 
-			// heuristic check for annexed pointer file:
-			// - check if the first 255 bytes of the file (or the entire
-			// contents if smaller) contain the string /annex/objects
-			maxpathidx := 255
-			if len(content) < maxpathidx {
-				maxpathidx = len(content)
-			}
-
-			if isAnnexPath(string(content[:maxpathidx])) {
+			if key, ok := annexPointerKey(content); ok {
 				// Pointer file to annexed content
 				status.Type = "Annex"
-				// strip any newlines from the end of the path
-				keypath := strings.TrimSpace(string(content))
-				_, key := path.Split(keypath)
-				contentloc, err := git.AnnexContentLocation(key)
+				contentloc, err := annexContentPath(key)
 				if err != nil {
-					getchan := make(chan git.RepoFileStatus)
-					go git.AnnexGetKey(key, getchan)
-					for range getchan {
-					}
-					contentloc, err = git.AnnexContentLocation(key)
-					if err != nil {
-						status.Err = fmt.Errorf("Annexed content is not available locally")
-						cochan <- status
-						continue
-					}
-					status.Err = nil
+					status.Err = err
+					cochan <- status
+					continue
 				}
 				err = git.CopyFile(contentloc, outfile)
 				if err != nil {
@@ -748,8 +1143,9 @@ func (fs FileStatus) Abbrev() string {
 	}
 }
 
-func lfDirect(paths ...string) (map[string]FileStatus, error) {
+func lfDirect(paths ...string) (map[string]FileStatus, map[string][]string, error) {
 	statuses := make(map[string]FileStatus)
+	weburls := make(map[string][]string)
 
 	wichan := make(chan git.AnnexWhereisRes)
 	go git.AnnexWhereis(paths, wichan)
@@ -758,6 +1154,9 @@ func lfDirect(paths ...string) (map[string]FileStatus, error) {
 			continue
 		}
 		fname := filepath.Clean(wiInfo.File)
+		if fileurls := wiInfo.URLs(); len(fileurls) > 0 {
+			weburls[fname] = fileurls
+		}
 		for _, remote := range wiInfo.Whereis {
 			// if no remotes are "here", the file is NoContent
 			statuses[fname] = NoContent
@@ -782,7 +1181,7 @@ func lfDirect(paths ...string) (map[string]FileStatus, error) {
 	go git.AnnexStatus(asargs, statuschan)
 	for item := range statuschan {
 		if item.Err != nil {
-			return nil, item.Err
+			return nil, nil, item.Err
 		}
 		fname := filepath.Clean(item.File)
 		if item.Status == "?" {
@@ -821,12 +1220,13 @@ func lfDirect(paths ...string) (map[string]FileStatus, error) {
 			}
 		}
 	}
-	return statuses, nil
+	return statuses, weburls, nil
 }
 
-func lfIndirect(paths ...string) (map[string]FileStatus, error) {
+func lfIndirect(paths ...string) (map[string]FileStatus, map[string][]string, error) {
 	// TODO: Determine if added files (LocalChanges) are new or not (new status needed?)
 	statuses := make(map[string]FileStatus)
+	weburls := make(map[string][]string)
 
 	cachedchan := make(chan string)
 	var cachedfiles, modifiedfiles, untrackedfiles, deletedfiles []string
@@ -918,6 +1318,9 @@ func lfIndirect(paths ...string) (map[string]FileStatus, error) {
 				continue
 			}
 			fname := filepath.Clean(wiInfo.File)
+			if fileurls := wiInfo.URLs(); len(fileurls) > 0 {
+				weburls[fname] = fileurls
+			}
 			// if no content location for this file is "here", the status is NoContent
 			statuses[fname] = NoContent
 			for _, remote := range wiInfo.Whereis {
@@ -970,14 +1373,21 @@ func lfIndirect(paths ...string) (map[string]FileStatus, error) {
 		statuses[fname] = Removed
 	}
 
-	return statuses, nil
+	return statuses, weburls, nil
 }
 
 // ListFiles lists the files and directories specified by paths and their sync status.
 func (gincl *Client) ListFiles(paths ...string) (map[string]FileStatus, error) {
+	statuses, _, err := gincl.ListFilesWithURLs(paths...)
+	return statuses, err
+}
+
+// ListFilesWithURLs lists the files and directories specified by paths and their sync status, like ListFiles.
+// It also returns the URLs from which the content of annexed files can be downloaded, for the files that have any.
+func (gincl *Client) ListFilesWithURLs(paths ...string) (map[string]FileStatus, map[string][]string, error) {
 	paths, err := expandglobs(paths, false)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	if git.IsDirect() {
 		return lfDirect(paths...)
@@ -985,6 +1395,77 @@ func (gincl *Client) ListFiles(paths ...string) (map[string]FileStatus, error) {
 	return lfIndirect(paths...)
 }
 
+// ContentLocation describes a repository that holds the content of an annexed file.
+type ContentLocation struct {
+	// Name of the git remote for the repository, "here" for the local repository, "web" for web addresses, or empty if the repository is not a known remote.
+	Remote      string   `json:"remote"`
+	UUID        string   `json:"uuid"`
+	Description string   `json:"description"`
+	Trust       string   `json:"trust"`
+	URLs        []string `json:"urls,omitempty"`
+}
+
+// FileLocations lists the repositories that hold the content of an annexed file.
+type FileLocations struct {
+	File      string            `json:"filename"`
+	Key       string            `json:"key"`
+	Locations []ContentLocation `json:"locations"`
+}
+
+// Whereis returns the locations of the content of the annexed files specified by paths.
+// Repository UUIDs are mapped to the names of the git remotes they belong to and the trust level of each repository is included.
+func Whereis(paths ...string) ([]FileLocations, error) {
+	paths, err := expandglobs(paths, false)
+	if err != nil {
+		return nil, err
+	}
+	remotes, err := git.AnnexRemoteUUIDs()
+	if err != nil {
+		return nil, err
+	}
+	trust, err := git.AnnexTrustLevels()
+	if err != nil {
+		// trust levels are informational; continue without them
+		log.Write("Failed to read annex trust levels: %s", err)
+		trust = make(map[string]string)
+	}
+
+	var files []FileLocations
+	var wierr error
+	wichan := make(chan git.AnnexWhereisRes)
+	go git.AnnexWhereis(paths, wichan)
+	for wiInfo := range wichan {
+		if wiInfo.Err != nil {
+			// keep reading until the channel is closed so that AnnexWhereis can return
+			if wierr == nil {
+				wierr = wiInfo.Err
+			}
+			continue
+		}
+		floc := FileLocations{File: filepath.Clean(wiInfo.File), Key: wiInfo.Key, Locations: make([]ContentLocation, 0, len(wiInfo.Whereis))}
+		for _, wi := range wiInfo.Whereis {
+			loc := ContentLocation{UUID: wi.UUID, Description: wi.Description, Trust: trust[wi.UUID], URLs: append([]string(nil), wi.URLs...)}
+			switch {
+			case wi.Here:
+				loc.Remote = "here"
+			case wi.UUID == git.WebUUID:
+				loc.Remote = "web"
+			default:
+				loc.Remote = remotes[wi.UUID]
+			}
+			if loc.Trust == "" {
+				loc.Trust = "semitrusted"
+			}
+			floc.Locations = append(floc.Locations, loc)
+		}
+		files = append(files, floc)
+	}
+	if wierr != nil {
+		return nil, wierr
+	}
+	return files, nil
+}
+
 // expandglobs expands a list of globs into paths (files and directories).
 // If strictmatch is true, an error is returned if at least one element of the input slice does not match a real path,
 // otherwise the pattern itself is returned when it matches no existing path.
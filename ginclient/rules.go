@@ -0,0 +1,346 @@
+package ginclient
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/G-Node/gin-cli/ginclient/config"
+	"github.com/G-Node/gin-cli/git"
+)
+
+// Functions for managing the per-path rules that determine whether files are added to the annex or to git.
+// The rules are stored as annex.largefiles attributes in a block of the .gitattributes file in the repository root, so that git-annex applies them in every clone.
+
+// AttributesFileName is the name of the file that holds the annex rules of a repository.
+const AttributesFileName = ".gitattributes"
+
+const (
+	rulesBlockStart = git.AnnexRulesBlockStart
+	rulesBlockEnd   = git.AnnexRulesBlockEnd
+)
+
+// AnnexRule determines whether files matching a pattern are added to the annex.
+type AnnexRule struct {
+	// Pattern in .gitattributes syntax, e.g., "*.ipynb" or "raw/**".
+	Pattern string `json:"pattern"`
+	// "always", "never", or a size threshold (e.g., "1M") above which files are annexed.
+	Annex string `json:"annex"`
+}
+
+// largefiles returns the annex.largefiles expression for the rule.
+func (rule AnnexRule) largefiles() string {
+	switch rule.Annex {
+	case "always":
+		return "anything"
+	case "never":
+		return "nothing"
+	}
+	return fmt.Sprintf("largerthan=%s", rule.Annex)
+}
+
+// Description returns a human-readable description of where matching files go.
+func (rule AnnexRule) Description() string {
+	switch rule.Annex {
+	case "always":
+		return "always annexed"
+	case "never":
+		return "always stored in git"
+	}
+	return fmt.Sprintf("annexed if larger than %s", rule.Annex)
+}
+
+// Matches returns true if the path (relative to the repository root, with '/' separators) matches the rule pattern.
+// Patterns without a '/' match the file name at any level. Patterns ending in "/**" match everything in the directory.
+// This covers the patterns written by 'gin rules set', but not all of the gitattributes syntax (e.g., "**" inside a pattern).
+func (rule AnnexRule) Matches(fpath string) bool {
+	if strings.HasSuffix(rule.Pattern, "/**") {
+		dir := strings.TrimSuffix(rule.Pattern, "/**")
+		return strings.HasPrefix(fpath, dir+"/")
+	}
+	if !strings.Contains(rule.Pattern, "/") {
+		match, _ := path.Match(rule.Pattern, path.Base(fpath))
+		return match
+	}
+	match, _ := path.Match(strings.TrimPrefix(rule.Pattern, "/"), fpath)
+	return match
+}
+
+// NewAnnexRule checks and normalises a pattern and annex setting and returns the rule.
+// A pattern ending in '/' is turned into a pattern that matches everything in the directory.
+func NewAnnexRule(pattern, annex string) (AnnexRule, error) {
+	pattern = filepath.ToSlash(strings.TrimSpace(pattern))
+	if pattern == "" || strings.ContainsAny(pattern, " \t") {
+		return AnnexRule{}, fmt.Errorf("invalid pattern '%s': must not be empty or contain spaces", pattern)
+	}
+	if strings.HasSuffix(pattern, "/") {
+		pattern += "**"
+	}
+	switch annex {
+	case "always", "never":
+	default:
+		if _, err := parseSize(annex); err != nil {
+			return AnnexRule{}, fmt.Errorf("invalid setting '%s': must be 'always', 'never', or a size", annex)
+		}
+	}
+	return AnnexRule{Pattern: pattern, Annex: annex}, nil
+}
+
+// destination returns where a file of the given size (-1 if unknown) is stored under the rule: "annex", "git", or "size-dependent".
+func (rule AnnexRule) destination(size int64) string {
+	switch {
+	case rule.Annex == "always":
+		return "annex"
+	case rule.Annex == "never":
+		return "git"
+	case size < 0:
+		return "size-dependent"
+	}
+	threshold, _ := parseSize(rule.Annex)
+	if size > threshold {
+		return "annex"
+	}
+	return "git"
+}
+
+// parseLargefiles returns the annex setting of a rule for an annex.largefiles value written by 'gin rules'.
+func parseLargefiles(value string) (string, bool) {
+	switch {
+	case value == "anything":
+		return "always", true
+	case value == "nothing":
+		return "never", true
+	case strings.HasPrefix(value, "largerthan="):
+		return strings.TrimPrefix(value, "largerthan="), true
+	}
+	return "", false
+}
+
+// parseRuleLine parses a .gitattributes line that sets annex.largefiles.
+func parseRuleLine(line string) (AnnexRule, bool) {
+	fields := strings.Fields(line)
+	if len(fields) != 2 || !strings.HasPrefix(fields[1], "annex.largefiles=") {
+		return AnnexRule{}, false
+	}
+	annex, ok := parseLargefiles(strings.TrimPrefix(fields[1], "annex.largefiles="))
+	if !ok {
+		return AnnexRule{}, false
+	}
+	return AnnexRule{Pattern: fields[0], Annex: annex}, true
+}
+
+func attributesPath() (string, error) {
+	reporoot, err := git.FindRepoRoot(".")
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(reporoot, AttributesFileName), nil
+}
+
+// readAttributes returns the lines of the attributes file before and after the rules block and the rules in the block.
+func readAttributes(attrpath string) (before, after []string, rules []AnnexRule, found bool, err error) {
+	data, err := ioutil.ReadFile(attrpath)
+	if os.IsNotExist(err) {
+		return nil, nil, nil, false, nil
+	} else if err != nil {
+		return nil, nil, nil, false, err
+	}
+	content := strings.TrimRight(string(data), "\n")
+	if content == "" {
+		return nil, nil, nil, false, nil
+	}
+	inblock := false
+	for _, line := range strings.Split(content, "\n") {
+		switch {
+		case line == rulesBlockStart:
+			inblock = true
+			found = true
+		case line == rulesBlockEnd:
+			inblock = false
+		case inblock:
+			if rule, ok := parseRuleLine(line); ok {
+				rules = append(rules, rule)
+			}
+		case found:
+			after = append(after, line)
+		default:
+			before = append(before, line)
+		}
+	}
+	return before, after, rules, found, nil
+}
+
+// defaultRules returns the rules equivalent to the annex settings in the client configuration.
+func defaultRules() []AnnexRule {
+	conf := config.Read()
+	var rules []AnnexRule
+	if conf.Annex.MinSize != "" {
+		rules = append(rules, AnnexRule{Pattern: "*", Annex: conf.Annex.MinSize})
+	} else {
+		rules = append(rules, AnnexRule{Pattern: "*", Annex: "always"})
+	}
+	for _, pattern := range conf.Annex.Exclude {
+		rules = append(rules, AnnexRule{Pattern: pattern, Annex: "never"})
+	}
+	return append(rules, AnnexRule{Pattern: "config.yml", Annex: "never"})
+}
+
+// ReadAnnexRules returns the annex rules of the repository.
+// If the repository does not define its own rules, the rules derived from the client configuration are returned and the second return value is false.
+func ReadAnnexRules() ([]AnnexRule, bool, error) {
+	attrpath, err := attributesPath()
+	if err != nil {
+		return nil, false, err
+	}
+	_, _, rules, found, err := readAttributes(attrpath)
+	if err != nil {
+		return nil, false, err
+	}
+	if !found {
+		return defaultRules(), false, nil
+	}
+	return rules, true, nil
+}
+
+// WriteAnnexRules replaces the rules block of the repository attributes file with the given rules.
+// Lines outside the block are kept.
+func WriteAnnexRules(rules []AnnexRule) error {
+	attrpath, err := attributesPath()
+	if err != nil {
+		return err
+	}
+	before, after, _, _, err := readAttributes(attrpath)
+	if err != nil {
+		return err
+	}
+	lines := before
+	lines = append(lines, rulesBlockStart)
+	for _, rule := range rules {
+		lines = append(lines, fmt.Sprintf("%s annex.largefiles=%s", rule.Pattern, rule.largefiles()))
+	}
+	lines = append(lines, rulesBlockEnd)
+	lines = append(lines, after...)
+	return ioutil.WriteFile(attrpath, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+}
+
+// SetAnnexRule adds a rule to the repository or replaces the existing rule with the same pattern.
+// If the repository does not define its own rules yet, the rules derived from the client configuration are written first, so that the behaviour for other files does not change.
+func SetAnnexRule(rule AnnexRule) error {
+	rules, _, err := ReadAnnexRules()
+	if err != nil {
+		return err
+	}
+	var newrules []AnnexRule
+	for _, r := range rules {
+		if r.Pattern != rule.Pattern {
+			newrules = append(newrules, r)
+		}
+	}
+	// new and changed rules go last to take precedence
+	return WriteAnnexRules(append(newrules, rule))
+}
+
+// UnsetAnnexRule removes the rule with the given pattern from the repository.
+func UnsetAnnexRule(pattern string) error {
+	rules, found, err := ReadAnnexRules()
+	if err != nil {
+		return err
+	}
+	if strings.HasSuffix(pattern, "/") {
+		pattern += "**"
+	}
+	var newrules []AnnexRule
+	for _, r := range rules {
+		if r.Pattern != pattern {
+			newrules = append(newrules, r)
+		}
+	}
+	if !found || len(newrules) == len(rules) {
+		return fmt.Errorf("no rule for pattern '%s'", pattern)
+	}
+	return WriteAnnexRules(newrules)
+}
+
+// RuleCheck explains where a file would be stored.
+type RuleCheck struct {
+	File string `json:"filename"`
+	// The rule that applies to the file. Nil if no rule matches or the rule cannot be determined (see Warning).
+	Rule *AnnexRule `json:"rule"`
+	// Size of the file in bytes, or -1 if the file does not exist.
+	Size int64 `json:"size"`
+	// "annex", "git", "size-dependent" if the file does not exist and the rule depends on the size, or "unknown" if the attribute was not written by 'gin rules'.
+	Destination string `json:"destination"`
+	// The effective annex.largefiles attribute as reported by git (it may differ from the rule if .gitattributes is edited by hand).
+	Attribute string `json:"attribute"`
+	// Set if the rule that matches the file could not be determined from the attribute.
+	Warning string `json:"warning,omitempty"`
+}
+
+// CheckAnnexRules determines which rule applies to a file and whether it would be added to the annex or to git.
+// If the repository defines its own rules, git-annex uses the annex.largefiles attribute, so the destination is determined from the attribute reported by git and the matching rule only explains it.
+// Otherwise, the rules derived from the client configuration are passed to git-annex as a setting that takes precedence over the attributes.
+func CheckAnnexRules(fpath string) (RuleCheck, error) {
+	check := RuleCheck{File: fpath, Size: -1}
+	rules, found, err := ReadAnnexRules()
+	if err != nil {
+		return check, err
+	}
+	reporoot, err := git.FindRepoRoot(".")
+	if err != nil {
+		return check, err
+	}
+	abspath, err := filepath.Abs(fpath)
+	if err != nil {
+		return check, err
+	}
+	relpath, err := filepath.Rel(reporoot, abspath)
+	if err != nil || !pathInside(abspath, reporoot) {
+		return check, fmt.Errorf("'%s' is not inside the repository", fpath)
+	}
+	relpath = filepath.ToSlash(relpath)
+	for idx := len(rules) - 1; idx >= 0; idx-- {
+		if rules[idx].Matches(relpath) {
+			check.Rule = &rules[idx]
+			break
+		}
+	}
+	if info, err := os.Stat(abspath); err == nil {
+		check.Size = info.Size()
+	}
+	check.Attribute, err = git.CheckAttr("annex.largefiles", fpath)
+	if err != nil {
+		return check, err
+	}
+
+	if !found {
+		if check.Rule == nil {
+			// git-annex default: everything is annexed
+			check.Destination = "annex"
+		} else {
+			check.Destination = check.Rule.destination(check.Size)
+		}
+		return check, nil
+	}
+
+	var agrees bool
+	if check.Attribute == "unspecified" {
+		// git-annex default: everything is annexed
+		check.Destination = "annex"
+		agrees = check.Rule == nil
+	} else if annex, ok := parseLargefiles(check.Attribute); ok {
+		check.Destination = AnnexRule{Annex: annex}.destination(check.Size)
+		agrees = check.Rule != nil && check.Rule.Annex == annex
+	} else {
+		// set by hand outside the rules block
+		check.Destination = "unknown"
+		agrees = false
+	}
+	if !agrees {
+		check.Rule = nil
+		check.Warning = "the matching rule could not be determined (the pattern may use gitattributes syntax that 'gin rules' does not interpret, or annex.largefiles may be set outside the rules block); the destination follows the annex.largefiles attribute"
+	}
+	return check, nil
+}
@@ -0,0 +1,120 @@
+package ginclient
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAnnexRuleMatches(t *testing.T) {
+	cases := []struct {
+		pattern string
+		path    string
+		match   bool
+	}{
+		{"raw/", "raw/2024/session1/spikes.nix", true},
+		{"raw/", "derived/raw/spikes.nix", false},
+		{"*.ipynb", "analysis/plots.ipynb", true},
+		{"*.ipynb", "analysis/plots.py", false},
+		{"derived/*.csv", "derived/table.csv", true},
+		{"derived/*.csv", "derived/sub/table.csv", false},
+	}
+	for _, c := range cases {
+		rule, err := NewAnnexRule(c.pattern, "always")
+		if err != nil {
+			t.Fatalf("Failed to create rule for '%s': %v", c.pattern, err)
+		}
+		if m := rule.Matches(c.path); m != c.match {
+			t.Errorf("Rule '%s' matching '%s': expected %t, got %t", rule.Pattern, c.path, c.match, m)
+		}
+	}
+
+	if _, err := NewAnnexRule("raw/", "sometimes"); err == nil {
+		t.Error("Invalid annex setting accepted")
+	}
+}
+
+func TestAnnexRulesAttributes(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "gin-rules-test")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(tmpdir)
+	attrpath := filepath.Join(tmpdir, AttributesFileName)
+
+	rules := []AnnexRule{{"*", "10M"}, {"raw/**", "always"}, {"*.ipynb", "never"}}
+	lines := []string{"*.png binary", rulesBlockStart}
+	for _, rule := range rules {
+		lines = append(lines, rule.Pattern+" annex.largefiles="+rule.largefiles())
+	}
+	lines = append(lines, rulesBlockEnd)
+	var content string
+	for _, l := range lines {
+		content += l + "\n"
+	}
+	if err = ioutil.WriteFile(attrpath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write attributes file: %v", err)
+	}
+
+	before, after, readrules, found, err := readAttributes(attrpath)
+	if err != nil || !found {
+		t.Fatalf("Failed to read rules block: %v (found: %t)", err, found)
+	}
+	if len(before) != 1 || before[0] != "*.png binary" || len(after) != 0 {
+		t.Errorf("Lines outside the rules block not preserved: %v, %v", before, after)
+	}
+	if len(readrules) != len(rules) {
+		t.Fatalf("Expected %d rules, got %d", len(rules), len(readrules))
+	}
+	for idx := range rules {
+		if readrules[idx] != rules[idx] {
+			t.Errorf("Rule %d: expected %v, got %v", idx, rules[idx], readrules[idx])
+		}
+	}
+}
+
+func TestCheckAnnexRules(t *testing.T) {
+	repodir := setupGitRepo(t, map[string]string{"README.md": "readme"})
+	defer os.RemoveAll(repodir)
+	files := map[string]string{
+		"table.csv":                    "1,2,3\n",
+		"raw/2024/session1/spikes.nix": "spikes",
+		"derived/large.bin":            "0123456789",
+	}
+	for fname, content := range files {
+		os.MkdirAll(filepath.Dir(fname), 0755)
+		ioutil.WriteFile(fname, []byte(content), 0644)
+	}
+	// "**" inside a pattern matches any number of directories in gitattributes
+	rules := []AnnexRule{{"*", "never"}, {"*.bin", "5"}, {"raw/**/*.nix", "always"}}
+	if err := WriteAnnexRules(rules); err != nil {
+		t.Fatalf("Failed to write rules: %v", err)
+	}
+
+	cases := []struct {
+		fname       string
+		destination string
+		rule        string
+	}{
+		{"table.csv", "git", "*"},
+		{"derived/large.bin", "annex", "*.bin"},
+		{"missing.bin", "size-dependent", "*.bin"},
+		{"raw/2024/session1/spikes.nix", "annex", ""},
+	}
+	for _, c := range cases {
+		check, err := CheckAnnexRules(c.fname)
+		if err != nil {
+			t.Fatalf("Failed to check rules for '%s': %v", c.fname, err)
+		}
+		if check.Destination != c.destination {
+			t.Errorf("'%s': expected destination %s, got %s (attribute %s)", c.fname, c.destination, check.Destination, check.Attribute)
+		}
+		switch {
+		case c.rule == "" && (check.Rule != nil || check.Warning == ""):
+			t.Errorf("'%s': expected a warning instead of rule %v", c.fname, check.Rule)
+		case c.rule != "" && (check.Rule == nil || check.Rule.Pattern != c.rule || check.Warning != ""):
+			t.Errorf("'%s': expected rule %s, got %v (warning %q)", c.fname, c.rule, check.Rule, check.Warning)
+		}
+	}
+}
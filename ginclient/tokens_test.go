@@ -0,0 +1,67 @@
+package ginclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	gogs "github.com/gogits/go-gogs-client"
+)
+
+// tokenServer is a fake server that holds the access tokens of the user alice.
+type tokenServer struct {
+	tokens []AccessToken
+}
+
+func (srv *tokenServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if username, password, ok := r.BasicAuth(); !ok || username != "alice" || password != "pw" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	const tokenspath = "/api/v1/users/alice/tokens"
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == tokenspath:
+		json.NewEncoder(w).Encode(srv.tokens)
+	case r.Method == http.MethodPost && r.URL.Path == tokenspath:
+		var opt gogs.CreateAccessTokenOption
+		json.NewDecoder(r.Body).Decode(&opt)
+		token := AccessToken{Name: opt.Name, Sha1: fmt.Sprintf("%040d", len(srv.tokens)+1)}
+		srv.tokens = append(srv.tokens, token)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(token)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestTokens(t *testing.T) {
+	tokensrv := &tokenServer{tokens: []AccessToken{{Name: "gin-cli", Sha1: "0123456789abcdef0123456789abcdef01234567"}}}
+	cleanup := testServer(t, "tokenstest", tokensrv)
+	defer cleanup()
+
+	gincl := New("tokenstest")
+
+	tokens, err := gincl.GetTokens("alice", "pw")
+	if err != nil {
+		t.Fatalf("failed to list tokens: %v", err)
+	}
+	if len(tokens) != 1 || tokens[0] != tokensrv.tokens[0] {
+		t.Errorf("unexpected token list: %+v", tokens)
+	}
+	if _, err = gincl.GetTokens("alice", "wrong"); err == nil || !strings.Contains(err.Error(), "authorisation failed") {
+		t.Errorf("expected authorisation error for wrong password, got %v", err)
+	}
+
+	token, err := gincl.CreateToken("alice", "pw", "ci-job")
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+	if token.Name != "ci-job" || token.Sha1 == "" || len(tokensrv.tokens) != 2 || tokensrv.tokens[1] != token {
+		t.Errorf("unexpected token created: %+v (server tokens %+v)", token, tokensrv.tokens)
+	}
+	if gincl.Token != "" {
+		t.Errorf("client token changed by CreateToken: %q", gincl.Token)
+	}
+}
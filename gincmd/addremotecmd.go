@@ -37,7 +37,7 @@ type remote struct {
 	// for "dir" type remotes, this is the directory path as supplied by the user
 	path string
 
-	// url is the full repository URL including username and protocol (e.g., ssh://git@gin.g-node.org:22/<username>/<repositoryname>)
+	// url is the full repository URL including username and protocol (e.g., ssh://git@gin.g-node.org:22/<username>/<repositoryname>, or https://gin.g-node.org:443/<username>/<repositoryname> for the HTTPS transport)
 	// for unknown remote types, this is equivalent to path
 	// for "dir" type remotes, this is the absolute path of the directory supplied by the user
 	url string
@@ -65,7 +65,7 @@ func parseRemote(remotestr string) remote {
 
 	conf := config.Read()
 	if srvcfg, ok := conf.Servers[rmt.server]; ok {
-		rmt.url = fmt.Sprintf("%s/%s", srvcfg.Git.AddressStr(), rmt.path)
+		rmt.url = fmt.Sprintf("%s/%s", srvcfg.RemoteAddressStr(), rmt.path)
 		rmt.rt = ginrt
 		return rmt
 	}
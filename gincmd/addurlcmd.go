@@ -0,0 +1,86 @@
+package gincmd
+
+import (
+	"fmt"
+
+	ginclient "github.com/G-Node/gin-cli/ginclient"
+	"github.com/G-Node/gin-cli/gincmd/ginerrors"
+	"github.com/G-Node/gin-cli/git"
+	"github.com/spf13/cobra"
+)
+
+func addurl(cmd *cobra.Command, args []string) {
+	prStyle := determinePrintStyle(cmd)
+	flags := cmd.Flags()
+	fpath, _ := flags.GetString("file")
+	relaxed, _ := flags.GetBool("relaxed")
+	fast, _ := flags.GetBool("fast")
+	if relaxed && fast {
+		usageDie(cmd)
+	}
+	if fpath != "" && len(args) > 1 {
+		Die("--file can only be used with a single URL")
+	}
+
+	switch git.Checkwd() {
+	case git.NotRepository:
+		Die(ginerrors.NotInRepo)
+	case git.NotAnnex:
+		Warn(ginerrors.MissingAnnex)
+	case git.UpgradeRequired:
+		annexVersionNotice()
+	}
+
+	var mode string
+	switch {
+	case relaxed:
+		mode = "relaxed"
+	case fast:
+		mode = "fast"
+	}
+
+	filepaths := make([]string, len(args))
+	for idx, rawurl := range args {
+		if fpath != "" {
+			filepaths[idx] = fpath
+			continue
+		}
+		fname, err := ginclient.URLFileName(rawurl)
+		if err != nil {
+			Die(fmt.Sprintf("%s; use --file to specify a file name", err))
+		}
+		filepaths[idx] = fname
+	}
+
+	if prStyle == psDefault {
+		fmt.Println(":: Adding files from URLs")
+	}
+	addchan := make(chan git.RepoFileStatus)
+	go git.AnnexAddURL(args, filepaths, mode, addchan)
+	formatOutput(addchan, prStyle, 0)
+}
+
+// AddURLCmd sets up the 'addurl' subcommand
+func AddURLCmd() *cobra.Command {
+	description := "Add files to the repository from web addresses. The URL of each file is recorded, so that its content can be downloaded from the web with 'gin get-content' in any clone of the repository, even if it is never uploaded to the GIN server.\n\nBy default, the content of each file is downloaded. With --fast, only the existence of the URL is checked and the content is not downloaded. With --relaxed, the URL is not checked either; use this for URLs whose content may change.\n\nIf no file path is specified, the file is named after the last part of the URL and placed in the current directory. The new files must be committed with 'gin commit' to record them in the repository."
+	args := map[string]string{
+		"<url>": "One or more web addresses of files to add.",
+	}
+	examples := map[string]string{
+		"Register a public recording without downloading it": "$ gin addurl --fast --file raw/session1.nix https://example.org/data/session1.nix",
+	}
+	var cmd = &cobra.Command{
+		Use:                   "addurl [--file <path>] [--relaxed | --fast] [--json] <url>...",
+		Short:                 "Add files to the repository from web addresses",
+		Long:                  formatdesc(description, args),
+		Example:               formatexamples(examples),
+		Args:                  cobra.MinimumNArgs(1),
+		Run:                   addurl,
+		DisableFlagsInUseLine: true,
+	}
+	cmd.Flags().String("file", "", "The `path` of the file to create. Can only be used with a single URL.")
+	cmd.Flags().Bool("relaxed", false, "Do not check the URL or download the content.")
+	cmd.Flags().Bool("fast", false, "Check that the URL exists but do not download the content.")
+	cmd.Flags().Bool("json", false, jsonHelpMsg)
+	return cmd
+}
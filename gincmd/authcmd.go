@@ -0,0 +1,238 @@
+package gincmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	ginclient "github.com/G-Node/gin-cli/ginclient"
+	"github.com/G-Node/gin-cli/ginclient/config"
+	"github.com/G-Node/gin-cli/git"
+	"github.com/G-Node/gin-cli/web"
+	"github.com/fatih/color"
+	"github.com/howeyc/gopass"
+	"github.com/spf13/cobra"
+)
+
+// promptPassphrase asks for the passphrase of the encrypted credential store on the terminal.
+func promptPassphrase(prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	pwbytes, err := gopass.GetPasswdMasked()
+	fmt.Fprintln(os.Stderr)
+	if err == gopass.ErrInterrupted {
+		Die("Cancelled.")
+	}
+	return string(pwbytes), err
+}
+
+// formatAge formats the time elapsed since t in the largest whole unit (days, hours, or minutes).
+func formatAge(t time.Time) string {
+	age := time.Since(t)
+	plural := func(n int, unit string) string {
+		if n == 1 {
+			return fmt.Sprintf("1 %s ago", unit)
+		}
+		return fmt.Sprintf("%d %ss ago", n, unit)
+	}
+	switch {
+	case age >= 24*time.Hour:
+		return plural(int(age.Hours()/24), "day")
+	case age >= time.Hour:
+		return plural(int(age.Hours()), "hour")
+	case age >= time.Minute:
+		return plural(int(age.Minutes()), "minute")
+	}
+	return "just now"
+}
+
+// printServerStatus prints the login status of the active profile on a server, as returned by ginclient.GetServerStatus.
+func printServerStatus(status ginclient.ServerStatus) {
+	fmt.Printf("* %s [%s]", status.Server, status.Address)
+	if status.Default {
+		fmt.Fprint(color.Output, green(" [default]"))
+	}
+	fmt.Println()
+	if !status.LoggedIn {
+		fmt.Println("  login:     not logged in")
+	} else {
+		username := status.Username
+		if username == "" {
+			// the server did not confirm the user of a token from the environment
+			username = "unknown user"
+		}
+		fmt.Printf("  login:     %s (token from %s", username, status.TokenSource)
+		if status.TokenStored != nil {
+			fmt.Printf(", logged in %s", formatAge(*status.TokenStored))
+		}
+		fmt.Print(")")
+		switch status.TokenStatus {
+		case ginclient.TokenValid:
+			fmt.Fprint(color.Output, green(" [token valid]"))
+		case ginclient.TokenInvalid:
+			fmt.Fprint(color.Output, red(" [token rejected by the server]"))
+		case ginclient.TokenUnchecked:
+			fmt.Fprint(color.Output, yellow(" [token not checked]"))
+		}
+		fmt.Println()
+	}
+	if status.KeyFilePresent {
+		fmt.Printf("  key:       %s", status.KeyFile)
+	} else {
+		fmt.Print("  key:       no session key")
+	}
+	switch {
+	case status.KeyRegistered:
+		fmt.Fprintf(color.Output, " %s\n", green(fmt.Sprintf("[registered as '%s']", status.KeyTitle)))
+	case status.TokenStatus == ginclient.TokenValid && status.Transport == config.TransportSSH:
+		fmt.Fprintf(color.Output, " %s\n", red("[no registered key]"))
+	default:
+		fmt.Println()
+	}
+	fmt.Printf("  transport: %s\n", status.Transport)
+	switch status.HostKeyStatus {
+	case git.HostKeyOK:
+		fmt.Fprintf(color.Output, "  host key:  %s (%s)\n", green("ok"), status.HostKeyFingerprint)
+	case git.HostKeyMissing:
+		fmt.Fprintf(color.Output, "  host key:  %s (server key %s; run 'gin add-server' to update the server configuration)\n", yellow("not configured"), status.HostKeyFingerprint)
+	case git.HostKeyChanged:
+		fmt.Fprintf(color.Output, "  host key:  %s (server key %s; verify the new key with the server administrator and run 'gin add-server' to update the server configuration)\n", red("changed"), status.HostKeyFingerprint)
+	}
+	for _, errmsg := range status.Errors {
+		fmt.Fprintf(color.Output, "  %s %s\n", yellow("[check failed]"), errmsg)
+	}
+}
+
+func authStatus(cmd *cobra.Command, args []string) {
+	jsonout, _ := cmd.Flags().GetBool("json")
+	conf := config.Read()
+	store, storeerr := web.SelectedCredentialStore()
+	var storename string
+	if storeerr == nil {
+		storename = store.Name()
+	}
+	profile, profilesrc := config.ActiveProfile()
+	infos, err := ginclient.CredentialsStatus()
+	CheckError(err)
+	statuses := ginclient.ServersStatus()
+
+	if jsonout {
+		if infos == nil {
+			infos = []ginclient.CredentialInfo{}
+		}
+		j, _ := json.Marshal(struct {
+			ConfiguredStore string                     `json:"configured_store"`
+			Store           string                     `json:"store"`
+			Profile         string                     `json:"profile"`
+			ProfileSource   string                     `json:"profile_source"`
+			Credentials     []ginclient.CredentialInfo `json:"credentials"`
+			Servers         []ginclient.ServerStatus   `json:"servers"`
+		}{conf.Credentials.Store, storename, profile, profilesrc, infos, statuses})
+		fmt.Println(string(j))
+		return
+	}
+
+	if storeerr != nil {
+		Warn(storeerr.Error())
+	} else {
+		fmt.Fprintf(color.Output, ":: Credential store: %s (configured: %s)\n", cyan(storename), conf.Credentials.Store)
+	}
+	fmt.Fprintf(color.Output, ":: Active profile: %s (%s)\n", cyan(profile), profilesrc)
+	fmt.Println(":: Servers")
+	for _, status := range statuses {
+		printServerStatus(status)
+	}
+	if len(infos) == 0 {
+		fmt.Println(":: No stored credentials")
+		return
+	}
+	fmt.Println(":: Stored credentials")
+	unmigrated := false
+	for _, info := range infos {
+		fmt.Printf("* %s [profile %s]", info.Server, info.Profile)
+		if info.Profile == profile && info.Server == conf.DefaultServer {
+			fmt.Fprint(color.Output, green(" [default server, active profile]"))
+		}
+		fmt.Println()
+		for _, tokenloc := range info.Tokens {
+			fmt.Printf("  token: %s", tokenloc.Location)
+			if !tokenloc.Encrypted {
+				fmt.Fprint(color.Output, yellow(" [unencrypted]"))
+			}
+			if tokenloc.Store != storename {
+				unmigrated = true
+			}
+			fmt.Println()
+		}
+		if info.KeyFile != "" {
+			fmt.Printf("  key:   %s\n", info.KeyFile)
+		}
+	}
+	if unmigrated && storeerr == nil {
+		fmt.Printf("\nSome tokens are not in the %s store. They are moved when they are next used, or run 'gin auth migrate' to move them now.\n", storename)
+	}
+}
+
+func authMigrate(cmd *cobra.Command, args []string) {
+	store, err := web.SelectedCredentialStore()
+	CheckError(err)
+	migrated, err := ginclient.MigrateCredentials()
+	for _, info := range migrated {
+		for _, tokenloc := range info.Tokens {
+			fmt.Fprintf(color.Output, ":: Moved token for %s [profile %s] from %s to %s\n", info.Server, info.Profile, tokenloc.Store, green(store.Name()))
+		}
+	}
+	CheckError(err)
+	if len(migrated) == 0 {
+		fmt.Printf(":: All tokens are in the %s store\n", store.Name())
+	}
+}
+
+func authStatusCmd() *cobra.Command {
+	description := "Show the credential store used for login tokens, the active profile, the login status of the active profile on each configured server, and where the tokens and keys of all profiles and servers are stored.\n\nFor each server, the login token is checked with the server, the registered keys of the account are compared with the session key (or your own keys, see 'gin login --no-session-key'), and the host key of the git server is compared with the configured one. Checks that fail because a server cannot be reached are reported but do not affect the other servers."
+	var cmd = &cobra.Command{
+		Use:                   "status [--json]",
+		Short:                 "Show the login status and where login credentials are stored",
+		Long:                  formatdesc(description, nil),
+		Args:                  cobra.NoArgs,
+		Run:                   authStatus,
+		DisableFlagsInUseLine: true,
+	}
+	cmd.Flags().Bool("json", false, jsonHelpMsg)
+	return cmd
+}
+
+func authMigrateCmd() *cobra.Command {
+	description := "Move the login tokens of all profiles and servers from other credential stores to the configured store. Tokens are also moved automatically from unencrypted files of earlier versions when they are first used."
+	var cmd = &cobra.Command{
+		Use:                   "migrate",
+		Short:                 "Move login tokens to the configured credential store",
+		Long:                  formatdesc(description, nil),
+		Args:                  cobra.NoArgs,
+		Run:                   authMigrate,
+		DisableFlagsInUseLine: true,
+	}
+	return cmd
+}
+
+// AuthCmd sets up the 'auth' command and its subcommands for inspecting and managing login credentials
+func AuthCmd() *cobra.Command {
+	description := fmt.Sprintf(`Inspect and manage the stored login credentials.
+
+Login tokens are kept in the credential store selected by the credentials.store configuration key (see 'gin config'):
+
+  keyring         the system keyring (Secret Service on Linux, through secret-tool, or the macOS keychain)
+  encrypted-file  files in the configuration directory, encrypted with a passphrase; the passphrase is read from the %s environment variable or prompted for
+  file            unencrypted files in the configuration directory
+  auto            the keyring if it is available, otherwise unencrypted files (default)`, web.PassphraseEnvVar)
+	var cmd = &cobra.Command{
+		Use:                   "auth <command>",
+		Short:                 "Inspect and manage login credentials",
+		Long:                  formatdesc(description, nil),
+		Args:                  cobra.NoArgs,
+		DisableFlagsInUseLine: true,
+	}
+	cmd.AddCommand(authStatusCmd())
+	cmd.AddCommand(authMigrateCmd())
+	return cmd
+}
@@ -0,0 +1,92 @@
+package gincmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	ginclient "github.com/G-Node/gin-cli/ginclient"
+	"github.com/G-Node/gin-cli/ginclient/config"
+	gogs "github.com/gogits/go-gogs-client"
+	"github.com/spf13/cobra"
+)
+
+// permissionName returns the name of the highest access level in a permission set.
+func permissionName(perm gogs.Permission) string {
+	switch {
+	case perm.Admin:
+		return "admin"
+	case perm.Push:
+		return "write"
+	case perm.Pull:
+		return "read"
+	default:
+		return "none"
+	}
+}
+
+func collaborators(cmd *cobra.Command, args []string) {
+	flags := cmd.Flags()
+	jsonout, _ := flags.GetBool("json")
+	srvalias, _ := flags.GetString("server")
+
+	conf := config.Read()
+	if srvalias == "" {
+		srvalias = conf.DefaultServer
+	}
+	repostr := args[0]
+	if !isValidRepoPath(repostr) {
+		Die(fmt.Sprintf("Invalid repository path '%s'. Full repository name should be the owner's username followed by the repository name, separated by a '/'.", repostr))
+	}
+
+	gincl := ginclient.New(srvalias)
+	requirelogin(cmd, gincl, !jsonout)
+	collabs, err := gincl.ListCollaborators(repostr)
+	CheckError(err)
+
+	if jsonout {
+		type collabinfo struct {
+			UserName   string `json:"username"`
+			FullName   string `json:"full_name"`
+			Permission string `json:"permission"`
+		}
+		outlist := make([]collabinfo, len(collabs))
+		for idx, c := range collabs {
+			outlist[idx] = collabinfo{UserName: c.UserName, FullName: c.FullName, Permission: permissionName(c.Permissions)}
+		}
+		j, _ := json.Marshal(outlist)
+		fmt.Println(string(j))
+		return
+	}
+
+	if len(collabs) == 0 {
+		fmt.Printf("Repository '%s' has no collaborators\n", repostr)
+		return
+	}
+	fmt.Printf(":: Collaborators of '%s'\n", repostr)
+	for _, c := range collabs {
+		fmt.Printf("* %s", c.UserName)
+		if c.FullName != "" {
+			fmt.Printf(" (%s)", c.FullName)
+		}
+		fmt.Printf(": %s\n", permissionName(c.Permissions))
+	}
+}
+
+// CollaboratorsCmd sets up the 'collaborators' listing subcommand
+func CollaboratorsCmd() *cobra.Command {
+	description := "List the users that have been given access to a repository and their permission level (read, write, or admin). Listing collaborators requires write or administrative access to the repository."
+	args := map[string]string{
+		"<repopath>": "The repository path must be specified on the command line. A repository path is the owner's username, followed by a \"/\" and the repository name.",
+	}
+	var cmd = &cobra.Command{
+		Use:                   "collaborators [--json] <repopath>",
+		Short:                 "List the users that have access to a repository",
+		Long:                  formatdesc(description, args),
+		Args:                  cobra.ExactArgs(1),
+		Run:                   collaborators,
+		DisableFlagsInUseLine: true,
+	}
+	cmd.Flags().Bool("json", false, jsonHelpMsg)
+	cmd.Flags().String("server", "", "Specify server `alias` where the repository resides. See also 'gin servers'.")
+	return cmd
+}
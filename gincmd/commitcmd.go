@@ -23,6 +23,10 @@ func commit(cmd *cobra.Command, args []string) {
 		annexVersionNotice()
 	}
 
+	if _, err := git.CurrentBranch(); err != nil {
+		Warn("no branch is checked out: the changes cannot be uploaded until they are recorded on a branch")
+	}
+
 	commitmsg, _ := cmd.Flags().GetString("message")
 
 	// TODO: Exit with error if a path argument is neither a file known to git nor a file in the working tree
@@ -4,14 +4,17 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"math"
 	"os"
 	"runtime"
 	"strings"
 
 	ginclient "github.com/G-Node/gin-cli/ginclient"
+	"github.com/G-Node/gin-cli/ginclient/config"
 	"github.com/G-Node/gin-cli/ginclient/log"
 	"github.com/G-Node/gin-cli/git"
+	"github.com/G-Node/gin-cli/web"
 	"github.com/bbrks/wrap"
 	"github.com/docker/docker/pkg/term"
 	"github.com/fatih/color"
@@ -32,21 +35,31 @@ var (
 
 	reqgitannex = []string{
 		"add-remote",
+		"addurl",
 		"commit",
 		"create",
 		"download",
+		"export",
+		"find",
+		"fork",
 		"get",
 		"get-content",
+		"import",
 		"init",
 		"lock",
 		"ls",
+		"meta",
+		"package",
 		"remotes",
 		"remove-content",
 		"remove-remote",
+		"rules",
+		"tag",
 		"unlock",
 		"upload",
 		"use-remote",
 		"version",
+		"whereis",
 	}
 )
 
@@ -112,11 +125,68 @@ func CheckErrorMsg(err error, msg string) {
 	}
 }
 
-// requirelogin prompts for login if the user is not already logged in.
-// It only checks if a local token exists and does not confirm its validity with the server.
+// requirelogin loads the login token for the client's server and validates it with the server (see ginclient.ValidateToken).
+// If the token is no longer valid, the program exits with a prompt to log in again.
+// Without a token, the command continues and requests are made anonymously.
 // The function should be called at the start of any command that requires being logged in to run.
 func requirelogin(cmd *cobra.Command, gincl *ginclient.Client, prompt bool) {
-	gincl.LoadToken()
+	err := gincl.LoadToken()
+	if err == ginclient.ErrTokenInvalid {
+		// token from the environment
+		Die(reloginMsg(gincl))
+	} else if err != nil {
+		log.Write("Not logged in: %s", err)
+		if gincl.UsesEnvToken() {
+			// the username for the environment token could not be determined
+			CheckError(err)
+		}
+		return
+	}
+	err = gincl.ValidateToken()
+	if err == ginclient.ErrTokenInvalid {
+		Die(reloginMsg(gincl))
+	} else if err != nil {
+		// the server could not be reached; the command reports any connection errors
+		log.Write("Token validation failed: %s", err)
+	}
+}
+
+// reloginMsg returns the message shown when the login token for the client's server is no longer accepted.
+func reloginMsg(gincl *ginclient.Client) string {
+	if gincl.UsesEnvToken() {
+		return fmt.Sprintf("%s (set in %s)", ginclient.ErrTokenInvalid, ginclient.TokenEnvVar)
+	}
+	loginCmd := "gin login"
+	if alias := gincl.ServerAlias(); alias != config.Read().DefaultServer {
+		loginCmd = fmt.Sprintf("gin login --server %s", alias)
+	}
+	if profile, _ := config.ActiveProfile(); profile != config.DefaultProfile {
+		loginCmd = fmt.Sprintf("%s --profile %s", loginCmd, profile)
+	}
+	return fmt.Sprintf("%s. Log in again with '%s'.", ginclient.ErrTokenInvalid, loginCmd)
+}
+
+// repoTarget determines the server alias and repository path for commands that operate on a server repository associated with the local clone.
+// The --repo and --server flags take precedence. Otherwise, the repository of the default remote of the current working directory is used.
+func repoTarget(cmd *cobra.Command) (string, string) {
+	flags := cmd.Flags()
+	srvalias, _ := flags.GetString("server")
+	repostr, _ := flags.GetString("repo")
+	if repostr == "" {
+		remotesrv, remotepath, err := ginclient.DefaultRemoteRepoPath()
+		CheckErrorMsg(err, "Could not determine the repository on the server. Run the command in a repository with a default GIN remote or specify the repository with --repo.")
+		repostr = remotepath
+		if srvalias == "" {
+			srvalias = remotesrv
+		}
+	}
+	if !isValidRepoPath(repostr) {
+		Die(fmt.Sprintf("Invalid repository path '%s'. Full repository name should be the owner's username followed by the repository name, separated by a '/'.", repostr))
+	}
+	if srvalias == "" {
+		srvalias = config.Read().DefaultServer
+	}
+	return srvalias, repostr
 }
 
 func annexVersionNotice() {
@@ -141,6 +211,29 @@ func usageDie(cmd *cobra.Command) {
 	Die("")
 }
 
+// confirm prints the prompt to stderr, so that it does not mix with the output of the command, and asks the user to answer 'yes' or 'no'.
+// It returns true only if the answer is 'yes'.
+// If the input is closed without an answer, it returns false.
+func confirm(prompt string) bool {
+	fmt.Fprintf(os.Stderr, "%s [yes/no]: ", prompt)
+	var response string
+	for {
+		_, err := fmt.Scanln(&response)
+		if err == io.EOF {
+			fmt.Fprintln(os.Stderr)
+			return false
+		}
+		switch strings.ToLower(response) {
+		case "yes", "y":
+			return true
+		case "no", "n":
+			return false
+		default:
+			fmt.Fprint(os.Stderr, "Please type 'yes' or 'no': ")
+		}
+	}
+}
+
 func printJSON(statuschan <-chan git.RepoFileStatus) (filesuccess map[string]bool) {
 	filesuccess = make(map[string]bool)
 	for stat := range statuschan {
@@ -468,6 +561,44 @@ func disableCommands(cmds map[string]*cobra.Command, giterr, annexerr error) {
 
 }
 
+// applyGlobalFlags sets the configuration overrides given by the global flags.
+// Commands that define their own --server flag use it directly.
+func applyGlobalFlags(cmd *cobra.Command, args []string) {
+	flags := cmd.Flags()
+	if flags.Changed("config-dir") {
+		confdir, _ := flags.GetString("config-dir")
+		config.SetConfigDir(confdir)
+	}
+	if pflag := cmd.InheritedFlags().Lookup("server"); pflag != nil && pflag.Changed {
+		CheckError(config.SetFlagOverride("defaultserver", pflag.Value.String(), "--server"))
+	}
+	if flags.Changed("profile") {
+		profile, _ := flags.GetString("profile")
+		CheckError(config.SetFlagProfile(profile))
+	}
+	// profile bound to the repository (see 'gin use-profile')
+	if profile, err := git.ConfigGet(ginclient.ProfileConfigKey); err == nil && profile != "" {
+		if err = config.SetRepoProfile(profile); err != nil {
+			Warn(fmt.Sprintf("%s (git config %s): ignored", err, ginclient.ProfileConfigKey))
+		}
+	}
+}
+
+// PreParseConfigDir sets the configuration directory from the --config-dir flag in the raw command line arguments.
+// It is used before the commands are set up, so that the configured binaries are used for the dependency checks.
+func PreParseConfigDir(args []string) {
+	for idx, arg := range args {
+		switch {
+		case arg == "--":
+			return
+		case arg == "--config-dir" && idx+1 < len(args):
+			config.SetConfigDir(args[idx+1])
+		case strings.HasPrefix(arg, "--config-dir="):
+			config.SetConfigDir(strings.TrimPrefix(arg, "--config-dir="))
+		}
+	}
+}
+
 // SetUpCommands sets up all the subcommands for the client and returns the root command, ready to execute.
 func SetUpCommands(verinfo VersionInfo) *cobra.Command {
 	verstr := verinfo.String()
@@ -476,9 +607,16 @@ func SetUpCommands(verinfo VersionInfo) *cobra.Command {
 		Long:                  "GIN Command Line Interface and client for the GIN services", // TODO: Add license and web info
 		Version:               fmt.Sprintln(verstr),
 		DisableFlagsInUseLine: true,
+		PersistentPreRun:      applyGlobalFlags,
 	}
+	rootCmd.PersistentFlags().String("config-dir", "", "Read and write the user configuration in `directory` instead of the default location (overrides GIN_CONFIG_DIR).")
+	rootCmd.PersistentFlags().String("server", "", "Use the server with the given `alias` as the default server (overrides the defaultserver setting). See also 'gin servers'.")
+	rootCmd.PersistentFlags().String("profile", "", "Use the credentials of the profile with the given `name` (overrides GIN_PROFILE and the profile of the repository). See also 'gin use-profile'.")
 	cmds := make(map[string]*cobra.Command)
 
+	web.PassphrasePrompt = promptPassphrase
+	ginclient.PassphrasePrompt = promptPassphrase
+
 	// Login
 	cmds["login"] = LoginCmd()
 
@@ -494,21 +632,57 @@ func SetUpCommands(verinfo VersionInfo) *cobra.Command {
 	// Use server
 	cmds["use-server"] = UseServerCmd()
 
+	// Use profile
+	cmds["use-profile"] = UseProfileCmd()
+
+	// Credentials
+	cmds["auth"] = AuthCmd()
+
+	// Git credential helper (unlisted)
+	cmds["credential-helper"] = CredentialHelperCmd()
+
 	// Servers
 	cmds["servers"] = ServersCmd()
 
+	// Client configuration
+	cmds["config"] = ConfigCmd()
+
 	// Account info
 	cmds["info"] = InfoCmd()
 
+	// Logged in user
+	cmds["whoami"] = WhoamiCmd()
+
 	// List repos
 	cmds["repos"] = ReposCmd()
 
+	// List organisations
+	cmds["orgs"] = OrgsCmd()
+
+	// Search repos
+	cmds["search"] = SearchCmd()
+
 	// Repo info
 	cmds["repoinfo"] = RepoInfoCmd()
 
+	// Repo settings
+	cmds["repo"] = RepoCmd()
+
+	// Share repo
+	cmds["share"] = ShareCmd()
+
+	// Unshare repo
+	cmds["unshare"] = UnshareCmd()
+
+	// List collaborators
+	cmds["collaborators"] = CollaboratorsCmd()
+
 	// Keys
 	cmds["keys"] = KeysCmd()
 
+	// Access tokens
+	cmds["tokens"] = TokensCmd()
+
 	// Init repo
 	cmds["init"] = InitCmd()
 
@@ -530,6 +704,12 @@ func SetUpCommands(verinfo VersionInfo) *cobra.Command {
 	// Delete repo (unlisted)
 	cmds["delete"] = DeleteCmd()
 
+	// Fork repo
+	cmds["fork"] = ForkCmd()
+
+	// Transfer repo
+	cmds["transfer"] = TransferCmd()
+
 	// Get repo
 	cmds["get"] = GetCmd()
 
@@ -542,6 +722,9 @@ func SetUpCommands(verinfo VersionInfo) *cobra.Command {
 	// Lock content
 	cmds["lock"] = LockCmd()
 
+	// Add files from URLs
+	cmds["addurl"] = AddURLCmd()
+
 	// Commit changes
 	cmds["commit"] = CommitCmd()
 
@@ -554,12 +737,45 @@ func SetUpCommands(verinfo VersionInfo) *cobra.Command {
 	// Sync
 	cmds["sync"] = SyncCmd()
 
+	// Content locations
+	cmds["whereis"] = WhereisCmd()
+
+	// Annex rules
+	cmds["rules"] = RulesCmd()
+
+	// File metadata
+	cmds["meta"] = MetaCmd()
+
+	// Find files
+	cmds["find"] = FindCmd()
+
 	// Get content
 	cmds["get-content"] = GetContentCmd()
 
 	// Remove content
 	cmds["remove-content"] = RemoveContentCmd()
 
+	// Tag
+	cmds["tag"] = TagCmd()
+
+	// Releases
+	cmds["release"] = ReleaseCmd()
+
+	// DOI metadata
+	cmds["datacite"] = DataCiteCmd()
+
+	// DOI requests
+	cmds["doi"] = DOICmd()
+
+	// Import
+	cmds["import"] = ImportCmd()
+
+	// Export
+	cmds["export"] = ExportCmd()
+
+	// Package
+	cmds["package"] = PackageCmd()
+
 	// Version
 	cmds["version"] = VersionCmd()
 
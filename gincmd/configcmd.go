@@ -0,0 +1,244 @@
+package gincmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/G-Node/gin-cli/ginclient/config"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+// configScope returns the scope selected by the --global and --local flags.
+// If neither is set, defscope is returned.
+func configScope(cmd *cobra.Command, defscope string) string {
+	global, _ := cmd.Flags().GetBool("global")
+	local, _ := cmd.Flags().GetBool("local")
+	switch {
+	case global && local:
+		usageDie(cmd)
+	case global:
+		return config.GlobalScope
+	case local:
+		return config.LocalScope
+	}
+	return defscope
+}
+
+// scopeSource returns the scope of a setting and the file, variable, or flag it comes from.
+func scopeSource(setting config.Setting) string {
+	if setting.Source == "" {
+		return setting.Scope
+	}
+	return fmt.Sprintf("%s: %s", setting.Scope, setting.Source)
+}
+
+func configGet(cmd *cobra.Command, args []string) {
+	jsonout, _ := cmd.Flags().GetBool("json")
+	showorigin, _ := cmd.Flags().GetBool("origin")
+	scope := configScope(cmd, "")
+	setting, ok, err := config.GetSetting(args[0], scope)
+	CheckError(err)
+	if !ok {
+		// like 'git config', exit with an error without a message when the key is not set
+		Die("")
+	}
+	if jsonout {
+		j, _ := json.Marshal(setting)
+		fmt.Println(string(j))
+		return
+	}
+	if showorigin {
+		fmt.Printf("%s\t%s\n", scopeSource(setting), setting.Value)
+		return
+	}
+	fmt.Println(setting.Value)
+}
+
+func configSet(cmd *cobra.Command, args []string) {
+	scope := configScope(cmd, config.GlobalScope)
+	CheckError(config.SetValue(args[0], args[1], scope))
+	setting, _, err := config.GetSetting(args[0], scope)
+	CheckError(err)
+	fmt.Fprintf(color.Output, ":: %s set to %s (%s)\n", cyan(setting.Key), setting.Value, setting.Source)
+}
+
+func configUnset(cmd *cobra.Command, args []string) {
+	scope := configScope(cmd, config.GlobalScope)
+	CheckError(config.UnsetValue(args[0], scope))
+	fmt.Fprintf(color.Output, ":: %s removed from the %s configuration\n", cyan(args[0]), scope)
+}
+
+func configList(cmd *cobra.Command, args []string) {
+	jsonout, _ := cmd.Flags().GetBool("json")
+	showorigin, _ := cmd.Flags().GetBool("origin")
+	scope := configScope(cmd, "")
+	settings, err := config.ListSettings(scope)
+	CheckError(err)
+	if jsonout {
+		if settings == nil {
+			settings = []config.Setting{}
+		}
+		j, _ := json.Marshal(settings)
+		fmt.Println(string(j))
+		return
+	}
+	for _, setting := range settings {
+		if showorigin {
+			fmt.Printf("%s\t", scopeSource(setting))
+		}
+		fmt.Printf("%s=%s\n", setting.Key, setting.Value)
+	}
+}
+
+func configCheck(cmd *cobra.Command, args []string) {
+	jsonout, _ := cmd.Flags().GetBool("json")
+	problems, err := config.Check()
+	CheckError(err)
+	settings, err := config.ListSettings("")
+	CheckError(err)
+	if jsonout {
+		if problems == nil {
+			problems = []config.Problem{}
+		}
+		j, _ := json.Marshal(struct {
+			Problems []config.Problem `json:"problems"`
+			Settings []config.Setting `json:"settings"`
+		}{problems, settings})
+		fmt.Println(string(j))
+	} else {
+		fmt.Println(":: Effective configuration")
+		for _, setting := range settings {
+			fmt.Fprintf(color.Output, "  %s=%s %s\n", setting.Key, setting.Value, cyan(fmt.Sprintf("(%s)", scopeSource(setting))))
+		}
+		fmt.Println()
+		if len(problems) == 0 {
+			fmt.Fprintln(color.Output, green(":: No problems found"))
+			return
+		}
+		fmt.Fprintf(color.Output, "%s\n", red(fmt.Sprintf(":: %d problem(s) found", len(problems))))
+		for _, p := range problems {
+			if p.Key == "" {
+				fmt.Printf("  %s: %s\n", p.Source, p.Message)
+			} else {
+				fmt.Printf("  %s: %s: %s\n", p.Source, p.Key, p.Message)
+			}
+		}
+	}
+	if len(problems) > 0 {
+		Die("")
+	}
+}
+
+func addScopeFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("global", false, "Use the user configuration file.")
+	cmd.Flags().Bool("local", false, "Use the configuration file in the root of the current repository (annex settings only).")
+}
+
+func configGetCmd() *cobra.Command {
+	description := "Print the value of a configuration key. By default, the effective value is printed, which may come from a command line flag, an environment variable, the repository configuration, the user configuration, or the built-in defaults. With --global or --local, only the value in the respective file is printed. If the key is not set, nothing is printed and the command exits with an error."
+	args := map[string]string{
+		"<key>": "The configuration key (e.g., annex.minsize or servers.gin.web.port).",
+	}
+	var cmd = &cobra.Command{
+		Use:                   "get [--global | --local] [--origin] [--json] <key>",
+		Short:                 "Print the value of a configuration key",
+		Long:                  formatdesc(description, args),
+		Args:                  cobra.ExactArgs(1),
+		Run:                   configGet,
+		DisableFlagsInUseLine: true,
+	}
+	addScopeFlags(cmd)
+	cmd.Flags().Bool("origin", false, "Show where the value comes from.")
+	cmd.Flags().Bool("json", false, jsonHelpMsg)
+	return cmd
+}
+
+func configSetCmd() *cobra.Command {
+	description := "Set the value of a configuration key. The value is checked before it is written: sizes must be a number with an optional unit (e.g., 10M), ports a number between 1 and 65535, and paths must exist (executables given by name are looked up in the PATH). By default, the value is written to the user configuration file. The repository configuration (--local) can only hold the annex settings."
+	args := map[string]string{
+		"<key>":   "The configuration key. Known keys are listed in the help of 'gin config'.",
+		"<value>": "The new value. Lists (annex.exclude) are given as comma separated values.",
+	}
+	examples := map[string]string{
+		"Annex files larger than 1 megabyte":                       "$ gin config set annex.minsize 1M",
+		"Never annex Python and Markdown files in this repository": "$ gin config set --local annex.exclude '*.py,*.md'",
+		"Use a specific git-annex installation":                    "$ gin config set bin.gitannex /opt/git-annex/git-annex",
+	}
+	var cmd = &cobra.Command{
+		Use:                   "set [--global | --local] <key> <value>",
+		Short:                 "Set the value of a configuration key",
+		Long:                  formatdesc(description, args),
+		Example:               formatexamples(examples),
+		Args:                  cobra.ExactArgs(2),
+		Run:                   configSet,
+		DisableFlagsInUseLine: true,
+	}
+	addScopeFlags(cmd)
+	return cmd
+}
+
+func configUnsetCmd() *cobra.Command {
+	description := "Remove a key from a configuration file, so that the value from the defaults (or the user configuration, for --local) applies again. Unknown keys reported by 'gin config check' can also be removed this way."
+	args := map[string]string{
+		"<key>": "The configuration key to remove.",
+	}
+	var cmd = &cobra.Command{
+		Use:                   "unset [--global | --local] <key>",
+		Short:                 "Remove a configuration key",
+		Long:                  formatdesc(description, args),
+		Args:                  cobra.ExactArgs(1),
+		Run:                   configUnset,
+		DisableFlagsInUseLine: true,
+	}
+	addScopeFlags(cmd)
+	return cmd
+}
+
+func configListCmd() *cobra.Command {
+	description := "List configuration values. By default, all effective values are listed, including the defaults. With --global or --local, only the values in the respective file are listed."
+	var cmd = &cobra.Command{
+		Use:                   "list [--global | --local] [--origin] [--json]",
+		Short:                 "List configuration values",
+		Long:                  formatdesc(description, nil),
+		Args:                  cobra.NoArgs,
+		Run:                   configList,
+		DisableFlagsInUseLine: true,
+	}
+	addScopeFlags(cmd)
+	cmd.Flags().Bool("origin", false, "Show where each value comes from: the defaults, a configuration file, an environment variable, or a command line flag.")
+	cmd.Flags().Bool("json", false, jsonHelpMsg)
+	return cmd
+}
+
+func configCheckCmd() *cobra.Command {
+	description := "Check the user and repository configuration files for unknown keys, invalid values, and settings that are ignored, and show the effective configuration with the file each value comes from. The command exits with an error if any problems are found."
+	var cmd = &cobra.Command{
+		Use:                   "check [--json]",
+		Short:                 "Check the configuration files for problems",
+		Long:                  formatdesc(description, nil),
+		Args:                  cobra.NoArgs,
+		Run:                   configCheck,
+		DisableFlagsInUseLine: true,
+	}
+	cmd.Flags().Bool("json", false, jsonHelpMsg)
+	return cmd
+}
+
+// ConfigCmd sets up the 'config' command and its subcommands for reading and writing the client configuration
+func ConfigCmd() *cobra.Command {
+	description := "Read, write, and check the client configuration. Values are read from the following sources, in decreasing order of precedence:\n\n  1. command line flags (--server for defaultserver)\n  2. environment variables (e.g., GIN_ANNEX_MINSIZE for annex.minsize or GIN_SERVERS_TEST_WEB_HOST for servers.test.web.host)\n  3. the config.yml file in the root of the current repository (annex settings only)\n  4. the user configuration file (the directory can be changed with --config-dir or GIN_CONFIG_DIR)\n  5. the built-in defaults\n\nEnvironment variables and flags only apply to the current invocation. Use 'gin config list --origin' to see where each value comes from.\n\nKnown keys: bin.git, bin.gitannex, bin.gitannexpath, bin.ssh, annex.minsize, annex.exclude, defaultserver, credentials.store (auto, keyring, encrypted-file, or file), keys.type (ed25519 or rsa), keys.bits (RSA key size), keys.encrypt (true or false), and for each server alias: servers.<alias>.web.protocol, servers.<alias>.web.host, servers.<alias>.web.port, servers.<alias>.git.user, servers.<alias>.git.host, servers.<alias>.git.port, servers.<alias>.git.hostkey, servers.<alias>.git.transport (ssh or https), servers.<alias>.git.useagent (true or false), servers.<alias>.git.identityfiles (comma separated list of key files)."
+	var cmd = &cobra.Command{
+		Use:                   "config <command>",
+		Short:                 "Read, write, and check the client configuration",
+		Long:                  formatdesc(description, nil),
+		Args:                  cobra.NoArgs,
+		DisableFlagsInUseLine: true,
+	}
+	cmd.AddCommand(configGetCmd())
+	cmd.AddCommand(configSetCmd())
+	cmd.AddCommand(configUnsetCmd())
+	cmd.AddCommand(configListCmd())
+	cmd.AddCommand(configCheckCmd())
+	return cmd
+}
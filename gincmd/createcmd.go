@@ -17,6 +17,7 @@ func createRepo(cmd *cobra.Command, args []string) {
 	here, _ := flags.GetBool("here")
 	noclone, _ := flags.GetBool("no-clone")
 	srvalias, _ := flags.GetString("server")
+	org, _ := flags.GetString("org")
 
 	if noclone && here {
 		usageDie(cmd)
@@ -39,9 +40,18 @@ func createRepo(cmd *cobra.Command, args []string) {
 			repoDesc = args[1]
 		}
 	}
-	repopath := fmt.Sprintf("%s/%s", gincl.Username, repoName)
+	owner := gincl.Username
+	if org != "" {
+		owner = org
+	}
+	repopath := fmt.Sprintf("%s/%s", owner, repoName)
 	fmt.Printf(":: Creating repository '%s' ", repopath)
-	err := gincl.CreateRepo(repoName, repoDesc)
+	var err error
+	if org != "" {
+		err = gincl.CreateOrgRepo(org, repoName, repoDesc)
+	} else {
+		err = gincl.CreateRepo(repoName, repoDesc)
+	}
 	CheckError(err)
 	fmt.Fprintln(color.Output, green("OK"))
 
@@ -83,10 +93,11 @@ func CreateCmd() *cobra.Command {
 		"Create a repository named 'example' with no description":                                            "$ gin create example",
 		"Create a repository named 'mydata' and initialise the current working directory as the local clone": "$ gin create --here mydata",
 		"Create a repository named 'eegdata' with a description":                                             "$ gin create eegdata \"My repository for storing EEG data\"",
+		"Create a repository named 'ephys' owned by the organisation 'lab'":                                  "$ gin create --org lab ephys",
 	}
 
 	var cmd = &cobra.Command{
-		Use:                   "create [--here | --no-clone] [<repository>] [<description>]",
+		Use:                   "create [--here | --no-clone] [--org <organisation>] [<repository>] [<description>]",
 		Short:                 "Create a new repository on the GIN server",
 		Long:                  formatdesc(description, args),
 		Example:               formatexamples(examples),
@@ -96,6 +107,7 @@ func CreateCmd() *cobra.Command {
 	}
 	cmd.Flags().Bool("here", false, "Create the local repository clone in the current working directory. Cannot be used with --no-clone.")
 	cmd.Flags().Bool("no-clone", false, "Create repository on the server but do not clone it locally. Cannot be used with --here.")
+	cmd.Flags().String("org", "", "Create the repository under the given `organisation` instead of the logged in user. You must be allowed to create repositories in the organisation.")
 	cmd.Flags().String("server", "", "Specify server `alias` where the repository will be created. See also 'gin servers'.")
 	return cmd
 }
@@ -0,0 +1,65 @@
+package gincmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	ginclient "github.com/G-Node/gin-cli/ginclient"
+	"github.com/G-Node/gin-cli/ginclient/log"
+	"github.com/G-Node/gin-cli/git"
+	"github.com/spf13/cobra"
+)
+
+// readCredentialRequest reads the attributes of a git credential request (key=value lines, terminated by an empty line or the end of input).
+func readCredentialRequest(in io.Reader) map[string]string {
+	attrs := make(map[string]string)
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			break
+		}
+		if kv := strings.SplitN(line, "=", 2); len(kv) == 2 {
+			attrs[kv[0]] = kv[1]
+		}
+	}
+	return attrs
+}
+
+// answerCredentialRequest reads a credential request for the given action (get, store, or erase) and writes the response.
+func answerCredentialRequest(action string, in io.Reader, out io.Writer) {
+	attrs := readCredentialRequest(in)
+	if action != "get" {
+		// tokens are stored and deleted with 'gin login' and 'gin logout'
+		return
+	}
+	username, password, err := ginclient.GitCredential(attrs["protocol"], attrs["host"])
+	if err != nil {
+		// git falls back to other helpers or prompts when no credentials are returned
+		log.Write("credential-helper: %s", err)
+		return
+	}
+	fmt.Fprintf(out, "username=%s\npassword=%s\n", username, password)
+}
+
+func credentialHelper(cmd *cobra.Command, args []string) {
+	answerCredentialRequest(args[0], os.Stdin, os.Stdout)
+}
+
+// CredentialHelperCmd sets up the (unlisted) 'credential-helper' command, which provides the login token to git for servers that use the HTTPS transport
+func CredentialHelperCmd() *cobra.Command {
+	description := "Git credential helper for servers that use the HTTPS transport (see the servers.<alias>.git.transport configuration key). It provides the stored login token to git and git-annex, so that repositories can be cloned, uploaded, and downloaded over HTTPS (port 443) when SSH connections are blocked.\n\nThe helper is configured automatically for all git commands run by the client and does not need to be added to the git configuration. It only answers 'get' requests; tokens are stored and removed with 'gin login' and 'gin logout'."
+	var cmd = &cobra.Command{
+		Use:                   fmt.Sprintf("%s <get|store|erase>", git.CredentialHelperCmd),
+		Short:                 "Git credential helper for the HTTPS transport",
+		Long:                  formatdesc(description, nil),
+		Args:                  cobra.ExactArgs(1),
+		Run:                   credentialHelper,
+		DisableFlagsInUseLine: true,
+		Hidden:                true,
+	}
+	return cmd
+}
@@ -0,0 +1,65 @@
+package gincmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/G-Node/gin-cli/ginclient/config"
+	"github.com/G-Node/gin-cli/web"
+)
+
+func TestAnswerCredentialRequest(t *testing.T) {
+	confdir, err := ioutil.TempDir("", "gin-credential-helper-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(confdir)
+	config.SetConfigDir(confdir)
+	defer config.SetConfigDir("")
+
+	config.SetConfig("credentials.store", web.FileStore)
+	srvcfg := config.ServerCfg{
+		Web: config.WebCfg{Protocol: "https", Host: "gin.example.org", Port: 443},
+		Git: config.GitCfg{Host: "gin.example.org", Port: 22, User: "git", Transport: config.TransportHTTPS},
+	}
+	if err = config.AddServerConf("httpstest", srvcfg); err != nil {
+		t.Fatalf("failed to configure test server: %v", err)
+	}
+	ut := web.UserToken{Username: "alice", Token: "secrettoken"}
+	if err = ut.StoreToken("httpstest"); err != nil {
+		t.Fatalf("failed to store token: %v", err)
+	}
+
+	request := "protocol=https\nhost=gin.example.org\npath=alice/repo.git\n\n"
+	cases := []struct {
+		action   string
+		request  string
+		response string
+	}{
+		{"get", request, "username=secrettoken\npassword=x-token\n"},
+		{"get", strings.Replace(request, "gin.example.org", "gin.example.org:443", 1), "username=secrettoken\npassword=x-token\n"},
+		// other servers and protocols are left to other helpers
+		{"get", strings.Replace(request, "gin.example.org", "other.example.org", 1), ""},
+		{"get", strings.Replace(request, "https", "http", 1), ""},
+		// tokens are only changed by login and logout
+		{"store", "protocol=https\nhost=gin.example.org\nusername=secrettoken\npassword=x-token\n\n", ""},
+		{"erase", "protocol=https\nhost=gin.example.org\nusername=secrettoken\npassword=x-token\n\n", ""},
+	}
+	for _, c := range cases {
+		var out bytes.Buffer
+		answerCredentialRequest(c.action, strings.NewReader(c.request), &out)
+		if out.String() != c.response {
+			t.Errorf("%s %q: expected response %q, got %q", c.action, c.request, c.response, out.String())
+		}
+	}
+
+	// the token is still available after 'erase'
+	var out bytes.Buffer
+	answerCredentialRequest("get", strings.NewReader(request), &out)
+	if !strings.Contains(out.String(), "username=secrettoken") {
+		t.Errorf("token not available after erase request: %q", out.String())
+	}
+}
@@ -0,0 +1,145 @@
+package gincmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	ginclient "github.com/G-Node/gin-cli/ginclient"
+	"github.com/G-Node/gin-cli/git"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+// dataciteFilePath returns the path of the DataCite file at the root of the current repository.
+// If the working directory is not in a repository, the path in the working directory is returned.
+func dataciteFilePath() string {
+	root, err := git.FindRepoRoot(".")
+	if err != nil || root == "" {
+		root = "."
+	}
+	return filepath.Join(root, ginclient.DataCiteFileName)
+}
+
+// printDataCiteProblems prints the list of problems found in a DataCite file.
+func printDataCiteProblems(fname string, problems []string) {
+	fmt.Fprintf(color.Output, ":: %s has %d problem(s):\n", fname, len(problems))
+	for _, p := range problems {
+		fmt.Fprintf(color.Output, "  %s %s\n", red("-"), p)
+	}
+}
+
+func dataciteInit(cmd *cobra.Command, args []string) {
+	force, _ := cmd.Flags().GetBool("force")
+	fname := dataciteFilePath()
+	if _, err := os.Stat(fname); err == nil && !force {
+		Die(fmt.Sprintf("%s already exists. Use --force to overwrite it.", fname))
+	}
+
+	srvalias, repostr := repoTarget(cmd)
+	gincl := ginclient.New(srvalias)
+	requirelogin(cmd, gincl, true)
+	repoinfo, err := gincl.GetRepo(repostr)
+	CheckError(err)
+	acc, err := gincl.RequestAccount(gincl.Username)
+	CheckError(err)
+
+	dc := ginclient.NewDataCite()
+	dc.Title = repoinfo.Name
+	dc.Description = strings.TrimSpace(repoinfo.Description)
+	var author ginclient.DataCiteAuthor
+	names := strings.Fields(acc.FullName)
+	if len(names) > 0 {
+		author.LastName = names[len(names)-1]
+		author.FirstName = strings.Join(names[:len(names)-1], " ")
+	}
+	dc.Authors = []ginclient.DataCiteAuthor{author}
+
+	b, err := dc.Marshal()
+	CheckError(err)
+	err = ioutil.WriteFile(fname, b, 0664)
+	CheckErrorMsg(err, fmt.Sprintf("Failed to write %s", fname))
+	fmt.Printf(":: Created %s from the information of repository '%s'\n", fname, repostr)
+
+	if problems := ginclient.ValidateDataCite(b); len(problems) > 0 {
+		fmt.Println("The following fields still need to be completed before requesting a DOI:")
+		for _, p := range problems {
+			fmt.Fprintf(color.Output, "  %s %s\n", yellow("-"), p)
+		}
+	}
+	fmt.Println("Edit the file, check it with 'gin datacite validate', and commit and upload it with 'gin commit' and 'gin upload'.")
+}
+
+func dataciteValidate(cmd *cobra.Command, args []string) {
+	jsonout, _ := cmd.Flags().GetBool("json")
+	fname := dataciteFilePath()
+	if len(args) == 1 {
+		fname = args[0]
+	}
+	b, err := ioutil.ReadFile(fname)
+	CheckErrorMsg(err, fmt.Sprintf("Could not read %s. Use 'gin datacite init' to create one.", fname))
+	problems := ginclient.ValidateDataCite(b)
+
+	if jsonout {
+		j, _ := json.Marshal(struct {
+			File     string   `json:"file"`
+			Valid    bool     `json:"valid"`
+			Problems []string `json:"problems"`
+		}{fname, len(problems) == 0, problems})
+		fmt.Println(string(j))
+		if len(problems) > 0 {
+			Die("")
+		}
+		return
+	}
+	if len(problems) > 0 {
+		printDataCiteProblems(fname, problems)
+		Die(fmt.Sprintf("%s is not valid", fname))
+	}
+	fmt.Fprintf(color.Output, ":: %s %s\n", fname, green("is valid"))
+}
+
+// DataCiteCmd sets up the 'datacite' command and its subcommands
+func DataCiteCmd() *cobra.Command {
+	description := fmt.Sprintf("Create and check the %s file that contains the metadata required for registering a DOI for a repository. See 'gin doi' for requesting a DOI.", ginclient.DataCiteFileName)
+	var cmd = &cobra.Command{
+		Use:                   "datacite <command>",
+		Short:                 "Create and check DOI metadata files",
+		Long:                  formatdesc(description, nil),
+		Args:                  cobra.NoArgs,
+		DisableFlagsInUseLine: true,
+	}
+
+	initdesc := fmt.Sprintf("Create a %s file at the root of the current repository. The title, description, and first author are filled in from the repository and account information on the server. The remaining fields must be completed by hand.", ginclient.DataCiteFileName)
+	initcmd := &cobra.Command{
+		Use:                   "init [--force] [--repo <repopath>]",
+		Short:                 fmt.Sprintf("Create a new %s file", ginclient.DataCiteFileName),
+		Long:                  formatdesc(initdesc, nil),
+		Args:                  cobra.NoArgs,
+		Run:                   dataciteInit,
+		DisableFlagsInUseLine: true,
+	}
+	initcmd.Flags().Bool("force", false, "Overwrite an existing file.")
+	initcmd.Flags().String("repo", "", "The `repository` path (owner/name) on the server. If not specified, the repository of the default remote of the current working directory is used.")
+	initcmd.Flags().String("server", "", "Specify server `alias` where the repository resides. See also 'gin servers'.")
+
+	validatedesc := fmt.Sprintf("Check a %s file for missing fields and invalid values. The check is performed offline against the rules bundled with the client and does not guarantee that the server will accept the file.", ginclient.DataCiteFileName)
+	validateargs := map[string]string{
+		"<filename>": fmt.Sprintf("The file to check. Defaults to the %s file at the root of the current repository.", ginclient.DataCiteFileName),
+	}
+	validatecmd := &cobra.Command{
+		Use:                   "validate [--json] [<filename>]",
+		Short:                 fmt.Sprintf("Check a %s file", ginclient.DataCiteFileName),
+		Long:                  formatdesc(validatedesc, validateargs),
+		Args:                  cobra.MaximumNArgs(1),
+		Run:                   dataciteValidate,
+		DisableFlagsInUseLine: true,
+	}
+	validatecmd.Flags().Bool("json", false, jsonHelpMsg)
+
+	cmd.AddCommand(initcmd, validatecmd)
+	return cmd
+}
@@ -0,0 +1,76 @@
+package gincmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	ginclient "github.com/G-Node/gin-cli/ginclient"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+func doiRequest(cmd *cobra.Command, args []string) {
+	jsonout, _ := cmd.Flags().GetBool("json")
+	srvalias, repostr := repoTarget(cmd)
+
+	// Check the local metadata file first, if there is one, to catch problems before contacting the server
+	fname := dataciteFilePath()
+	if b, err := ioutil.ReadFile(fname); err == nil {
+		if problems := ginclient.ValidateDataCite(b); len(problems) > 0 {
+			if !jsonout {
+				printDataCiteProblems(fname, problems)
+			}
+			Die(fmt.Sprintf("%s is not valid. Fix the problems, commit and upload the file, and try again.", fname))
+		}
+	}
+
+	gincl := ginclient.New(srvalias)
+	requirelogin(cmd, gincl, !jsonout)
+	status, err := gincl.RequestDOI(repostr)
+	CheckError(err)
+
+	if jsonout {
+		j, _ := json.Marshal(status)
+		fmt.Println(string(j))
+		return
+	}
+	fmt.Fprintf(color.Output, ":: DOI request for '%s': %s\n", repostr, green(status.Status))
+	if status.Message != "" {
+		fmt.Printf("\t%s\n", status.Message)
+	}
+	if status.DOI != "" {
+		fmt.Printf("\tDOI: %s\n", status.DOI)
+	}
+	if status.URL != "" {
+		fmt.Printf("\tDetails: %s\n", status.URL)
+	}
+}
+
+// DOICmd sets up the 'doi' command and its subcommands
+func DOICmd() *cobra.Command {
+	description := "Request the registration of a DOI for a repository on the server. See 'gin datacite' for creating the required metadata file."
+	var cmd = &cobra.Command{
+		Use:                   "doi <command>",
+		Short:                 "Request DOIs for repositories",
+		Long:                  formatdesc(description, nil),
+		Args:                  cobra.NoArgs,
+		DisableFlagsInUseLine: true,
+	}
+
+	requestdesc := fmt.Sprintf("Ask the server to register a DOI for the current state of a repository and report the status of the request. The repository on the server must contain a valid %s file and you must have administrative access to it. If the current repository contains a %s file, it is checked before the request is sent.", ginclient.DataCiteFileName, ginclient.DataCiteFileName)
+	requestcmd := &cobra.Command{
+		Use:                   "request [--repo <repopath>] [--json]",
+		Short:                 "Request a DOI for a repository",
+		Long:                  formatdesc(requestdesc, nil),
+		Args:                  cobra.NoArgs,
+		Run:                   doiRequest,
+		DisableFlagsInUseLine: true,
+	}
+	requestcmd.Flags().Bool("json", false, jsonHelpMsg)
+	requestcmd.Flags().String("repo", "", "The `repository` path (owner/name) on the server. If not specified, the repository of the default remote of the current working directory is used.")
+	requestcmd.Flags().String("server", "", "Specify server `alias` where the repository resides. See also 'gin servers'.")
+
+	cmd.AddCommand(requestcmd)
+	return cmd
+}
@@ -0,0 +1,199 @@
+package gincmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	ginclient "github.com/G-Node/gin-cli/ginclient"
+	"github.com/G-Node/gin-cli/ginclient/log"
+	"github.com/G-Node/gin-cli/gincmd/ginerrors"
+	"github.com/G-Node/gin-cli/git"
+	"github.com/spf13/cobra"
+)
+
+// matchPattern returns true if the repository path matches the glob pattern.
+// Patterns without a '/' are matched against the file name and each directory name in the path; other patterns are matched against the leading components of the path.
+func matchPattern(pattern, fpath string) bool {
+	pattern = strings.TrimSuffix(pattern, "/")
+	parts := strings.Split(fpath, "/")
+	if !strings.Contains(pattern, "/") {
+		for _, part := range parts {
+			if match, _ := path.Match(pattern, part); match {
+				return true
+			}
+		}
+		return false
+	}
+	for idx := range parts {
+		if match, _ := path.Match(pattern, strings.Join(parts[:idx+1], "/")); match {
+			return true
+		}
+	}
+	return false
+}
+
+// pathFilter returns a function that accepts paths that match any of the include patterns (or all paths if there are none) and none of the exclude patterns.
+func pathFilter(include, exclude []string) func(string) bool {
+	return func(fpath string) bool {
+		for _, pattern := range exclude {
+			if matchPattern(pattern, fpath) {
+				return false
+			}
+		}
+		if len(include) == 0 {
+			return true
+		}
+		for _, pattern := range include {
+			if matchPattern(pattern, fpath) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// archiveFormatFromName returns the archive format that matches the extension of the file name, or an empty string if there is none.
+func archiveFormatFromName(fname string) string {
+	switch {
+	case strings.HasSuffix(fname, ".zip"):
+		return "zip"
+	case strings.HasSuffix(fname, ".tar.gz"), strings.HasSuffix(fname, ".tgz"):
+		return "tar.gz"
+	}
+	return ""
+}
+
+func export(cmd *cobra.Command, args []string) {
+	flags := cmd.Flags()
+	jsonout, _ := flags.GetBool("json")
+	rev, _ := flags.GetString("rev")
+	format, _ := flags.GetString("format")
+	outfile, _ := flags.GetString("output")
+	include, _ := flags.GetStringArray("include")
+	exclude, _ := flags.GetStringArray("exclude")
+
+	switch git.Checkwd() {
+	case git.NotRepository:
+		Die(ginerrors.NotInRepo)
+	case git.NotAnnex:
+		Warn(ginerrors.MissingAnnex)
+	case git.UpgradeRequired:
+		annexVersionNotice()
+	}
+
+	if format == "" {
+		format = archiveFormatFromName(outfile)
+		if format == "" {
+			format = "zip"
+		}
+	}
+	validformat := false
+	for _, f := range ginclient.ArchiveFormats {
+		validformat = validformat || f == format
+	}
+	if !validformat {
+		Die(fmt.Sprintf("invalid archive format '%s': must be one of %s", format, strings.Join(ginclient.ArchiveFormats, ", ")))
+	}
+
+	reporoot, err := git.FindRepoRoot(".")
+	CheckError(err)
+	// directory name inside the archive: <repository>-<revision>
+	prefix := fmt.Sprintf("%s-%s", filepath.Base(reporoot), strings.Replace(rev, "/", "-", -1))
+	if outfile == "" {
+		outfile = fmt.Sprintf("%s.%s", prefix, format)
+	}
+	outfile, _ = filepath.Abs(outfile)
+	if _, err := os.Stat(outfile); err == nil {
+		Die(fmt.Sprintf("output file '%s' already exists", outfile))
+	}
+	out, err := os.Create(outfile)
+	CheckErrorMsg(err, fmt.Sprintf("Failed to create output file '%s'", outfile))
+
+	if !jsonout {
+		fmt.Printf(":: Exporting version '%s' to %s\n", rev, outfile)
+	}
+	expchan := make(chan ginclient.FileCheckoutStatus)
+	go ginclient.ExportArchive(rev, args, pathFilter(include, exclude), format, prefix, out, expchan)
+	var nfiles, nerr int
+	for status := range expchan {
+		if jsonout {
+			errmsg := ""
+			if status.Err != nil {
+				errmsg = status.Err.Error()
+			}
+			j, _ := json.Marshal(struct {
+				FileName string `json:"filename"`
+				Type     string `json:"type"`
+				Archived string `json:"archivepath"`
+				Err      string `json:"err"`
+			}{status.Filename, status.Type, status.Destination, errmsg})
+			fmt.Println(string(j))
+		}
+		if status.Err != nil {
+			nerr++
+			if !jsonout {
+				if status.Filename == "" {
+					fmt.Printf(" Export failed: %s\n", status.Err.Error())
+				} else {
+					fmt.Printf(" Failed to export '%s': %s\n", status.Filename, status.Err.Error())
+				}
+			}
+			continue
+		}
+		if status.Type != "Manifest" {
+			nfiles++
+		}
+	}
+	cerr := out.Close()
+	if cerr != nil || nerr > 0 {
+		// do not leave an incomplete archive behind
+		if rerr := os.Remove(outfile); rerr != nil {
+			log.Write("Failed to remove incomplete archive: %s", rerr)
+		}
+	}
+	CheckErrorMsg(cerr, fmt.Sprintf("Failed to write output file '%s'", outfile))
+	if nerr > 0 {
+		plural := ""
+		if nerr > 1 {
+			plural = "s"
+		}
+		Die(fmt.Sprintf("%d operation%s failed: the archive was not created", nerr, plural))
+	}
+
+	if !jsonout {
+		fmt.Printf(":: %d files exported\n", nfiles)
+	}
+}
+
+// ExportCmd sets up the 'export' subcommand
+func ExportCmd() *cobra.Command {
+	description := fmt.Sprintf("Export a version of the repository, including the content of annexed files, to a single archive file that can be shared with people who do not have access to the repository. Annexed content that is not available locally is downloaded as needed. A checksum file (%s) listing the SHA256 checksum of every exported file is added to the archive. If any file cannot be exported, no archive is created.\n\nPatterns given to --include and --exclude are matched against file and directory names, or, if they contain a '/', against paths relative to the repository root. Excludes take precedence over includes.", ginclient.ManifestFileName)
+	args := map[string]string{
+		"<filenames>": "One or more directories or files to export. If none are specified, the whole repository is exported.",
+	}
+	examples := map[string]string{
+		"Export the version tagged 'submitted' to a zip file":             "$ gin export --rev submitted -o dataset.zip",
+		"Export only the data/ directory, without the raw recordings":     "$ gin export --exclude raw -o data.tar.gz data",
+		"Export only NIX files from the current version as a tar.gz file": "$ gin export --format tar.gz --include '*.nix'",
+	}
+	var cmd = &cobra.Command{
+		Use:                   "export [--rev <version>] [--format zip | tar.gz] [-o <file>] [--include <pattern>]... [--exclude <pattern>]... [--json] [<filenames>]...",
+		Short:                 "Export a version of the repository to an archive file",
+		Long:                  formatdesc(description, args),
+		Example:               formatexamples(examples),
+		Args:                  cobra.ArbitraryArgs,
+		Run:                   export,
+		DisableFlagsInUseLine: true,
+	}
+	cmd.Flags().String("rev", "HEAD", "The `version` (ID, tag, or branch) to export.")
+	cmd.Flags().String("format", "", "Archive `format`: 'zip' or 'tar.gz'. If not specified, it is determined from the output file name, defaulting to zip.")
+	cmd.Flags().StringP("output", "o", "", "Name of the archive `file` to create. Defaults to <repository>-<version>.<format> in the current directory.")
+	cmd.Flags().StringArray("include", nil, "Only export files matching the `pattern`. Can be specified multiple times.")
+	cmd.Flags().StringArray("exclude", nil, "Do not export files matching the `pattern`. Can be specified multiple times.")
+	cmd.Flags().Bool("json", false, jsonHelpMsg)
+	return cmd
+}
@@ -0,0 +1,38 @@
+package gincmd
+
+import "testing"
+
+func TestPathFilter(t *testing.T) {
+	filter := pathFilter([]string{"*.nix", "data/"}, []string{"raw", "data/tmp*"})
+	cases := map[string]bool{
+		"recording.nix":         true,
+		"sub/recording.nix":     true,
+		"data/values.csv":       true,
+		"data/tmp.csv":          false,
+		"raw/recording.nix":     false,
+		"sub/raw/recording.nix": false,
+		"notes.txt":             false,
+	}
+	for fpath, expected := range cases {
+		if filter(fpath) != expected {
+			t.Errorf("pathFilter(%q) = %t, expected %t", fpath, !expected, expected)
+		}
+	}
+	if !pathFilter(nil, nil)("any/file") {
+		t.Error("empty filter rejected a file")
+	}
+}
+
+func TestArchiveFormatFromName(t *testing.T) {
+	cases := map[string]string{
+		"dataset.zip":    "zip",
+		"dataset.tar.gz": "tar.gz",
+		"dataset.tgz":    "tar.gz",
+		"dataset":        "",
+	}
+	for fname, expected := range cases {
+		if format := archiveFormatFromName(fname); format != expected {
+			t.Errorf("archiveFormatFromName(%q) = %q, expected %q", fname, format, expected)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package gincmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	ginclient "github.com/G-Node/gin-cli/ginclient"
+	"github.com/G-Node/gin-cli/gincmd/ginerrors"
+	"github.com/G-Node/gin-cli/git"
+	"github.com/spf13/cobra"
+)
+
+func find(cmd *cobra.Command, args []string) {
+	flags := cmd.Flags()
+	jsonout, _ := flags.GetBool("json")
+	nullsep, _ := flags.GetBool("null")
+	if jsonout && nullsep {
+		usageDie(cmd)
+	}
+	var opts ginclient.FindOptions
+	opts.Metadata, _ = flags.GetStringArray("meta")
+	opts.Larger, _ = flags.GetString("larger")
+	opts.Smaller, _ = flags.GetString("smaller")
+	opts.In, _ = flags.GetStringArray("in")
+	opts.NotIn, _ = flags.GetStringArray("not-in")
+	opts.Status, _ = flags.GetStringSlice("status")
+	opts.ChangedSince, _ = flags.GetString("changed-since")
+	opts.Globs, _ = flags.GetStringArray("glob")
+	opts.Annexed, _ = flags.GetBool("annexed")
+	opts.Git, _ = flags.GetBool("git")
+
+	validstatuses := []string{"OK", "TC", "NC", "MD", "LC"}
+	for idx, status := range opts.Status {
+		status = strings.ToUpper(status)
+		valid := false
+		for _, vs := range validstatuses {
+			valid = valid || vs == status
+		}
+		if !valid {
+			Die(fmt.Sprintf("invalid status '%s': must be one of %s", status, strings.Join(validstatuses, ", ")))
+		}
+		opts.Status[idx] = status
+	}
+
+	switch git.Checkwd() {
+	case git.NotRepository:
+		Die(ginerrors.NotInRepo)
+	case git.NotAnnex:
+		Warn(ginerrors.MissingAnnex)
+	case git.UpgradeRequired:
+		annexVersionNotice()
+	}
+
+	files, err := ginclient.FindFiles(args, opts)
+	CheckError(err)
+	switch {
+	case jsonout:
+		if files == nil {
+			files = []ginclient.FoundFile{}
+		}
+		j, _ := json.Marshal(files)
+		fmt.Println(string(j))
+	case nullsep:
+		for _, f := range files {
+			fmt.Printf("%s\000", f.Name)
+		}
+	default:
+		for _, f := range files {
+			fmt.Println(f.Name)
+		}
+	}
+}
+
+// FindCmd sets up the 'find' subcommand
+func FindCmd() *cobra.Command {
+	description := "Find files that match all of the given criteria and print their paths, one per line. Files are matched whether their content is available locally or not. The output can be passed to other commands, such as get-content, remove-content, or upload, e.g., using xargs. Use -0 to separate paths with null characters (for 'xargs -0') or --json for detailed output.\n\nMetadata criteria (--meta) match files whose metadata field has a value that matches the glob pattern (see 'gin meta'). Metadata and remote criteria (--in, --not-in) only match annexed files. Sizes can be specified with decimal or binary units (e.g., 500k, 100MB, 1GiB). Status criteria use the abbreviations of 'gin ls --short' (OK, TC, NC, MD, LC) and can be combined (e.g., --status NC,LC). Glob patterns are matched against the file name and the path. Dates for --changed-since can be given in any format understood by git (e.g., 2019-06-01 or '2 weeks ago'); only recorded changes are considered."
+	args := map[string]string{
+		"<filenames>": "One or more directories or files to search. If none are specified, all files under the current directory are searched.",
+	}
+	examples := map[string]string{
+		"Find all files of subject s01 larger than 1 GB":             "$ gin find --meta subject=s01 --larger 1G",
+		"Download the content of all NIX files that are not present": "$ gin find --status NC --glob '*.nix' -0 | xargs -0 gin get-content",
+		"List annexed files that have not been uploaded to origin":   "$ gin find --not-in origin",
+		"Show files changed in the last week":                        "$ gin find --changed-since '1 week ago'",
+	}
+	var cmd = &cobra.Command{
+		Use:                   "find [--meta <field=glob>]... [--larger <size>] [--smaller <size>] [--in <remote>]... [--not-in <remote>]... [--status <status>,...] [--changed-since <date>] [--glob <pattern>]... [--annexed | --git] [-0 | --json] [<filenames>]...",
+		Short:                 "Find files by metadata, size, status, location, and time",
+		Long:                  formatdesc(description, args),
+		Example:               formatexamples(examples),
+		Args:                  cobra.ArbitraryArgs,
+		Run:                   find,
+		DisableFlagsInUseLine: true,
+	}
+	cmd.Flags().StringArray("meta", nil, "Only match files whose metadata matches the `field=glob` expression. Can be specified multiple times.")
+	cmd.Flags().String("larger", "", "Only match files larger than `size`.")
+	cmd.Flags().String("smaller", "", "Only match files smaller than `size`.")
+	cmd.Flags().StringArray("in", nil, "Only match files whose content is stored in the `remote`. Can be specified multiple times.")
+	cmd.Flags().StringArray("not-in", nil, "Only match files whose content is not stored in the `remote`. Can be specified multiple times.")
+	cmd.Flags().StringSlice("status", nil, "Only match files with the given `status` (e.g., NC, LC, MD). Multiple statuses can be separated by commas.")
+	cmd.Flags().String("changed-since", "", "Only match files changed since `date`.")
+	cmd.Flags().StringArray("glob", nil, "Only match files whose name or path matches the `pattern`. Can be specified multiple times.")
+	cmd.Flags().Bool("annexed", false, "Only match annexed files.")
+	cmd.Flags().Bool("git", false, "Only match files stored in git.")
+	cmd.Flags().BoolP("null", "0", false, "Separate paths with null characters instead of newlines.")
+	cmd.Flags().Bool("json", false, jsonHelpMsg)
+	return cmd
+}
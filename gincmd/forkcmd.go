@@ -0,0 +1,94 @@
+package gincmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	ginclient "github.com/G-Node/gin-cli/ginclient"
+	"github.com/G-Node/gin-cli/ginclient/config"
+	"github.com/G-Node/gin-cli/git"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+func fork(cmd *cobra.Command, args []string) {
+	flags := cmd.Flags()
+	jsonout, _ := flags.GetBool("json")
+	clone, _ := flags.GetBool("clone")
+	noclone, _ := flags.GetBool("no-clone")
+	org, _ := flags.GetString("org")
+	srvalias, _ := flags.GetString("server")
+
+	if clone && noclone {
+		usageDie(cmd)
+	}
+
+	conf := config.Read()
+	if srvalias == "" {
+		srvalias = conf.DefaultServer
+	}
+	repostr := args[0]
+	if !isValidRepoPath(repostr) {
+		Die(fmt.Sprintf("Invalid repository path '%s'. Full repository name should be the owner's username followed by the repository name, separated by a '/'.", repostr))
+	}
+
+	gincl := ginclient.New(srvalias)
+	requirelogin(cmd, gincl, !jsonout)
+
+	if !jsonout {
+		fmt.Printf(":: Forking repository '%s' ", repostr)
+	}
+	forkinfo, err := gincl.ForkRepo(repostr, org)
+	CheckError(err)
+
+	if jsonout {
+		j, _ := json.Marshal(forkinfo)
+		fmt.Println(string(j))
+	} else {
+		fmt.Fprintln(color.Output, green("OK"))
+		fmt.Printf(":: Created fork '%s'\n", forkinfo.FullName)
+	}
+
+	if !clone && !noclone && !jsonout {
+		clone = confirm(fmt.Sprintf("Would you like to get (clone) '%s' now?", forkinfo.FullName))
+	}
+	if !clone {
+		return
+	}
+
+	getRepo(cmd, []string{forkinfo.FullName})
+	// getRepo leaves us in the new clone: add the original repository as 'upstream'
+	rmt := parseRemote(fmt.Sprintf("%s:%s", srvalias, repostr))
+	err = git.RemoteAdd("upstream", rmt.url)
+	CheckError(err)
+	if !jsonout {
+		fmt.Printf(":: Added new remote: upstream [%s]\n", rmt.url)
+	}
+}
+
+// ForkCmd sets up the 'fork' repository subcommand
+func ForkCmd() *cobra.Command {
+	description := "Create a copy (fork) of a repository on the server, owned by the logged in user or one of their organisations. After forking, the client offers to get (clone) the new repository. When cloning, the original repository is added to the local clone as a remote named 'upstream'."
+	args := map[string]string{
+		"<repopath>": "The path of the repository to fork. A repository path is the owner's username, followed by a \"/\" and the repository name.",
+	}
+	examples := map[string]string{
+		"Fork the repository 'lab/template' and clone it":                 "$ gin fork --clone lab/template",
+		"Fork the repository 'alice/example' into the organisation 'lab'": "$ gin fork --org lab alice/example",
+	}
+	var cmd = &cobra.Command{
+		Use:                   "fork [--org <organisation>] [--clone | --no-clone] [--json] <repopath>",
+		Short:                 "Fork a repository on the server",
+		Long:                  formatdesc(description, args),
+		Example:               formatexamples(examples),
+		Args:                  cobra.ExactArgs(1),
+		Run:                   fork,
+		DisableFlagsInUseLine: true,
+	}
+	cmd.Flags().String("org", "", "Create the fork under the given `organisation` instead of the logged in user.")
+	cmd.Flags().Bool("clone", false, "Clone the new repository without asking. Cannot be used with --no-clone.")
+	cmd.Flags().Bool("no-clone", false, "Do not clone the new repository and do not ask. Cannot be used with --clone.")
+	cmd.Flags().Bool("json", false, jsonHelpMsg)
+	cmd.Flags().String("server", "", "Specify server `alias` where the repository resides. See also 'gin servers'.")
+	return cmd
+}
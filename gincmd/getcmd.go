@@ -17,6 +17,7 @@ func isValidRepoPath(path string) bool {
 func getRepo(cmd *cobra.Command, args []string) {
 	prStyle := determinePrintStyle(cmd)
 	srvalias, _ := cmd.Flags().GetString("server")
+	tagname, _ := cmd.Flags().GetString("tag")
 	conf := config.Read()
 	if srvalias == "" {
 		srvalias = conf.DefaultServer
@@ -32,6 +33,15 @@ func getRepo(cmd *cobra.Command, args []string) {
 	clonechan := make(chan git.RepoFileStatus)
 	go gincl.CloneRepo(repostr, clonechan)
 	formatOutput(clonechan, prStyle, 0)
+	if tagname != "" {
+		// CloneRepo leaves us in the new clone
+		err := git.CheckoutRef(tagname)
+		CheckError(err)
+		if prStyle != psJSON {
+			fmt.Printf(":: Checked out version '%s'\n", tagname)
+		}
+		Warn(fmt.Sprintf("version '%s' is checked out without a branch: changes cannot be uploaded until a branch is checked out (e.g., 'git checkout master')", tagname))
+	}
 	defaultRemoteIfUnset("origin")
 	new, err := ginclient.CommitIfNew()
 	if new {
@@ -54,10 +64,11 @@ func GetCmd() *cobra.Command {
 	examples := map[string]string{
 		"Get and initialise the repository named 'example' owned by user 'alice'": "$ gin get alice/example",
 		"Get and initialise the repository named 'eegdata' owned by user 'peter'": "$ gin get peter/eegdata",
+		"Get the version of 'alice/example' tagged 'submitted'":                   "$ gin get --tag submitted alice/example",
 	}
 	var cmd = &cobra.Command{
 		// Use:                   "get [--json | --verbose] <repopath>",
-		Use:                   "get [--json] <repopath>",
+		Use:                   "get [--json] [--tag <name>] <repopath>",
 		Short:                 "Retrieve (clone) a repository from the remote server",
 		Long:                  formatdesc(description, args),
 		Example:               formatexamples(examples),
@@ -66,6 +77,7 @@ func GetCmd() *cobra.Command {
 		DisableFlagsInUseLine: true,
 	}
 	cmd.Flags().Bool("json", false, jsonHelpMsg)
+	cmd.Flags().String("tag", "", "Check out the version with the given tag `name` (or version ID) after cloning instead of the latest version. The version is checked out without a branch, so changes cannot be uploaded.")
 	// cmd.Flags().Bool("verbose", false, verboseHelpMsg)
 	cmd.Flags().String("server", "", "Specify server `alias` for the repository. See also 'gin servers'.")
 	return cmd
@@ -0,0 +1,149 @@
+package gincmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	ginclient "github.com/G-Node/gin-cli/ginclient"
+	"github.com/G-Node/gin-cli/gincmd/ginerrors"
+	"github.com/G-Node/gin-cli/git"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+func importFiles(cmd *cobra.Command, args []string) {
+	flags := cmd.Flags()
+	jsonout, _ := flags.GetBool("json")
+	urllist, _ := flags.GetString("urls")
+	dest, _ := flags.GetString("dest")
+	batchsize, _ := flags.GetInt("batch-size")
+	restart, _ := flags.GetBool("restart")
+
+	if urllist == "" && len(args) != 1 {
+		usageDie(cmd)
+	}
+	if urllist != "" && len(args) != 0 {
+		usageDie(cmd)
+	}
+	if batchsize < 1 {
+		usageDie(cmd)
+	}
+
+	// only the mode flags of the chosen import type are valid
+	modeflags := []string{"copy", "move", "hardlink"}
+	otherflags := []string{"fast", "relaxed", "download"}
+	if urllist != "" {
+		modeflags, otherflags = otherflags, modeflags
+	}
+	for _, m := range otherflags {
+		if set, _ := flags.GetBool(m); set {
+			usageDie(cmd)
+		}
+	}
+	var mode string
+	for _, m := range modeflags {
+		if set, _ := flags.GetBool(m); set {
+			if mode != "" {
+				usageDie(cmd)
+			}
+			mode = m
+		}
+	}
+
+	switch git.Checkwd() {
+	case git.NotRepository:
+		Die(ginerrors.NotInRepo)
+	case git.NotAnnex:
+		Warn(ginerrors.MissingAnnex)
+	case git.UpgradeRequired:
+		annexVersionNotice()
+	}
+
+	opts := ginclient.ImportOptions{Dest: dest, Mode: mode, BatchSize: batchsize, Restart: restart}
+	impchan := make(chan ginclient.ImportStatus)
+	var source string
+	if urllist != "" {
+		source = urllist
+		go ginclient.ImportURLs(urllist, opts, impchan)
+	} else {
+		source = args[0]
+		go ginclient.ImportDirectory(source, opts, impchan)
+	}
+
+	for status := range impchan {
+		if jsonout {
+			errmsg := ""
+			if status.Err != nil {
+				errmsg = status.Err.Error()
+			}
+			j, _ := json.Marshal(struct {
+				ginclient.ImportStatus
+				Err string `json:"err"`
+			}{status, errmsg})
+			fmt.Println(string(j))
+			if status.Err != nil {
+				Die("")
+			}
+			continue
+		}
+		if status.Err != nil {
+			if status.Batch == 0 {
+				Die(status.Err)
+			}
+			fmt.Fprintln(color.Output, red("FAILED"))
+			Die(fmt.Sprintf("%s\nThe import can be resumed by running the same command again.", status.Err.Error()))
+		}
+		if status.Batch == 0 {
+			if status.Imported > 0 {
+				fmt.Printf(":: Resuming import of %s: %d of %d files already imported\n", source, status.Imported, status.Total)
+			} else {
+				fmt.Printf(":: Importing %d files from %s\n", status.Total, source)
+			}
+			if status.NBatches > 0 {
+				fmt.Printf("   Batch 1/%d ... ", status.NBatches)
+			}
+			continue
+		}
+		fmt.Fprintf(color.Output, "%s (%d files, %d/%d imported)\n", green("OK"), status.Files, status.Imported, status.Total)
+		if status.Batch < status.NBatches {
+			fmt.Printf("   Batch %d/%d ... ", status.Batch+1, status.NBatches)
+		}
+	}
+	if !jsonout {
+		fmt.Println(":: Import complete")
+	}
+}
+
+// ImportCmd sets up the 'import' subcommand
+func ImportCmd() *cobra.Command {
+	description := fmt.Sprintf("Import a large directory tree or a list of web-hosted files into the current repository. Files are added and committed in batches, so that progress is reported per batch and an interrupted import can be resumed by running the same command again. Files that already exist in the repository are never overwritten. Since each batch is committed, the import does not start while other changes are staged.\n\nA directory is imported by copying its files into the repository (default), by moving them, or by creating hard links to them. Hard links do not need additional disk space, but the source directory must be on the same file system as the repository and the linked files must not be modified afterwards.\n\nWith --urls, files are registered in the repository by their web address instead. Each line of the list must contain a URL and, optionally separated by a comma, the path of the file to create. If the path is omitted, the file name is taken from the URL. By default, the content is not downloaded; only the existence of each URL is checked (--fast). With --relaxed, the URLs are not checked either. With --download, the content of each file is downloaded. Content that is not downloaded can be retrieved later with 'gin get-content'.\n\nThe batch size is %d files unless specified.", ginclient.DefaultImportBatchSize)
+	args := map[string]string{
+		"<directory>": "The directory to import. It must not be inside the repository.",
+	}
+	examples := map[string]string{
+		"Copy a directory of recordings into the 'raw' directory of the repository": "$ gin import --dest raw /data/legacy/recordings",
+		"Move files into the repository, committing every 5000 files":               "$ gin import --move --batch-size 5000 /data/legacy/recordings",
+		"Register files hosted on a web server without downloading them":            "$ gin import --urls files.csv --dest external",
+	}
+	var cmd = &cobra.Command{
+		Use:                   "import [--dest <directory>] [--copy | --move | --hardlink | --urls <file> [--fast | --relaxed | --download]] [--batch-size <n>] [--restart] [--json] [<directory>]",
+		Short:                 "Import a directory or a list of URLs into the repository in batches",
+		Long:                  formatdesc(description, args),
+		Example:               formatexamples(examples),
+		Args:                  cobra.MaximumNArgs(1),
+		Run:                   importFiles,
+		DisableFlagsInUseLine: true,
+	}
+	cmd.Flags().String("dest", ".", "The `directory` in the repository where imported files are placed.")
+	cmd.Flags().Bool("copy", false, "Copy files into the repository (default).")
+	cmd.Flags().Bool("move", false, "Move files into the repository.")
+	cmd.Flags().Bool("hardlink", false, "Create hard links to the files in the repository.")
+	cmd.Flags().String("urls", "", "Import the URLs listed in `file` (CSV: url[,path]).")
+	cmd.Flags().Bool("fast", false, "Check that each URL exists without downloading the content (default for --urls).")
+	cmd.Flags().Bool("relaxed", false, "Do not check the URLs or download the content.")
+	cmd.Flags().Bool("download", false, "Download the content of each URL.")
+	cmd.Flags().Int("batch-size", ginclient.DefaultImportBatchSize, "Number of files to add and commit at a time.")
+	cmd.Flags().Bool("restart", false, "Discard the progress of an unfinished import and start over.")
+	cmd.Flags().Bool("json", false, jsonHelpMsg)
+	return cmd
+}
@@ -10,6 +10,7 @@ import (
 
 	ginclient "github.com/G-Node/gin-cli/ginclient"
 	"github.com/G-Node/gin-cli/ginclient/config"
+	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 )
 
@@ -100,11 +101,78 @@ func delKey(gincl *ginclient.Client, idx int) {
 	fmt.Printf("Deleted key with name '%s'\n", name)
 }
 
+func rotateKey(cmd *cobra.Command, args []string) {
+	srvalias, _ := cmd.Flags().GetString("server")
+	if srvalias == "" {
+		srvalias = config.Read().DefaultServer
+	}
+	gincl := ginclient.New(srvalias)
+	requirelogin(cmd, gincl, true)
+	fmt.Printf(":: Rotating session key for %s\n", srvalias)
+	title, err := gincl.RotateSessionKey()
+	if title != "" {
+		fmt.Fprintf(color.Output, ":: New key '%s' %s\n", title, green("OK"))
+	}
+	CheckError(err)
+	fmt.Println(":: Old key deleted from the server")
+}
+
+func loadKey(cmd *cobra.Command, args []string) {
+	flags := cmd.Flags()
+	srvalias, _ := flags.GetString("server")
+	lifetime, _ := flags.GetDuration("lifetime")
+	if lifetime < 0 {
+		usageDie(cmd)
+	}
+	if srvalias == "" {
+		srvalias = config.Read().DefaultServer
+	}
+	gincl := ginclient.New(srvalias)
+	requirelogin(cmd, gincl, true)
+	err := gincl.LoadSessionKey(uint32(lifetime.Seconds()))
+	CheckError(err)
+	fmt.Printf(":: Session key for %s added to the SSH agent\n", srvalias)
+}
+
+func keysRotateCmd() *cobra.Command {
+	description := "Replace the session key that was created on login with a new one. The new key is created with the configured type and size (see 'gin login') and added to the server. Access to the git server is verified with the new key, by listing one of your repositories, and only then is the old key deleted from the server. If the verification fails, the old key remains in use."
+	var cmd = &cobra.Command{
+		Use:                   "rotate [--server <alias>]",
+		Short:                 "Replace the session key with a new one",
+		Long:                  formatdesc(description, nil),
+		Args:                  cobra.NoArgs,
+		Run:                   rotateKey,
+		DisableFlagsInUseLine: true,
+	}
+	cmd.Flags().String("server", "", "Specify server `alias` for the session key. See also 'gin servers'.")
+	return cmd
+}
+
+func keysLoadCmd() *cobra.Command {
+	description := fmt.Sprintf("Decrypt the session key and add it to the running SSH agent, so that git commands do not ask for the key passphrase. The passphrase is read from the %s environment variable or prompted for.\n\nSession keys are only encrypted when the keys.encrypt configuration key is enabled.", ginclient.KeyPassphraseEnvVar)
+	examples := map[string]string{
+		"Add the session key to the agent for 8 hours": "$ gin keys load --lifetime 8h",
+	}
+	var cmd = &cobra.Command{
+		Use:                   "load [--server <alias>] [--lifetime <duration>]",
+		Short:                 "Add the session key to the SSH agent",
+		Long:                  formatdesc(description, nil),
+		Example:               formatexamples(examples),
+		Args:                  cobra.NoArgs,
+		Run:                   loadKey,
+		DisableFlagsInUseLine: true,
+	}
+	cmd.Flags().String("server", "", "Specify server `alias` for the session key. See also 'gin servers'.")
+	cmd.Flags().Duration("lifetime", 0, "Remove the key from the agent after the given `duration` (e.g., 30m, 8h). By default the key is kept until the agent exits.")
+	return cmd
+}
+
 // KeysCmd sets up the 'keys' list, add, delete subcommand(s)
 func KeysCmd() *cobra.Command {
-	description := "List, add, or delete SSH keys. If no argument is provided, a numbered list of key names is printed. The key number can be used with the '--delete' flag to remove a key from the server.\n\nThe command can also be used to add a public key to your account from an existing filename (see '--add' flag)."
+	description := "List, add, or delete SSH keys. If no argument is provided, a numbered list of key names is printed. The key number can be used with the '--delete' flag to remove a key from the server.\n\nThe command can also be used to add a public key to your account from an existing filename (see '--add' flag).\n\nThe session key that is created on login can be replaced with 'gin keys rotate' and added to the SSH agent with 'gin keys load'."
 	examples := map[string]string{
 		"Add a public key to your account, as generated from the default ssh-keygen command": "$ gin keys --add ~/.ssh/id_rsa.pub",
+		"Replace the session key of this machine":                                            "$ gin keys rotate",
 	}
 	var cmd = &cobra.Command{
 		Use:                   "keys [--add <filename> | --delete <keynum> | --verbose | -v]",
@@ -120,5 +188,7 @@ func KeysCmd() *cobra.Command {
 	cmd.Flags().BoolP("verbose", "v", false, "Verbose printing. Prints the entire public key.")
 	cmd.Flags().String("server", "", "Specify server `alias` to query, add, or remove keys. See also 'gin servers'.")
 	cmd.Flags().Bool("json", false, jsonHelpMsg)
+	cmd.AddCommand(keysRotateCmd())
+	cmd.AddCommand(keysLoadCmd())
 	return cmd
 }
@@ -2,38 +2,32 @@ package gincmd
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
 
 	ginclient "github.com/G-Node/gin-cli/ginclient"
 	"github.com/G-Node/gin-cli/ginclient/config"
+	"github.com/G-Node/gin-cli/git"
 	"github.com/howeyc/gopass"
 	"github.com/spf13/cobra"
 )
 
-// login requests credentials, performs login with auth server, and stores the token.
-func login(cmd *cobra.Command, args []string) {
-	var username, password string
-
-	flags := cmd.Flags()
-	srvalias, _ := flags.GetString("server")
-
-	conf := config.Read()
-	if srvalias == "" {
-		srvalias = conf.DefaultServer
-	}
-	fmt.Printf("Logging into %s\n", srvalias)
-
+// promptCredentials prompts for the username, unless it is given as argument, and the password.
+// The prompts are printed to stderr, so that they do not mix with the output of the command.
+func promptCredentials(args []string) (string, string) {
+	var username string
 	if len(args) == 0 {
 		// prompt for login
-		fmt.Print("Login: ")
+		fmt.Fprint(os.Stderr, "Login: ")
 		fmt.Scanln(&username)
 	} else {
 		username = args[0]
 	}
 
 	// prompt for password
-	fmt.Print("Password: ")
-	pwbytes, err := gopass.GetPasswdMasked()
-	fmt.Println()
+	pwbytes, err := gopass.GetPasswdPrompt("Password: ", true, os.Stdin, os.Stderr)
+	fmt.Fprintln(os.Stderr)
 	if err != nil {
 		// read error or gopass.ErrInterrupted
 		if err == gopass.ErrInterrupted {
@@ -45,14 +39,64 @@ func login(cmd *cobra.Command, args []string) {
 		Die(err)
 	}
 
-	password = string(pwbytes)
+	password := string(pwbytes)
 	if password == "" {
 		Die("No password provided. Aborting.")
 	}
+	return username, password
+}
+
+// login requests credentials, performs login with auth server, and stores the token.
+func login(cmd *cobra.Command, args []string) {
+	var username, password string
+	var err error
+
+	flags := cmd.Flags()
+	srvalias, _ := flags.GetString("server")
+	nosessionkey, _ := flags.GetBool("no-session-key")
+	tokenstdin, _ := flags.GetBool("token-stdin")
+	if tokenstdin && len(args) > 0 {
+		usageDie(cmd)
+	}
 
+	conf := config.Read()
+	if srvalias == "" {
+		srvalias = conf.DefaultServer
+	}
 	gincl := ginclient.New(srvalias)
-	err = gincl.Login(username, password, "gin-cli")
-	CheckError(err)
+	// servers with the HTTPS transport do not use SSH keys
+	httpsTransport := conf.Servers[srvalias].HTTPSTransport()
+	if nosessionkey && !httpsTransport && !gincl.UserKeysConfigured() {
+		Die(fmt.Sprintf("--no-session-key requires your own keys for server '%[1]s': set servers.%[1]s.git.useagent or servers.%[1]s.git.identityfiles with 'gin config set'", srvalias))
+	}
+	profile, _ := config.ActiveProfile()
+	if profile == config.DefaultProfile {
+		fmt.Printf("Logging into %s\n", srvalias)
+	} else {
+		fmt.Printf("Logging into %s (profile %s)\n", srvalias, profile)
+	}
+
+	if tokenstdin {
+		tokenbytes, err := ioutil.ReadAll(os.Stdin)
+		CheckErrorMsg(err, "Failed to read token from standard input")
+		if strings.TrimSpace(string(tokenbytes)) == "" {
+			Die("No token provided. Aborting.")
+		}
+		err = gincl.LoginWithToken(string(tokenbytes), !nosessionkey)
+		if err == ginclient.ErrTokenInvalid {
+			Die("The token was rejected by the server: it may have expired or been revoked.")
+		}
+		CheckError(err)
+		username = gincl.Username
+	} else {
+		username, password = promptCredentials(args)
+		if nosessionkey {
+			err = gincl.LoginToken(username, password, "gin-cli")
+		} else {
+			err = gincl.Login(username, password, "gin-cli")
+		}
+		CheckError(err)
+	}
 	info, err := gincl.RequestAccount(username)
 	CheckError(err)
 	name := info.FullName
@@ -61,13 +105,24 @@ func login(cmd *cobra.Command, args []string) {
 	}
 	fmt.Printf(":: Welcome %s\n", name)
 	fmt.Printf(":: Successfully logged into %s [%s]\n", srvalias, gincl.WebAddress())
+	if nosessionkey && !httpsTransport {
+		key, err := gincl.FindRegisteredKey()
+		if err != nil {
+			Warn(fmt.Sprintf("%s\nGit commands will fail until one of your keys is added to your account (see 'gin keys --add').", err))
+		} else {
+			fmt.Printf(":: Using your key '%s' for git commands\n", key.Title)
+		}
+	}
+	if gincl.SessionKeyEncrypted() && !git.AgentAvailable() {
+		fmt.Println(":: The session key is encrypted and no SSH agent is running: git commands will ask for the key passphrase. Start an agent and run 'gin keys load' to enter it only once.")
+	}
 }
 
 // LoginCmd sets up the 'login' subcommand
 func LoginCmd() *cobra.Command {
-	description := "Login to the GIN services.\n\nIf no username is specified on the command line, you will be prompted for it. The login command always prompts for a password."
+	description := "Login to the GIN services.\n\nIf no username is specified on the command line, you will be prompted for it. The login command always prompts for a password, unless an existing access token is read from standard input with --token-stdin. Logging in with a token does not create a new token on the server, which makes it suitable for automated jobs.\n\nCommands can also use a token from the GIN_TOKEN environment variable for the default server without logging in. The token is not stored. Since no session key is created in this case, git commands require the HTTPS transport (servers.<alias>.git.transport) or your own keys (see below).\n\nTo log into the same server with a second account, log in with a named profile using the global --profile flag (e.g., 'gin login --profile bot'). See 'gin use-profile' for how profiles are selected.\n\nOn login, a new session key is created for accessing the git server. Its type and size are set with the keys.type (ed25519 or rsa) and keys.bits configuration keys. If keys.encrypt is enabled, the private key is encrypted with a passphrase, which is read from the GIN_KEY_PASSPHRASE environment variable or prompted for, and the key is added to the running SSH agent (see 'gin keys load').\n\nTo use your own keys instead (e.g., keys held by the SSH agent or a hardware token), configure them for the server with the servers.<alias>.git.useagent and servers.<alias>.git.identityfiles configuration keys and log in with --no-session-key. These keys are only offered to the git host of that server. No session key is created, and the login checks that one of your keys is registered with your account."
 	var cmd = &cobra.Command{
-		Use:                   "login [<username>]",
+		Use:                   "login [--no-session-key] [<username> | --token-stdin]",
 		Short:                 "Login to the GIN services",
 		Long:                  formatdesc(description, nil),
 		Args:                  cobra.MaximumNArgs(1),
@@ -75,5 +130,7 @@ func LoginCmd() *cobra.Command {
 		DisableFlagsInUseLine: true,
 	}
 	cmd.Flags().String("server", "", "Specify server `alias` to log into. See also 'gin servers'.")
+	cmd.Flags().Bool("token-stdin", false, "Read an access token from standard input instead of prompting for the username and password.")
+	cmd.Flags().Bool("no-session-key", false, "Do not create a session key. Your own keys, configured for the server, are used for git commands.")
 	return cmd
 }
@@ -26,6 +26,10 @@ func logout(cmd *cobra.Command, args []string) {
 	}
 
 	gincl.Logout()
+	if profile, _ := config.ActiveProfile(); profile != config.DefaultProfile {
+		fmt.Printf(":: You have been logged out (profile %s).\n", profile)
+		return
+	}
 	fmt.Println(":: You have been logged out.")
 }
 
@@ -32,7 +32,7 @@ func lsRepo(cmd *cobra.Command, args []string) {
 	// TODO: Use repo remotes; no server configuration
 	gincl := ginclient.New("gin")
 
-	filesStatus, err := gincl.ListFiles(args...)
+	filesStatus, weburls, err := gincl.ListFilesWithURLs(args...)
 	CheckError(err)
 
 	// TODO: Print warning when in direct mode: git files that have not been uploaded will show up as synced.
@@ -43,12 +43,13 @@ func lsRepo(cmd *cobra.Command, args []string) {
 		}
 	} else if jsonout {
 		type fstat struct {
-			FileName string `json:"filename"`
-			Status   string `json:"status"`
+			FileName string   `json:"filename"`
+			Status   string   `json:"status"`
+			URLs     []string `json:"urls,omitempty"`
 		}
 		var statuses []fstat
 		for fname, status := range filesStatus {
-			statuses = append(statuses, fstat{FileName: fname, Status: status.Abbrev()})
+			statuses = append(statuses, fstat{FileName: fname, Status: status.Abbrev(), URLs: weburls[fname]})
 		}
 		jsonbytes, err := json.Marshal(statuses)
 		CheckError(err)
@@ -60,11 +61,13 @@ func lsRepo(cmd *cobra.Command, args []string) {
 		for file, status := range filesStatus {
 			statFiles[status] = append(statFiles[status], file)
 		}
-		printFileStatusList(statFiles)
+		printFileStatusList(statFiles, weburls)
 	}
 }
 
-func printFileStatusList(statFiles map[ginclient.FileStatus][]string) {
+// printFileStatusList prints the files grouped by status.
+// Files that have an entry in weburls are marked as available from the web.
+func printFileStatusList(statFiles map[ginclient.FileStatus][]string, weburls map[string][]string) {
 	// sort files in each status (stable sorting unnecessary)
 	// also collect active statuses for sorting
 	var statuses ginclient.FileStatusSlice
@@ -108,9 +111,19 @@ func printFileStatusList(statFiles map[ginclient.FileStatus][]string) {
 			fmt.Print("  (use \"gin commit <file>...\" to begin tracking and save the current state)\n")
 			fmt.Print("  (use \"gin upload <file>...\" to save the current state and upload directly)\n")
 		}
-		fmt.Fprintf(color.Output, "\n\t%s\n\n", cwriter(strings.Join(statFiles[status], "\n\t")))
+		names := make([]string, len(statFiles[status]))
+		for idx, fname := range statFiles[status] {
+			names[idx] = fname
+			if len(weburls[fname]) > 0 {
+				names[idx] += " [web]"
+			}
+		}
+		fmt.Fprintf(color.Output, "\n\t%s\n\n", cwriter(strings.Join(names, "\n\t")))
 		summary.WriteString(fmt.Sprintf("   %s: %d", cwriter(status.Abbrev()), len(statFiles[status])))
 	}
+	if len(weburls) > 0 {
+		fmt.Print("Files marked [web] can be downloaded from a web address (see \"gin whereis\").\n\n")
+	}
 	fmt.Fprintln(color.Output, summary)
 }
 
@@ -126,7 +139,9 @@ NC: The local file is a placeholder and its contents have not been downloaded.
 MD: The file has been modified locally and the changes have not been recorded yet.
 LC: The file has been modified locally, the changes have been recorded but they haven't been uploaded.
 RM: The file has been removed from the repository.
-??: The file is not under repository control.`
+??: The file is not under repository control.
+
+Files whose content can be downloaded from a web address (see 'gin addurl') are marked [web] in the listing. The JSON output includes their URLs.`
 
 	args := map[string]string{
 		"<filenames>": "One or more directories or files to list.",
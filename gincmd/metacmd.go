@@ -0,0 +1,278 @@
+package gincmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/G-Node/gin-cli/gincmd/ginerrors"
+	"github.com/G-Node/gin-cli/git"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+// splitMetaArgs splits the arguments of a metadata command into metadata expressions (field=value) and paths.
+// If the arguments contain '--', everything before it is an expression. Otherwise, the leading arguments that contain a '=' are expressions.
+func splitMetaArgs(cmd *cobra.Command, args []string) (exprs, paths []string) {
+	if dash := cmd.ArgsLenAtDash(); dash >= 0 {
+		return args[:dash], args[dash:]
+	}
+	idx := 0
+	for idx < len(args) && strings.Contains(args[idx], "=") {
+		idx++
+	}
+	return args[:idx], args[idx:]
+}
+
+// userFields returns the metadata fields sorted by name, without the fields that git-annex maintains automatically.
+func userFields(fields map[string][]string) []string {
+	var names []string
+	for name := range fields {
+		if name == "lastchanged" || strings.HasSuffix(name, "-lastchanged") {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// runMetadata applies the metadata changes to the paths and prints the resulting metadata of each file.
+func runMetadata(cmd *cobra.Command, paths, setexprs, removefields []string) {
+	jsonout, _ := cmd.Flags().GetBool("json")
+	switch git.Checkwd() {
+	case git.NotRepository:
+		Die(ginerrors.NotInRepo)
+	case git.NotAnnex:
+		Warn(ginerrors.MissingAnnex)
+	case git.UpgradeRequired:
+		annexVersionNotice()
+	}
+
+	type filemeta struct {
+		FileName string              `json:"filename"`
+		Fields   map[string][]string `json:"fields"`
+		Err      string              `json:"err,omitempty"`
+	}
+	var results []filemeta
+	nerr := 0
+	metachan := make(chan git.AnnexMetadataRes)
+	go git.AnnexMetadata(paths, setexprs, removefields, metachan)
+	for meta := range metachan {
+		fm := filemeta{FileName: meta.File, Fields: make(map[string][]string)}
+		if meta.Err != nil {
+			nerr++
+			fm.Err = meta.Err.Error()
+		}
+		for _, name := range userFields(meta.Fields) {
+			fm.Fields[name] = meta.Fields[name]
+		}
+		results = append(results, fm)
+	}
+
+	if jsonout {
+		if results == nil {
+			results = []filemeta{}
+		}
+		j, _ := json.Marshal(results)
+		fmt.Println(string(j))
+	} else {
+		for _, fm := range results {
+			if fm.Err != "" {
+				fmt.Fprintf(color.Output, "%s %s\n", fm.FileName, red(fm.Err))
+				continue
+			}
+			fmt.Println(fm.FileName)
+			for _, name := range userFields(fm.Fields) {
+				fmt.Fprintf(color.Output, "  %s=%s\n", cyan(name), strings.Join(fm.Fields[name], ","))
+			}
+		}
+	}
+	if nerr > 0 {
+		plural := ""
+		if nerr > 1 {
+			plural = "s"
+		}
+		Die(fmt.Sprintf("%d operation%s failed", nerr, plural))
+	}
+}
+
+func metaSet(cmd *cobra.Command, args []string) {
+	exprs, paths := splitMetaArgs(cmd, args)
+	if len(exprs) == 0 || len(paths) == 0 {
+		usageDie(cmd)
+	}
+	for _, expr := range exprs {
+		if strings.HasPrefix(expr, "=") {
+			Die(fmt.Sprintf("invalid metadata expression '%s': field name is missing", expr))
+		}
+	}
+	runMetadata(cmd, paths, exprs, nil)
+}
+
+func metaGet(cmd *cobra.Command, args []string) {
+	runMetadata(cmd, args, nil, nil)
+}
+
+func metaRemove(cmd *cobra.Command, args []string) {
+	exprs, paths := splitMetaArgs(cmd, args)
+	if len(exprs) == 0 || len(paths) == 0 {
+		usageDie(cmd)
+	}
+	var setexprs, removefields []string
+	for _, expr := range exprs {
+		parts := strings.SplitN(expr, "=", 2)
+		switch {
+		case parts[0] == "":
+			Die(fmt.Sprintf("invalid metadata expression '%s': field name is missing", expr))
+		case parts[1] == "":
+			// field=: remove all values
+			removefields = append(removefields, parts[0])
+		default:
+			setexprs = append(setexprs, fmt.Sprintf("%s-=%s", parts[0], parts[1]))
+		}
+	}
+	runMetadata(cmd, paths, setexprs, removefields)
+}
+
+func metaView(cmd *cobra.Command, args []string) {
+	exit, _ := cmd.Flags().GetBool("exit")
+	if exit == (len(args) > 0) {
+		usageDie(cmd)
+	}
+	switch git.Checkwd() {
+	case git.NotRepository:
+		Die(ginerrors.NotInRepo)
+	case git.NotAnnex:
+		Die(ginerrors.MissingAnnex)
+	case git.UpgradeRequired:
+		annexVersionNotice()
+	}
+
+	if exit {
+		branch, err := git.CurrentBranch()
+		CheckError(err)
+		if !strings.HasPrefix(branch, "views/") {
+			Die("not in a metadata view")
+		}
+		for strings.HasPrefix(branch, "views/") {
+			CheckError(git.AnnexVPop())
+			branch, err = git.CurrentBranch()
+			CheckError(err)
+		}
+		fmt.Printf(":: Returned to branch '%s'\n", branch)
+		return
+	}
+
+	for _, expr := range args {
+		if !strings.Contains(expr, "=") {
+			Die(fmt.Sprintf("invalid view expression '%s': must be of the form field=glob", expr))
+		}
+	}
+	CheckError(git.AnnexView(args))
+	fmt.Println(":: Switched to metadata view")
+	fmt.Println("   Files are arranged in directories by metadata value. Use 'gin meta view --exit' to return to the normal file layout.")
+}
+
+func metaSetCmd() *cobra.Command {
+	description := "Set metadata fields on annexed files. Each field can hold multiple values: 'field=value' replaces all values of the field with the given value and 'field+=value' adds a value. Metadata expressions are followed by the files and directories to tag. Directories are tagged recursively. If a path contains a '=', separate the expressions from the paths with '--'."
+	args := map[string]string{
+		"<field=value>": "One or more metadata expressions.",
+		"<filenames>":   "One or more directories or files to tag.",
+	}
+	examples := map[string]string{
+		"Tag all files of a session":             "$ gin meta set subject=s01 session=3 modality=ephys recordings/s01-3",
+		"Add a second value to the 'tags' field": "$ gin meta set tags+=reviewed recordings/s01-3/spikes.nix",
+	}
+	var cmd = &cobra.Command{
+		Use:                   "set [--json] <field=value>... <filenames>...",
+		Short:                 "Set metadata fields on files",
+		Long:                  formatdesc(description, args),
+		Example:               formatexamples(examples),
+		Args:                  cobra.MinimumNArgs(2),
+		Run:                   metaSet,
+		DisableFlagsInUseLine: true,
+	}
+	cmd.Flags().Bool("json", false, jsonHelpMsg)
+	return cmd
+}
+
+func metaGetCmd() *cobra.Command {
+	description := "Show the metadata fields of annexed files."
+	args := map[string]string{
+		"<filenames>": "One or more directories or files. If none are specified, all annexed files under the current directory are shown.",
+	}
+	var cmd = &cobra.Command{
+		Use:                   "get [--json] [<filenames>]...",
+		Short:                 "Show the metadata fields of files",
+		Long:                  formatdesc(description, args),
+		Args:                  cobra.ArbitraryArgs,
+		Run:                   metaGet,
+		DisableFlagsInUseLine: true,
+	}
+	cmd.Flags().Bool("json", false, jsonHelpMsg)
+	return cmd
+}
+
+func metaRemoveCmd() *cobra.Command {
+	description := "Remove metadata from annexed files. 'field=value' removes a single value from a field and 'field=' removes all values of the field. Metadata expressions are followed by the files and directories to change. If a path contains a '=', separate the expressions from the paths with '--'."
+	args := map[string]string{
+		"<field=[value]>": "One or more metadata expressions.",
+		"<filenames>":     "One or more directories or files to change.",
+	}
+	examples := map[string]string{
+		"Remove the session field from a file": "$ gin meta rm session= recordings/s01-3/spikes.nix",
+	}
+	var cmd = &cobra.Command{
+		Use:                   "rm [--json] <field=[value]>... <filenames>...",
+		Short:                 "Remove metadata fields or values from files",
+		Long:                  formatdesc(description, args),
+		Example:               formatexamples(examples),
+		Args:                  cobra.MinimumNArgs(2),
+		Run:                   metaRemove,
+		Aliases:               []string{"remove"},
+		DisableFlagsInUseLine: true,
+	}
+	cmd.Flags().Bool("json", false, jsonHelpMsg)
+	return cmd
+}
+
+func metaViewCmd() *cobra.Command {
+	description := "Switch to a view of the repository that contains only the annexed files that match the given metadata, arranged in directories named after their metadata values. The value can be a glob pattern; a '*' value creates a directory for each value of the field. The view is a separate branch: changes to metadata made by moving files between directories in the view are recorded with 'gin commit'. Use --exit to return to the normal file layout."
+	args := map[string]string{
+		"<field=glob>": "One or more metadata expressions.",
+	}
+	examples := map[string]string{
+		"Browse the files of subject s01 by session": "$ gin meta view subject=s01 session=*",
+		"Return to the normal file layout":           "$ gin meta view --exit",
+	}
+	var cmd = &cobra.Command{
+		Use:                   "view <field=glob>... | --exit",
+		Short:                 "Browse files by metadata",
+		Long:                  formatdesc(description, args),
+		Example:               formatexamples(examples),
+		Args:                  cobra.ArbitraryArgs,
+		Run:                   metaView,
+		DisableFlagsInUseLine: true,
+	}
+	cmd.Flags().Bool("exit", false, "Leave the metadata view and return to the original branch.")
+	return cmd
+}
+
+// MetaCmd sets up the 'meta' command and its subcommands for managing file metadata
+func MetaCmd() *cobra.Command {
+	description := "Manage metadata of annexed files. Metadata fields (such as subject, session, or modality) can be used to find files with 'gin find --meta' and to browse the repository by metadata. See the help of each subcommand for details."
+	var cmd = &cobra.Command{
+		Use:                   "meta <command>",
+		Short:                 "Manage file metadata",
+		Long:                  formatdesc(description, nil),
+		Args:                  cobra.NoArgs,
+		DisableFlagsInUseLine: true,
+	}
+	cmd.AddCommand(metaSetCmd())
+	cmd.AddCommand(metaGetCmd())
+	cmd.AddCommand(metaRemoveCmd())
+	cmd.AddCommand(metaViewCmd())
+	return cmd
+}
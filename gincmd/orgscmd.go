@@ -0,0 +1,108 @@
+package gincmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	ginclient "github.com/G-Node/gin-cli/ginclient"
+	"github.com/G-Node/gin-cli/ginclient/config"
+	gogs "github.com/gogits/go-gogs-client"
+	"github.com/spf13/cobra"
+)
+
+func orgs(cmd *cobra.Command, args []string) {
+	flags := cmd.Flags()
+	jsonout, _ := flags.GetBool("json")
+	showteams, _ := flags.GetBool("teams")
+	srvalias, _ := flags.GetString("server")
+
+	conf := config.Read()
+	if srvalias == "" {
+		srvalias = conf.DefaultServer
+	}
+
+	gincl := ginclient.New(srvalias)
+	requirelogin(cmd, gincl, !jsonout)
+
+	var orglist []gogs.Organization
+	if len(args) > 0 {
+		// specific organisations requested: show their teams
+		showteams = true
+		for _, name := range args {
+			orglist = append(orglist, gogs.Organization{UserName: name})
+		}
+	} else {
+		var err error
+		orglist, err = gincl.ListOrgs()
+		CheckError(err)
+	}
+
+	type orginfo struct {
+		gogs.Organization
+		Teams []gogs.Team `json:"teams,omitempty"`
+	}
+	outlist := make([]orginfo, len(orglist))
+	for idx, org := range orglist {
+		outlist[idx] = orginfo{Organization: org}
+		if showteams {
+			teams, err := gincl.ListTeams(org.UserName)
+			CheckError(err)
+			outlist[idx].Teams = teams
+		}
+	}
+
+	if jsonout {
+		j, _ := json.Marshal(outlist)
+		fmt.Println(string(j))
+		return
+	}
+
+	if len(outlist) == 0 {
+		fmt.Println("You are not a member of any organisation")
+		return
+	}
+	for _, org := range outlist {
+		fmt.Printf("* %s", org.UserName)
+		if org.FullName != "" {
+			fmt.Printf(" (%s)", org.FullName)
+		}
+		fmt.Println()
+		if org.Description != "" {
+			fmt.Printf("\t%s\n", org.Description)
+		}
+		if showteams {
+			for _, team := range org.Teams {
+				fmt.Printf("\t- %s: %s", team.Name, team.Permission)
+				if team.Description != "" {
+					fmt.Printf(" (%s)", team.Description)
+				}
+				fmt.Println()
+			}
+		}
+	}
+}
+
+// OrgsCmd sets up the 'orgs' listing subcommand
+func OrgsCmd() *cobra.Command {
+	description := "List the organisations the logged in user is a member of. Optionally, list the teams of each organisation and their permission level.\n\nTo list the repositories of an organisation, use 'gin repos --org <organisation>'."
+	args := map[string]string{
+		"<organisation>": "One or more organisation names. If specified, the teams of the given organisations are listed. Only members of an organisation can list its teams.",
+	}
+	examples := map[string]string{
+		"List your organisations":                  "$ gin orgs",
+		"List your organisations and their teams":  "$ gin orgs --teams",
+		"List the teams of the organisation 'lab'": "$ gin orgs lab",
+	}
+	var cmd = &cobra.Command{
+		Use:                   "orgs [--teams] [--json] [<organisation>...]",
+		Short:                 "List organisations and teams",
+		Long:                  formatdesc(description, args),
+		Example:               formatexamples(examples),
+		Run:                   orgs,
+		DisableFlagsInUseLine: true,
+	}
+	cmd.Flags().Bool("teams", false, "Also list the teams of each organisation.")
+	cmd.Flags().Bool("json", false, jsonHelpMsg)
+	cmd.Flags().String("server", "", "Specify server `alias` to query. See also 'gin servers'.")
+	return cmd
+}
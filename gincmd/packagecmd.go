@@ -0,0 +1,182 @@
+package gincmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	ginclient "github.com/G-Node/gin-cli/ginclient"
+	"github.com/G-Node/gin-cli/gincmd/ginerrors"
+	"github.com/G-Node/gin-cli/git"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+func packageRepo(cmd *cobra.Command, args []string) {
+	flags := cmd.Flags()
+	jsonout, _ := flags.GetBool("json")
+	rev, _ := flags.GetString("rev")
+	outdir, _ := flags.GetString("output")
+	include, _ := flags.GetStringArray("include")
+	exclude, _ := flags.GetStringArray("exclude")
+	var opts ginclient.PackageOptions
+	opts.BagIt, _ = flags.GetBool("bagit")
+	opts.ROCrate, _ = flags.GetBool("ro-crate")
+	opts.Algorithm, _ = flags.GetString("checksum")
+
+	if !opts.BagIt && !opts.ROCrate {
+		usageDie(cmd)
+	}
+	if opts.Algorithm != "sha256" && opts.Algorithm != "md5" {
+		Die(fmt.Sprintf("invalid checksum algorithm '%s': must be 'sha256' or 'md5'", opts.Algorithm))
+	}
+
+	switch git.Checkwd() {
+	case git.NotRepository:
+		Die(ginerrors.NotInRepo)
+	case git.NotAnnex:
+		Warn(ginerrors.MissingAnnex)
+	case git.UpgradeRequired:
+		annexVersionNotice()
+	}
+
+	// Repository information from the server is optional
+	if srvalias, repostr, err := ginclient.DefaultRemoteRepoPath(); err == nil {
+		gincl := ginclient.New(srvalias)
+		gincl.LoadToken()
+		if repoinfo, err := gincl.GetRepo(repostr); err == nil {
+			opts.Repository = &repoinfo
+		} else if !jsonout {
+			Warn(fmt.Sprintf("could not retrieve repository information from the server: %s", err))
+		}
+	}
+
+	reporoot, err := git.FindRepoRoot(".")
+	CheckError(err)
+	opts.Name = filepath.Base(reporoot)
+	if outdir == "" {
+		outdir = fmt.Sprintf("%s-%s", filepath.Base(reporoot), strings.Replace(rev, "/", "-", -1))
+	}
+	outdir, _ = filepath.Abs(outdir)
+
+	if !jsonout {
+		fmt.Printf(":: Packaging version '%s' in %s\n", rev, outdir)
+	}
+	pkgchan := make(chan ginclient.FileCheckoutStatus)
+	go ginclient.PackageRevision(rev, args, pathFilter(include, exclude), outdir, opts, pkgchan)
+	var nfiles, nerr int
+	for status := range pkgchan {
+		if jsonout {
+			errmsg := ""
+			if status.Err != nil {
+				errmsg = status.Err.Error()
+			}
+			j, _ := json.Marshal(struct {
+				FileName string `json:"filename"`
+				Type     string `json:"type"`
+				Path     string `json:"packagepath"`
+				Err      string `json:"err"`
+			}{status.Filename, status.Type, status.Destination, errmsg})
+			fmt.Println(string(j))
+		}
+		if status.Err != nil {
+			nerr++
+			if !jsonout {
+				if status.Filename == "" {
+					fmt.Printf(" Packaging failed: %s\n", status.Err.Error())
+				} else {
+					fmt.Printf(" Failed to package '%s': %s\n", status.Filename, status.Err.Error())
+				}
+			}
+			continue
+		}
+		if status.Type != "Metadata" {
+			nfiles++
+		}
+	}
+	if !jsonout {
+		fmt.Printf(":: %d files packaged\n", nfiles)
+	}
+	if nerr > 0 {
+		plural := ""
+		if nerr > 1 {
+			plural = "s"
+		}
+		Die(fmt.Sprintf("%d operation%s failed: the package was not created", nerr, plural))
+	}
+}
+
+func packageValidate(cmd *cobra.Command, args []string) {
+	jsonout, _ := cmd.Flags().GetBool("json")
+	dir := args[0]
+	types, problems := ginclient.ValidatePackage(dir)
+	if jsonout {
+		j, _ := json.Marshal(struct {
+			Path     string   `json:"path"`
+			Types    []string `json:"types"`
+			Valid    bool     `json:"valid"`
+			Problems []string `json:"problems"`
+		}{dir, types, len(problems) == 0, problems})
+		fmt.Println(string(j))
+		if len(problems) > 0 {
+			Die("")
+		}
+		return
+	}
+	if len(types) > 0 {
+		fmt.Printf(":: Checking %s (%s)\n", dir, strings.Join(types, ", "))
+	}
+	if len(problems) > 0 {
+		for _, p := range problems {
+			fmt.Fprintf(color.Output, "  %s %s\n", red("-"), p)
+		}
+		Die(fmt.Sprintf("%s is not valid", dir))
+	}
+	fmt.Fprintf(color.Output, ":: %s %s\n", dir, green("is valid"))
+}
+
+// PackageCmd sets up the 'package' command and its 'validate' subcommand
+func PackageCmd() *cobra.Command {
+	description := fmt.Sprintf("Package a version of the repository, including the content of annexed files, in a new directory as a BagIt bag, an RO-Crate, or both (an RO-Crate inside a BagIt bag). Annexed content that is not available locally is downloaded as needed.\n\nChecksums are taken from the annex keys of files whose backend matches the checksum algorithm (e.g., SHA256E keys for sha256), so that their content does not need to be hashed again. Descriptive metadata is taken from the %s file of the packaged version and from the repository information on the server.\n\nPatterns given to --include and --exclude work as for 'gin export'. Use 'gin package validate' to check an existing package.\n\nIf any file cannot be packaged, the package directory is removed.", ginclient.DataCiteFileName)
+	args := map[string]string{
+		"<filenames>": "One or more directories or files to package. If none are specified, the whole repository is packaged.",
+	}
+	examples := map[string]string{
+		"Create a BagIt bag of the version tagged 'submitted'": "$ gin package --bagit --rev submitted -o dataset-bag",
+		"Create an RO-Crate inside a BagIt bag":                "$ gin package --bagit --ro-crate",
+		"Check a bag before sending it to the archive":         "$ gin package validate dataset-bag",
+	}
+	var cmd = &cobra.Command{
+		Use:                   "package (--bagit | --ro-crate)... [--rev <version>] [-o <directory>] [--checksum sha256 | md5] [--include <pattern>]... [--exclude <pattern>]... [--json] [<filenames>]...",
+		Short:                 "Package a version of the repository as a BagIt bag or RO-Crate",
+		Long:                  formatdesc(description, args),
+		Example:               formatexamples(examples),
+		Args:                  cobra.ArbitraryArgs,
+		Run:                   packageRepo,
+		DisableFlagsInUseLine: true,
+	}
+	cmd.Flags().Bool("bagit", false, "Create a BagIt bag.")
+	cmd.Flags().Bool("ro-crate", false, "Create an RO-Crate. When combined with --bagit, the crate is the payload of the bag.")
+	cmd.Flags().String("rev", "HEAD", "The `version` (ID, tag, or branch) to package.")
+	cmd.Flags().StringP("output", "o", "", "The package `directory` to create. Defaults to <repository>-<version> in the current directory.")
+	cmd.Flags().String("checksum", "sha256", "Checksum `algorithm` for manifests: 'sha256' or 'md5'.")
+	cmd.Flags().StringArray("include", nil, "Only package files matching the `pattern`. Can be specified multiple times.")
+	cmd.Flags().StringArray("exclude", nil, "Do not package files matching the `pattern`. Can be specified multiple times.")
+	cmd.Flags().Bool("json", false, jsonHelpMsg)
+
+	validateargs := map[string]string{
+		"<directory>": "The package directory to check.",
+	}
+	validatecmd := &cobra.Command{
+		Use:                   "validate [--json] <directory>",
+		Short:                 "Check a BagIt bag or RO-Crate",
+		Long:                  formatdesc("Check that a BagIt bag is complete and that all checksums in its manifests match, and that an RO-Crate has the required metadata entities and that the checksums of the files it lists match.", validateargs),
+		Args:                  cobra.ExactArgs(1),
+		Run:                   packageValidate,
+		DisableFlagsInUseLine: true,
+	}
+	validatecmd.Flags().Bool("json", false, jsonHelpMsg)
+	cmd.AddCommand(validatecmd)
+	return cmd
+}
@@ -0,0 +1,191 @@
+package gincmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	ginclient "github.com/G-Node/gin-cli/ginclient"
+	"github.com/G-Node/gin-cli/git"
+	"github.com/fatih/color"
+	gogs "github.com/gogits/go-gogs-client"
+	"github.com/spf13/cobra"
+)
+
+// tagOnRemote returns false if a tag exists in the local repository but has not been uploaded to the default remote.
+// In all other cases, including when the check cannot be performed, it returns true.
+func tagOnRemote(tagname string) bool {
+	if _, err := git.RevParse(fmt.Sprintf("refs/tags/%s", tagname)); err != nil {
+		// not a local tag
+		return true
+	}
+	defremote, err := ginclient.DefaultRemote()
+	if err != nil {
+		return true
+	}
+	refs, err := git.LsRemote(defremote)
+	if err != nil {
+		return true
+	}
+	return strings.Contains(refs, fmt.Sprintf("refs/tags/%s\n", tagname))
+}
+
+func releaseCreate(cmd *cobra.Command, args []string) {
+	flags := cmd.Flags()
+	jsonout, _ := flags.GetBool("json")
+	repoflag, _ := flags.GetString("repo")
+	opt := ginclient.CreateReleaseOption{TagName: args[0]}
+	opt.Name, _ = flags.GetString("title")
+	opt.Body, _ = flags.GetString("notes")
+	opt.Draft, _ = flags.GetBool("draft")
+	opt.Prerelease, _ = flags.GetBool("prerelease")
+	if opt.Name == "" {
+		opt.Name = opt.TagName
+	}
+
+	srvalias, repostr := repoTarget(cmd)
+	if repoflag == "" && !tagOnRemote(opt.TagName) {
+		Die(fmt.Sprintf("Tag '%s' has not been uploaded yet. Run 'gin upload' first.", opt.TagName))
+	}
+
+	gincl := ginclient.New(srvalias)
+	requirelogin(cmd, gincl, !jsonout)
+	release, err := gincl.CreateRelease(repostr, opt)
+	CheckError(err)
+	if jsonout {
+		j, _ := json.Marshal(release)
+		fmt.Println(string(j))
+		return
+	}
+	fmt.Printf(":: Created release '%s' for tag '%s' of '%s'\n", release.Name, release.TagName, repostr)
+}
+
+func printRelease(release gogs.Release) {
+	fmt.Fprintf(color.Output, "* %s", green(release.TagName))
+	if release.Name != "" && release.Name != release.TagName {
+		fmt.Printf(": %s", release.Name)
+	}
+	if release.Draft {
+		fmt.Fprintf(color.Output, " %s", yellow("[draft]"))
+	}
+	if release.Prerelease {
+		fmt.Fprintf(color.Output, " %s", yellow("[pre-release]"))
+	}
+	fmt.Println()
+	fmt.Printf("\tCreated: %s", release.Created.Format("2006-01-02 15:04:05"))
+	if release.Author != nil {
+		fmt.Printf(" by %s", release.Author.UserName)
+	}
+	fmt.Println()
+	body := strings.TrimSpace(release.Body)
+	if body != "" {
+		fmt.Printf("\t%s\n", strings.Replace(body, "\n", "\n\t", -1))
+	}
+	fmt.Println()
+}
+
+func releaseList(cmd *cobra.Command, args []string) {
+	jsonout, _ := cmd.Flags().GetBool("json")
+	srvalias, repostr := repoTarget(cmd)
+	gincl := ginclient.New(srvalias)
+	requirelogin(cmd, gincl, !jsonout)
+	releases, err := gincl.ListReleases(repostr)
+	CheckError(err)
+	if jsonout {
+		j, _ := json.Marshal(releases)
+		fmt.Println(string(j))
+		return
+	}
+	if len(releases) == 0 {
+		fmt.Printf("Repository '%s' has no releases\n", repostr)
+		return
+	}
+	for _, release := range releases {
+		printRelease(release)
+	}
+}
+
+func releaseDelete(cmd *cobra.Command, args []string) {
+	tagname := args[0]
+	srvalias, repostr := repoTarget(cmd)
+	gincl := ginclient.New(srvalias)
+	requirelogin(cmd, gincl, true)
+	releases, err := gincl.ListReleases(repostr)
+	CheckError(err)
+	for _, release := range releases {
+		if release.TagName != tagname {
+			continue
+		}
+		err = gincl.DeleteRelease(repostr, release.ID)
+		CheckError(err)
+		fmt.Printf(":: Deleted release '%s' of '%s'\n", tagname, repostr)
+		return
+	}
+	Die(fmt.Sprintf("Repository '%s' has no release for tag '%s'", repostr, tagname))
+}
+
+func releaseFlags(cmd *cobra.Command) {
+	cmd.Flags().String("repo", "", "The `repository` path (owner/name) on the server. If not specified, the repository of the default remote of the current working directory is used.")
+	cmd.Flags().String("server", "", "Specify server `alias` where the repository resides. See also 'gin servers'.")
+}
+
+// ReleaseCmd sets up the 'release' command and its subcommands
+func ReleaseCmd() *cobra.Command {
+	description := "Manage the releases of a repository on the server. A release publishes a tagged version of the repository, with a title and release notes, on the repository page. Tags are created with 'gin tag'."
+	var cmd = &cobra.Command{
+		Use:                   "release <command>",
+		Short:                 "Manage repository releases on the server",
+		Long:                  formatdesc(description, nil),
+		Args:                  cobra.NoArgs,
+		DisableFlagsInUseLine: true,
+	}
+
+	createargs := map[string]string{
+		"<tag>": "The name of the tag to publish. If the tag does not exist on the server, it is created from the latest version of the default branch.",
+	}
+	createexamples := map[string]string{
+		"Publish the tag 'submitted' as a release": "$ gin release create --title \"Dataset submitted to journal\" submitted",
+	}
+	createcmd := &cobra.Command{
+		Use:                   "create [--title <title>] [--notes <text>] [--draft] [--prerelease] [--repo <repopath>] [--json] <tag>",
+		Short:                 "Create a release from a tag",
+		Long:                  formatdesc("Create a new release on the server for a tag. Tags created locally must be uploaded before a release can be created for them.", createargs),
+		Example:               formatexamples(createexamples),
+		Args:                  cobra.ExactArgs(1),
+		Run:                   releaseCreate,
+		DisableFlagsInUseLine: true,
+	}
+	createcmd.Flags().String("title", "", "The `title` of the release. Defaults to the tag name.")
+	createcmd.Flags().String("notes", "", "Release notes `text`.")
+	createcmd.Flags().Bool("draft", false, "Create the release as a draft (not visible to others).")
+	createcmd.Flags().Bool("prerelease", false, "Mark the release as a pre-release.")
+	createcmd.Flags().Bool("json", false, jsonHelpMsg)
+	releaseFlags(createcmd)
+
+	listcmd := &cobra.Command{
+		Use:                   "list [--repo <repopath>] [--json]",
+		Short:                 "List the releases of a repository",
+		Long:                  formatdesc("List the releases of a repository on the server.", nil),
+		Args:                  cobra.NoArgs,
+		Run:                   releaseList,
+		DisableFlagsInUseLine: true,
+	}
+	listcmd.Flags().Bool("json", false, jsonHelpMsg)
+	releaseFlags(listcmd)
+
+	deleteargs := map[string]string{
+		"<tag>": "The tag name of the release to delete.",
+	}
+	deletecmd := &cobra.Command{
+		Use:                   "delete [--repo <repopath>] <tag>",
+		Short:                 "Delete a release",
+		Long:                  formatdesc("Delete a release from the server. The tag itself is not deleted.", deleteargs),
+		Args:                  cobra.ExactArgs(1),
+		Run:                   releaseDelete,
+		DisableFlagsInUseLine: true,
+	}
+	releaseFlags(deletecmd)
+
+	cmd.AddCommand(createcmd, listcmd, deletecmd)
+	return cmd
+}
@@ -0,0 +1,163 @@
+package gincmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	ginclient "github.com/G-Node/gin-cli/ginclient"
+	"github.com/G-Node/gin-cli/ginclient/config"
+	"github.com/G-Node/gin-cli/git"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+// updateRemoteURLs changes the URL of any remote in the current repository that points to oldpath so that it points to newpath.
+// It is used after a repository has been renamed or moved to a new owner on the server.
+// Nothing is changed if the working directory is not in a repository.
+// If quiet is true, only errors are printed.
+func updateRemoteURLs(oldpath, newpath string, quiet bool) {
+	if _, err := git.FindRepoRoot("."); err != nil {
+		return
+	}
+	remotes, err := git.RemoteShow()
+	if err != nil {
+		Warn(fmt.Sprintf("could not read local remotes: %s", err))
+		return
+	}
+	for name, url := range remotes {
+		var suffix string
+		if strings.HasSuffix(url, ".git") {
+			suffix = ".git"
+		}
+		trimmed := strings.TrimSuffix(url, suffix)
+		if !strings.HasSuffix(trimmed, "/"+oldpath) && !strings.HasSuffix(trimmed, ":"+oldpath) {
+			continue
+		}
+		newurl := strings.TrimSuffix(trimmed, oldpath) + newpath + suffix
+		if !quiet {
+			fmt.Printf(":: Updating URL of remote '%s' to %s ", name, newurl)
+		}
+		err = git.RemoteSetURL(name, newurl)
+		CheckError(err)
+		if !quiet {
+			fmt.Fprintln(color.Output, green("OK"))
+		}
+	}
+}
+
+func repoSet(cmd *cobra.Command, args []string) {
+	flags := cmd.Flags()
+	jsonout, _ := flags.GetBool("json")
+	public, _ := flags.GetBool("public")
+	private, _ := flags.GetBool("private")
+	srvalias, _ := flags.GetString("server")
+	yes, _ := flags.GetBool("yes")
+
+	if public && private {
+		usageDie(cmd)
+	}
+
+	var opt ginclient.EditRepoOption
+	if flags.Changed("name") {
+		name, _ := flags.GetString("name")
+		opt.Name = &name
+	}
+	if flags.Changed("description") {
+		description, _ := flags.GetString("description")
+		opt.Description = &description
+	}
+	if flags.Changed("website") {
+		website, _ := flags.GetString("website")
+		opt.Website = &website
+	}
+	if public || private {
+		opt.Private = &private
+	}
+	if opt == (ginclient.EditRepoOption{}) {
+		usageDie(cmd)
+	}
+
+	conf := config.Read()
+	if srvalias == "" {
+		srvalias = conf.DefaultServer
+	}
+	repostr := args[0]
+	if !isValidRepoPath(repostr) {
+		Die(fmt.Sprintf("Invalid repository path '%s'. Full repository name should be the owner's username followed by the repository name, separated by a '/'.", repostr))
+	}
+
+	gincl := ginclient.New(srvalias)
+	requirelogin(cmd, gincl, !jsonout)
+
+	if public {
+		repoinfo, err := gincl.GetRepo(repostr)
+		CheckError(err)
+		if repoinfo.Private && !yes {
+			fmt.Fprintf(os.Stderr, "You are about to make the repository '%s' public.\n", repoinfo.FullName)
+			fmt.Fprintln(os.Stderr, "All of its files and history will be visible to anyone, including users who are not logged in.")
+			if !confirm("Are you sure you want to continue?") {
+				Exit("Aborted")
+			}
+		}
+	}
+
+	newinfo, err := gincl.EditRepo(repostr, opt)
+	CheckError(err)
+
+	if opt.Name != nil && newinfo.FullName != "" && newinfo.FullName != repostr {
+		updateRemoteURLs(repostr, newinfo.FullName, jsonout)
+	}
+
+	if jsonout {
+		j, _ := json.Marshal(newinfo)
+		fmt.Println(string(j))
+		return
+	}
+	printRepoInfo(newinfo)
+}
+
+func repoSetCmd() *cobra.Command {
+	description := "Change the settings of a repository on the server. Only the settings specified by flags are changed. Changing repository settings requires administrative access to the repository.\n\nMaking a private repository public requires confirmation, unless --yes is specified. When a repository is renamed, any remotes of the repository in the current working directory that point to the old location are updated to the new one."
+	args := map[string]string{
+		"<repopath>": "The repository path must be specified on the command line. A repository path is the owner's username, followed by a \"/\" and the repository name.",
+	}
+	examples := map[string]string{
+		"Make the repository 'alice/example' public":   "$ gin repo set --public alice/example",
+		"Change the description of 'alice/eegdata'":    "$ gin repo set --description \"EEG recordings 2019\" alice/eegdata",
+		"Rename the repository 'alice/tmp' to 'ephys'": "$ gin repo set --name ephys alice/tmp",
+	}
+	var cmd = &cobra.Command{
+		Use:                   "set [--public [--yes] | --private] [--description <text>] [--website <url>] [--name <name>] [--json] <repopath>",
+		Short:                 "Change the settings of a repository",
+		Long:                  formatdesc(description, args),
+		Example:               formatexamples(examples),
+		Args:                  cobra.ExactArgs(1),
+		Run:                   repoSet,
+		DisableFlagsInUseLine: true,
+	}
+	cmd.Flags().Bool("public", false, "Make the repository public. Cannot be used with --private.")
+	cmd.Flags().Bool("private", false, "Make the repository private. Cannot be used with --public.")
+	cmd.Flags().String("description", "", "Set the repository `description`.")
+	cmd.Flags().String("website", "", "Set the repository website `URL`.")
+	cmd.Flags().String("name", "", "Rename the repository to `name`. The owner of the repository does not change.")
+	cmd.Flags().Bool("yes", false, "Do not ask for confirmation when making a repository public.")
+	cmd.Flags().Bool("json", false, jsonHelpMsg)
+	cmd.Flags().String("server", "", "Specify server `alias` where the repository resides. See also 'gin servers'.")
+	return cmd
+}
+
+// RepoCmd sets up the 'repo' command and its subcommands for managing repository settings
+func RepoCmd() *cobra.Command {
+	description := "Manage the settings of a repository on the server. See the help of each subcommand for details."
+	var cmd = &cobra.Command{
+		Use:                   "repo <command>",
+		Short:                 "Manage repository settings on the server",
+		Long:                  formatdesc(description, nil),
+		Args:                  cobra.NoArgs,
+		DisableFlagsInUseLine: true,
+	}
+	cmd.AddCommand(repoSetCmd())
+	return cmd
+}
@@ -22,6 +22,7 @@ func repos(cmd *cobra.Command, args []string) {
 	allrepos, _ := flags.GetBool("all")
 	sharedrepos, _ := flags.GetBool("shared")
 	srvalias, _ := flags.GetString("server")
+	org, _ := flags.GetString("org")
 
 	conf := config.Read()
 	if srvalias == "" {
@@ -30,9 +31,16 @@ func repos(cmd *cobra.Command, args []string) {
 	if (allrepos && sharedrepos) || ((allrepos || sharedrepos) && len(args) > 0) {
 		usageDie(cmd)
 	}
+	if org != "" && (allrepos || sharedrepos || len(args) > 0) {
+		usageDie(cmd)
+	}
 
 	gincl := ginclient.New(srvalias)
 	requirelogin(cmd, gincl, !jsonout)
+	if org != "" {
+		orgRepos(gincl, org, jsonout)
+		return
+	}
 	username := gincl.Username
 	if len(args) == 1 && args[0] != username {
 		username = args[0]
@@ -84,6 +92,24 @@ func repos(cmd *cobra.Command, args []string) {
 	}
 }
 
+// orgRepos prints the repositories of an organisation that are accessible to the logged in user.
+func orgRepos(gincl *ginclient.Client, org string, jsonout bool) {
+	repolist, err := gincl.ListOrgRepos(org)
+	CheckError(err)
+	if jsonout {
+		if len(repolist) > 0 {
+			j, _ := json.Marshal(repolist)
+			fmt.Println(string(j))
+		}
+		return
+	}
+	if len(repolist) == 0 {
+		fmt.Println("No repositories found")
+		return
+	}
+	printRepoList(repolist)
+}
+
 // ReposCmd sets up the 'repos' listing subcommand
 func ReposCmd() *cobra.Command {
 	description := "List repositories on the server that provide read access. If no argument is provided, it will list the repositories owned by the logged in user.\n\nNote that only one of the options can be specified."
@@ -92,7 +118,7 @@ func ReposCmd() *cobra.Command {
 		"<username>": "The name of the user whose repositories should be listed. The list consists of public repositories and repositories shared with the logged in user.",
 	}
 	var cmd = &cobra.Command{
-		Use:                   "repos [--shared | --all | <username>]",
+		Use:                   "repos [--shared | --all | --org <organisation> | <username>]",
 		Short:                 "List available remote repositories",
 		Long:                  formatdesc(description, args),
 		Args:                  cobra.MaximumNArgs(1),
@@ -101,6 +127,7 @@ func ReposCmd() *cobra.Command {
 	}
 	cmd.Flags().Bool("all", false, "List all repositories accessible to the logged in user.")
 	cmd.Flags().Bool("shared", false, "List all repositories that the user is a member of (excluding own repositories).")
+	cmd.Flags().String("org", "", "List the repositories owned by the given `organisation`.")
 	cmd.Flags().Bool("json", false, jsonHelpMsg)
 	cmd.Flags().String("server", "", "Specify server `alias` where the repository will be created. See also 'gin servers'.")
 	return cmd
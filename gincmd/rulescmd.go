@@ -0,0 +1,190 @@
+package gincmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	ginclient "github.com/G-Node/gin-cli/ginclient"
+	"github.com/G-Node/gin-cli/gincmd/ginerrors"
+	"github.com/G-Node/gin-cli/git"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+func checkRulesRepo() {
+	switch git.Checkwd() {
+	case git.NotRepository:
+		Die(ginerrors.NotInRepo)
+	case git.NotAnnex:
+		Warn(ginerrors.MissingAnnex)
+	case git.UpgradeRequired:
+		annexVersionNotice()
+	}
+}
+
+func rulesShow(cmd *cobra.Command, args []string) {
+	jsonout, _ := cmd.Flags().GetBool("json")
+	checkRulesRepo()
+	rules, found, err := ginclient.ReadAnnexRules()
+	CheckError(err)
+	if jsonout {
+		j, _ := json.Marshal(struct {
+			Repository bool                  `json:"repository"`
+			Rules      []ginclient.AnnexRule `json:"rules"`
+		}{found, rules})
+		fmt.Println(string(j))
+		return
+	}
+	if found {
+		fmt.Printf(":: Repository rules (%s); later rules take precedence\n", ginclient.AttributesFileName)
+	} else {
+		fmt.Println(":: No repository rules defined; using the client configuration")
+	}
+	for idx, rule := range rules {
+		fmt.Fprintf(color.Output, " %2d. %s %s\n", idx+1, cyan(fmt.Sprintf("%-20s", rule.Pattern)), rule.Description())
+	}
+}
+
+func rulesSet(cmd *cobra.Command, args []string) {
+	checkRulesRepo()
+	rule, err := ginclient.NewAnnexRule(args[0], args[1])
+	CheckError(err)
+	CheckError(ginclient.SetAnnexRule(rule))
+	fmt.Fprintf(color.Output, ":: Files matching %s are %s\n", cyan(rule.Pattern), rule.Description())
+	fmt.Printf("   Use 'gin commit %s' to record the change. Rules only apply to files added after the change.\n", ginclient.AttributesFileName)
+}
+
+func rulesUnset(cmd *cobra.Command, args []string) {
+	checkRulesRepo()
+	CheckError(ginclient.UnsetAnnexRule(args[0]))
+	fmt.Printf(":: Rule for %s removed\n", args[0])
+	fmt.Printf("   Use 'gin commit %s' to record the change.\n", ginclient.AttributesFileName)
+}
+
+func rulesCheck(cmd *cobra.Command, args []string) {
+	jsonout, _ := cmd.Flags().GetBool("json")
+	checkRulesRepo()
+	var checks []ginclient.RuleCheck
+	for _, fpath := range args {
+		check, err := ginclient.CheckAnnexRules(fpath)
+		CheckError(err)
+		checks = append(checks, check)
+	}
+	if jsonout {
+		j, _ := json.Marshal(checks)
+		fmt.Println(string(j))
+		return
+	}
+	for _, check := range checks {
+		var dest string
+		switch check.Destination {
+		case "annex":
+			dest = green("annex")
+		case "git":
+			dest = yellow("git")
+		case "size-dependent":
+			dest = "annex or git, depending on its size"
+		default:
+			dest = "determined by git-annex from the annex.largefiles attribute"
+		}
+		fmt.Fprintf(color.Output, "%s: %s\n", check.File, dest)
+		if check.Warning != "" {
+			fmt.Fprintf(color.Output, "  %s %s\n", yellow("warning:"), check.Warning)
+		} else if check.Rule == nil {
+			fmt.Println("  no rule matches; annexed by default")
+		} else {
+			fmt.Fprintf(color.Output, "  rule %s: %s\n", cyan(check.Rule.Pattern), check.Rule.Description())
+		}
+		if check.Size >= 0 {
+			fmt.Printf("  size: %d bytes\n", check.Size)
+		}
+		fmt.Printf("  annex.largefiles: %s\n", check.Attribute)
+	}
+}
+
+func rulesShowCmd() *cobra.Command {
+	description := "Show the rules that determine whether files are added to the annex or stored in git. If the repository does not define its own rules, the rules derived from the annex settings of the client configuration are shown."
+	var cmd = &cobra.Command{
+		Use:                   "show [--json]",
+		Short:                 "Show the annex rules of the repository",
+		Long:                  formatdesc(description, nil),
+		Args:                  cobra.NoArgs,
+		Run:                   rulesShow,
+		DisableFlagsInUseLine: true,
+	}
+	cmd.Flags().Bool("json", false, jsonHelpMsg)
+	return cmd
+}
+
+func rulesSetCmd() *cobra.Command {
+	description := "Set the rule for files matching a pattern, replacing any existing rule for the same pattern. The new rule takes precedence over all other rules. When the first rule is set, the annex settings of the client configuration are written as the initial rules, so that other files are handled as before."
+	args := map[string]string{
+		"<pattern>": "A file name pattern (e.g., '*.ipynb'), a path pattern (e.g., 'derived/*.csv'), or a directory ending in '/' (e.g., 'raw/') to match everything in it. Patterns without a '/' match files at any level.",
+		"<annex>":   "'always' to always add matching files to the annex, 'never' to always store them in git, or a size (e.g., 1M) above which they are added to the annex.",
+	}
+	examples := map[string]string{
+		"Always annex everything under raw/":             "$ gin rules set raw/ always",
+		"Never annex notebooks":                          "$ gin rules set '*.ipynb' never",
+		"Annex files in derived/ larger than 1 megabyte": "$ gin rules set derived/ 1M",
+	}
+	var cmd = &cobra.Command{
+		Use:                   "set <pattern> <annex>",
+		Short:                 "Set the annex rule for a pattern",
+		Long:                  formatdesc(description, args),
+		Example:               formatexamples(examples),
+		Args:                  cobra.ExactArgs(2),
+		Run:                   rulesSet,
+		DisableFlagsInUseLine: true,
+	}
+	return cmd
+}
+
+func rulesUnsetCmd() *cobra.Command {
+	description := "Remove the rule for a pattern."
+	args := map[string]string{
+		"<pattern>": "The pattern of the rule to remove, as shown by 'gin rules show'.",
+	}
+	var cmd = &cobra.Command{
+		Use:                   "unset <pattern>",
+		Short:                 "Remove the annex rule for a pattern",
+		Long:                  formatdesc(description, args),
+		Args:                  cobra.ExactArgs(1),
+		Run:                   rulesUnset,
+		DisableFlagsInUseLine: true,
+	}
+	return cmd
+}
+
+func rulesCheckCmd() *cobra.Command {
+	description := "Explain whether files would be added to the annex or stored in git, and which rule applies. The destination is determined from the effective annex.largefiles attribute reported by git, which is also shown. If the attribute does not match the rule that 'gin rules' finds for a file (e.g., because the attributes file has been edited by hand), a warning is shown instead of the rule."
+	args := map[string]string{
+		"<filenames>": "One or more files to check. The files do not need to exist.",
+	}
+	var cmd = &cobra.Command{
+		Use:                   "check [--json] <filenames>...",
+		Short:                 "Explain where files would be stored",
+		Long:                  formatdesc(description, args),
+		Args:                  cobra.MinimumNArgs(1),
+		Run:                   rulesCheck,
+		DisableFlagsInUseLine: true,
+	}
+	cmd.Flags().Bool("json", false, jsonHelpMsg)
+	return cmd
+}
+
+// RulesCmd sets up the 'rules' command and its subcommands for managing the annex rules of a repository
+func RulesCmd() *cobra.Command {
+	description := fmt.Sprintf("Manage the rules that determine which files are added to the annex and which are stored in git. The rules are stored in the %s file of the repository, so that they apply to all clones and to git-annex itself. They replace the annex settings (minsize and exclude) of the client configuration for the repository. See the help of each subcommand for details.", ginclient.AttributesFileName)
+	var cmd = &cobra.Command{
+		Use:                   "rules <command>",
+		Short:                 "Manage which files are annexed",
+		Long:                  formatdesc(description, nil),
+		Args:                  cobra.NoArgs,
+		DisableFlagsInUseLine: true,
+	}
+	cmd.AddCommand(rulesShowCmd())
+	cmd.AddCommand(rulesSetCmd())
+	cmd.AddCommand(rulesUnsetCmd())
+	cmd.AddCommand(rulesCheckCmd())
+	return cmd
+}
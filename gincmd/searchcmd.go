@@ -0,0 +1,121 @@
+package gincmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	ginclient "github.com/G-Node/gin-cli/ginclient"
+	"github.com/G-Node/gin-cli/ginclient/config"
+	gogs "github.com/gogits/go-gogs-client"
+	"github.com/spf13/cobra"
+)
+
+// sortRepos sorts a list of repositories in place by the given key.
+// Names are sorted alphabetically, dates from newest to oldest, and sizes from largest to smallest.
+func sortRepos(repolist []gogs.Repository, key string) {
+	var less func(i, j int) bool
+	switch key {
+	case "name":
+		less = func(i, j int) bool {
+			return strings.ToLower(repolist[i].FullName) < strings.ToLower(repolist[j].FullName)
+		}
+	case "created":
+		less = func(i, j int) bool { return repolist[i].Created.After(repolist[j].Created) }
+	case "updated":
+		less = func(i, j int) bool { return repolist[i].Updated.After(repolist[j].Updated) }
+	case "size":
+		less = func(i, j int) bool { return repolist[i].Size > repolist[j].Size }
+	default:
+		return
+	}
+	sort.SliceStable(repolist, less)
+}
+
+func search(cmd *cobra.Command, args []string) {
+	flags := cmd.Flags()
+	jsonout, _ := flags.GetBool("json")
+	owner, _ := flags.GetString("owner")
+	public, _ := flags.GetBool("public")
+	private, _ := flags.GetBool("private")
+	sortkey, _ := flags.GetString("sort")
+	srvalias, _ := flags.GetString("server")
+
+	if public && private {
+		usageDie(cmd)
+	}
+	switch sortkey {
+	case "", "name", "created", "updated", "size":
+	default:
+		Die(fmt.Sprintf("invalid sort key '%s': must be one of 'name', 'created', 'updated', or 'size'", sortkey))
+	}
+
+	conf := config.Read()
+	if srvalias == "" {
+		srvalias = conf.DefaultServer
+	}
+	gincl := ginclient.New(srvalias)
+	requirelogin(cmd, gincl, !jsonout)
+
+	var ownerID int64
+	if owner != "" {
+		acc, err := gincl.RequestAccount(owner)
+		CheckError(err)
+		ownerID = acc.ID
+	}
+
+	query := strings.Join(args, " ")
+	results, err := gincl.SearchRepos(query, ownerID)
+	CheckError(err)
+
+	var repolist []gogs.Repository
+	for _, repo := range results {
+		if (public && repo.Private) || (private && !repo.Private) {
+			continue
+		}
+		repolist = append(repolist, repo)
+	}
+	sortRepos(repolist, sortkey)
+
+	if jsonout {
+		if len(repolist) > 0 {
+			j, _ := json.Marshal(repolist)
+			fmt.Println(string(j))
+		}
+		return
+	}
+	if len(repolist) == 0 {
+		fmt.Println("No repositories found")
+		return
+	}
+	printRepoList(repolist)
+}
+
+// SearchCmd sets up the 'search' subcommand
+func SearchCmd() *cobra.Command {
+	description := "Search the server for repositories whose name matches the query. Only repositories that are accessible to the logged in user are listed: public repositories, your own repositories, and repositories shared with you.\n\nBy default, results are listed in the order returned by the server."
+	args := map[string]string{
+		"<query>": "The search terms. Multiple words are searched as a single phrase.",
+	}
+	examples := map[string]string{
+		"Search for repositories with 'eeg' in their name":                       "$ gin search eeg",
+		"Search for public repositories of the organisation 'lab', newest first": "$ gin search --owner lab --public --sort created ephys",
+	}
+	var cmd = &cobra.Command{
+		Use:                   "search [--owner <name>] [--public | --private] [--sort <key>] [--json] <query>...",
+		Short:                 "Search for repositories on the server",
+		Long:                  formatdesc(description, args),
+		Example:               formatexamples(examples),
+		Args:                  cobra.MinimumNArgs(1),
+		Run:                   search,
+		DisableFlagsInUseLine: true,
+	}
+	cmd.Flags().String("owner", "", "Only list repositories owned by the user or organisation with the given `name`.")
+	cmd.Flags().Bool("public", false, "Only list public repositories. Cannot be used with --private.")
+	cmd.Flags().Bool("private", false, "Only list private repositories. Cannot be used with --public.")
+	cmd.Flags().String("sort", "", "Sort results by `key`: 'name' (alphabetical), 'created' or 'updated' (newest first), or 'size' (largest first).")
+	cmd.Flags().Bool("json", false, jsonHelpMsg)
+	cmd.Flags().String("server", "", "Specify server `alias` to search. See also 'gin servers'.")
+	return cmd
+}
@@ -39,7 +39,11 @@ func servers(cmd *cobra.Command, args []string) {
 			}
 			fmt.Println()
 			fmt.Printf("  web: %s\n", srvcfg.Web.AddressStr())
-			fmt.Printf("  git: %s\n\n", srvcfg.Git.AddressStr())
+			if srvcfg.HTTPSTransport() {
+				fmt.Printf("  git: %s (HTTPS transport)\n\n", srvcfg.RemoteAddressStr())
+			} else {
+				fmt.Printf("  git: %s\n\n", srvcfg.Git.AddressStr())
+			}
 		}
 	}
 }
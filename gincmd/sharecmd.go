@@ -0,0 +1,103 @@
+package gincmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	ginclient "github.com/G-Node/gin-cli/ginclient"
+	"github.com/G-Node/gin-cli/ginclient/config"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+// shareResult is the JSON output of the share and unshare commands.
+type shareResult struct {
+	Repository string `json:"repository"`
+	User       string `json:"user"`
+	Permission string `json:"permission"`
+}
+
+// permissionFromFlags returns the permission level selected by the --read, --write, and --admin flags.
+// If none of the flags is set, the default (write) is returned.
+func permissionFromFlags(cmd *cobra.Command) string {
+	flags := cmd.Flags()
+	read, _ := flags.GetBool("read")
+	write, _ := flags.GetBool("write")
+	admin, _ := flags.GetBool("admin")
+
+	nset := 0
+	permission := "write"
+	for perm, set := range map[string]bool{"read": read, "write": write, "admin": admin} {
+		if set {
+			nset++
+			permission = perm
+		}
+	}
+	if nset > 1 {
+		usageDie(cmd)
+	}
+	return permission
+}
+
+func share(cmd *cobra.Command, args []string) {
+	flags := cmd.Flags()
+	jsonout, _ := flags.GetBool("json")
+	srvalias, _ := flags.GetString("server")
+	permission := permissionFromFlags(cmd)
+
+	conf := config.Read()
+	if srvalias == "" {
+		srvalias = conf.DefaultServer
+	}
+	repostr, username := args[0], args[1]
+	if !isValidRepoPath(repostr) {
+		Die(fmt.Sprintf("Invalid repository path '%s'. Full repository name should be the owner's username followed by the repository name, separated by a '/'.", repostr))
+	}
+
+	gincl := ginclient.New(srvalias)
+	requirelogin(cmd, gincl, !jsonout)
+
+	if !jsonout {
+		fmt.Printf(":: Granting %s access to '%s' for user '%s' ", permission, repostr, username)
+	}
+	err := gincl.AddCollaborator(repostr, username, permission)
+	if !jsonout && err != nil {
+		fmt.Println()
+	}
+	CheckError(err)
+
+	if jsonout {
+		j, _ := json.Marshal(shareResult{Repository: repostr, User: username, Permission: permission})
+		fmt.Println(string(j))
+		return
+	}
+	fmt.Fprintln(color.Output, green("OK"))
+}
+
+// ShareCmd sets up the 'share' subcommand
+func ShareCmd() *cobra.Command {
+	description := "Give another user access to a repository on the GIN server. The user becomes a collaborator on the repository with the specified permission level. If the user is already a collaborator, their permission level is changed.\n\nOnly one of the permission flags can be specified. If none is specified, the user is given write access."
+	args := map[string]string{
+		"<repopath>": "The repository path must be specified on the command line. A repository path is the owner's username, followed by a \"/\" and the repository name.",
+		"<username>": "The name of the user who should be given access to the repository.",
+	}
+	examples := map[string]string{
+		"Give user 'bob' read-only access to the repository 'alice/example'": "$ gin share --read alice/example bob",
+		"Give user 'carol' full administrative access to 'alice/eegdata'":    "$ gin share --admin alice/eegdata carol",
+	}
+	var cmd = &cobra.Command{
+		Use:                   "share [--read | --write | --admin] [--json] <repopath> <username>",
+		Short:                 "Give another user access to a repository",
+		Long:                  formatdesc(description, args),
+		Example:               formatexamples(examples),
+		Args:                  cobra.ExactArgs(2),
+		Run:                   share,
+		DisableFlagsInUseLine: true,
+	}
+	cmd.Flags().Bool("read", false, "Give the user read-only access (clone and download).")
+	cmd.Flags().Bool("write", false, "Give the user write access (upload changes). This is the default.")
+	cmd.Flags().Bool("admin", false, "Give the user administrative access (write access and management of repository settings and collaborators).")
+	cmd.Flags().Bool("json", false, jsonHelpMsg)
+	cmd.Flags().String("server", "", "Specify server `alias` where the repository resides. See also 'gin servers'.")
+	return cmd
+}
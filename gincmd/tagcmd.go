@@ -0,0 +1,87 @@
+package gincmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/G-Node/gin-cli/gincmd/ginerrors"
+	"github.com/G-Node/gin-cli/git"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+func tag(cmd *cobra.Command, args []string) {
+	flags := cmd.Flags()
+	jsonout, _ := flags.GetBool("json")
+	message, _ := flags.GetString("message")
+
+	switch git.Checkwd() {
+	case git.NotRepository:
+		Die(ginerrors.NotInRepo)
+	case git.UpgradeRequired:
+		annexVersionNotice()
+	}
+
+	if len(args) == 0 {
+		if flags.Changed("message") {
+			usageDie(cmd)
+		}
+		tags, err := git.TagList()
+		CheckError(err)
+		if jsonout {
+			j, _ := json.Marshal(tags)
+			fmt.Println(string(j))
+			return
+		}
+		if len(tags) == 0 {
+			fmt.Println("No tags found")
+			return
+		}
+		for _, t := range tags {
+			hash := t.Hash
+			if len(hash) > 7 {
+				hash = hash[:7]
+			}
+			fmt.Fprintf(color.Output, "%s  %s  %s  %s\n", green(t.Name), hash, t.Date.Format("2006-01-02 15:04:05"), t.Message)
+		}
+		return
+	}
+
+	name := args[0]
+	err := git.TagCreate(name, message)
+	CheckError(err)
+	if jsonout {
+		j, _ := json.Marshal(struct {
+			Name    string `json:"name"`
+			Message string `json:"message"`
+		}{name, message})
+		fmt.Println(string(j))
+		return
+	}
+	fmt.Printf(":: Created tag '%s' for the current version\n", name)
+	fmt.Println("The tag will be uploaded with the next 'gin upload'")
+}
+
+// TagCmd sets up the 'tag' subcommand
+func TagCmd() *cobra.Command {
+	description := "Mark the current version of the repository with a name (tag), or list the existing tags if no name is given. Tags are uploaded with the next 'gin upload' (tags created with git directly are not uploaded) and can be used in place of version IDs, for instance with 'gin version --id' or 'gin get --tag'. To publish a tag as a release on the server, use 'gin release create'."
+	args := map[string]string{
+		"<name>": "The name of the new tag. Tag names cannot contain spaces.",
+	}
+	examples := map[string]string{
+		"Mark the current version as the one submitted to a journal": "$ gin tag -m \"Dataset as submitted to the journal\" submitted",
+		"List all tags": "$ gin tag",
+	}
+	var cmd = &cobra.Command{
+		Use:                   "tag [--json] [-m <message>] [<name>]",
+		Short:                 "Name the current version of the repository",
+		Long:                  formatdesc(description, args),
+		Example:               formatexamples(examples),
+		Args:                  cobra.MaximumNArgs(1),
+		Run:                   tag,
+		DisableFlagsInUseLine: true,
+	}
+	cmd.Flags().StringP("message", "m", "", "Description of the tagged version. If none is given, the tag name is used.")
+	cmd.Flags().Bool("json", false, jsonHelpMsg)
+	return cmd
+}
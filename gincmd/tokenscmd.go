@@ -0,0 +1,178 @@
+package gincmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	ginclient "github.com/G-Node/gin-cli/ginclient"
+	"github.com/G-Node/gin-cli/ginclient/config"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+// tokensClient returns the client for the server selected with the --server flag.
+func tokensClient(cmd *cobra.Command) *ginclient.Client {
+	srvalias, _ := cmd.Flags().GetString("server")
+	if srvalias == "" {
+		srvalias = config.Read().DefaultServer
+	}
+	return ginclient.New(srvalias)
+}
+
+// tokenCredentials prompts for the password (and the username, if not logged in) that the server requires for managing access tokens.
+// It returns the client for the server selected with the --server flag along with the credentials.
+func tokenCredentials(cmd *cobra.Command) (*ginclient.Client, string, string) {
+	gincl := tokensClient(cmd)
+	var args []string
+	if err := gincl.LoadToken(); err == nil && gincl.Username != "" {
+		args = []string{gincl.Username}
+		fmt.Fprintf(os.Stderr, ":: Managing tokens of %s on %s\n", gincl.Username, gincl.ServerAlias())
+	}
+	username, password := promptCredentials(args)
+	return gincl, username, password
+}
+
+// maskToken returns the first characters of a token, for identifying it without printing it in full.
+func maskToken(token string) string {
+	if len(token) <= 8 {
+		return token
+	}
+	return token[:8] + "..."
+}
+
+func listTokens(cmd *cobra.Command, args []string) {
+	jsonout, _ := cmd.Flags().GetBool("json")
+	gincl, username, password := tokenCredentials(cmd)
+	tokens, err := gincl.GetTokens(username, password)
+	CheckError(err)
+
+	type tokenInfo struct {
+		Name   string `json:"name"`
+		Prefix string `json:"prefix"`
+		// Current is true for the token used by this client
+		Current bool `json:"current"`
+	}
+	infos := make([]tokenInfo, len(tokens))
+	for idx, token := range tokens {
+		infos[idx] = tokenInfo{Name: token.Name, Prefix: maskToken(token.Sha1), Current: token.Sha1 != "" && token.Sha1 == gincl.Token}
+	}
+	if jsonout {
+		j, _ := json.Marshal(infos)
+		fmt.Println(string(j))
+		return
+	}
+	if len(infos) == 0 {
+		fmt.Println(":: No access tokens")
+		return
+	}
+	fmt.Printf(":: Access tokens of %s\n", username)
+	for _, info := range infos {
+		fmt.Printf("* %s", info.Name)
+		if info.Prefix != "" {
+			fmt.Printf(" (%s)", info.Prefix)
+		}
+		if info.Current {
+			fmt.Fprint(color.Output, green(" [used by this client]"))
+		}
+		fmt.Println()
+	}
+}
+
+func createToken(cmd *cobra.Command, args []string) {
+	name := args[0]
+	gincl, username, password := tokenCredentials(cmd)
+	tokens, err := gincl.GetTokens(username, password)
+	CheckError(err)
+	for _, token := range tokens {
+		if token.Name == name {
+			// tokens are revoked by name
+			Die(fmt.Sprintf("a token named '%s' already exists", name))
+		}
+	}
+	token, err := gincl.CreateToken(username, password, name)
+	CheckError(err)
+	fmt.Fprintf(color.Output, ":: Token '%s' created %s\n", name, green("OK"))
+	fmt.Println(":: The token is only shown once. It can be used with the GIN_TOKEN environment variable or 'gin login --token-stdin'.")
+	fmt.Println(token.Sha1)
+}
+
+// revokeToken points to the account settings on the web, since the server API has no endpoint for deleting access tokens.
+func revokeToken(cmd *cobra.Command, args []string) {
+	name := args[0]
+	gincl := tokensClient(cmd)
+	fmt.Printf(":: Access tokens can only be revoked on the web. Delete the token '%s' in the application settings of your account:\n", name)
+	fmt.Printf("   %s/user/settings/applications\n", gincl.WebAddress())
+	if name == "gin-cli" {
+		fmt.Println(":: All machines where you logged in with your password use this token and need to log in again with 'gin login'.")
+	}
+}
+
+func tokensListCmd() *cobra.Command {
+	description := "List the names of your access tokens on the server. Tokens are identified by their first characters; the token used by this client is marked."
+	var cmd = &cobra.Command{
+		Use:                   "list [--server <alias>] [--json]",
+		Short:                 "List your access tokens",
+		Long:                  formatdesc(description, nil),
+		Args:                  cobra.NoArgs,
+		Run:                   listTokens,
+		DisableFlagsInUseLine: true,
+	}
+	cmd.Flags().String("server", "", "Specify server `alias`. See also 'gin servers'.")
+	cmd.Flags().Bool("json", false, jsonHelpMsg)
+	return cmd
+}
+
+func tokensCreateCmd() *cobra.Command {
+	description := "Create a new access token with the given name and print it. The token is not stored by the client. Token names must be unique, since tokens are revoked by name.\n\nTokens grant the same access as your password; they cannot be restricted to specific repositories or operations. Create a separate token for each automated job or machine, so that it can be revoked independently."
+	args := map[string]string{
+		"<name>": "The name of the new token.",
+	}
+	examples := map[string]string{
+		"Create a token for a CI job": "$ gin tokens create ci-analysis",
+	}
+	var cmd = &cobra.Command{
+		Use:                   "create [--server <alias>] <name>",
+		Short:                 "Create a named access token",
+		Long:                  formatdesc(description, args),
+		Example:               formatexamples(examples),
+		Args:                  cobra.ExactArgs(1),
+		Run:                   createToken,
+		DisableFlagsInUseLine: true,
+	}
+	cmd.Flags().String("server", "", "Specify server `alias`. See also 'gin servers'.")
+	return cmd
+}
+
+func tokensRevokeCmd() *cobra.Command {
+	description := "Show where to revoke the access token with the given name. The server does not provide a way to revoke tokens other than the application settings of your account on the web, so this command prints the address of that page. Commands that use a revoked token are no longer authorised.\n\nThe client logs in with a token named 'gin-cli', which is shared by all machines where you logged in with your password. Revoking it logs out all these machines; their session keys remain registered until they are deleted with 'gin keys --delete'."
+	args := map[string]string{
+		"<name>": "The name of the token to revoke (see 'gin tokens list').",
+	}
+	var cmd = &cobra.Command{
+		Use:                   "revoke [--server <alias>] <name>",
+		Short:                 "Show where to revoke an access token",
+		Long:                  formatdesc(description, args),
+		Args:                  cobra.ExactArgs(1),
+		Run:                   revokeToken,
+		DisableFlagsInUseLine: true,
+	}
+	cmd.Flags().String("server", "", "Specify server `alias`. See also 'gin servers'.")
+	return cmd
+}
+
+// TokensCmd sets up the 'tokens' command and its subcommands for managing the access tokens of the user's account
+func TokensCmd() *cobra.Command {
+	description := "List and create the access tokens of your account, and show where to revoke them. Tokens are used for logging in without a password, for example in automated jobs (see 'gin login --token-stdin' and the GIN_TOKEN environment variable).\n\nThe server requires your password for managing tokens, so the list and create commands prompt for it. If you are logged in, the username of the login is used; otherwise it is prompted for as well."
+	var cmd = &cobra.Command{
+		Use:                   "tokens <command>",
+		Short:                 "Manage the access tokens of your account",
+		Long:                  formatdesc(description, nil),
+		Args:                  cobra.NoArgs,
+		DisableFlagsInUseLine: true,
+	}
+	cmd.AddCommand(tokensListCmd())
+	cmd.AddCommand(tokensCreateCmd())
+	cmd.AddCommand(tokensRevokeCmd())
+	return cmd
+}
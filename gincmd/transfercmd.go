@@ -0,0 +1,79 @@
+package gincmd
+
+import (
+	"fmt"
+
+	ginclient "github.com/G-Node/gin-cli/ginclient"
+	"github.com/G-Node/gin-cli/ginclient/config"
+	"github.com/G-Node/gin-cli/ginclient/log"
+	"github.com/spf13/cobra"
+)
+
+func transfer(cmd *cobra.Command, args []string) {
+	flags := cmd.Flags()
+	srvalias, _ := flags.GetString("server")
+
+	conf := config.Read()
+	if srvalias == "" {
+		srvalias = conf.DefaultServer
+	}
+	repostr, newowner := args[0], args[1]
+	if !isValidRepoPath(repostr) {
+		Die(fmt.Sprintf("Invalid repository path '%s'. Full repository name should be the owner's username followed by the repository name, separated by a '/'.", repostr))
+	}
+
+	gincl := ginclient.New(srvalias)
+	requirelogin(cmd, gincl, true)
+
+	repoinfo, err := gincl.GetRepo(repostr)
+	CheckError(err)
+
+	if repoinfo.FullName != repostr {
+		log.Write("ERROR: Mismatch in repository names: %s != %s", repoinfo.FullName, repostr)
+		Die("An unexpected error occurred while communicating with the server.")
+	}
+
+	fmt.Println("--- WARNING ---")
+	fmt.Printf("You are about to transfer the repository '%s' to '%s'.\n", repostr, newowner)
+	fmt.Println("You may lose access to the repository, depending on your permissions for the new owner.")
+	fmt.Println("If you are sure you want to transfer this repository, type its full name (owner/name) below")
+	fmt.Print("> ")
+	var confirmation string
+	fmt.Scanln(&confirmation)
+	if confirmation != repostr {
+		Die("Confirmation does not match repository name. Cancelling.")
+	}
+
+	newinfo, err := gincl.TransferRepo(repostr, newowner)
+	CheckError(err)
+
+	newpath := newinfo.FullName
+	if newpath == "" {
+		newpath = fmt.Sprintf("%s/%s", newowner, repoinfo.Name)
+	}
+	fmt.Printf("Repository %s has been transferred to %s\n", repostr, newpath)
+	updateRemoteURLs(repostr, newpath, false)
+}
+
+// TransferCmd sets up the 'transfer' repository subcommand
+func TransferCmd() *cobra.Command {
+	description := "Transfer the ownership of a repository to another user or an organisation. Transferring a repository requires administrative access to it and the right to create repositories for the new owner. Any remotes of the repository in the current working directory are updated to the new location."
+	args := map[string]string{
+		"<repopath>":  "The path of the repository to transfer. A repository path is the owner's username, followed by a \"/\" and the repository name.",
+		"<new-owner>": "The name of the user or organisation that will own the repository.",
+	}
+	examples := map[string]string{
+		"Transfer the repository 'alice/dataset' to the organisation 'lab'": "$ gin transfer alice/dataset lab",
+	}
+	var cmd = &cobra.Command{
+		Use:                   "transfer <repopath> <new-owner>",
+		Short:                 "Transfer a repository to a new owner",
+		Long:                  formatdesc(description, args),
+		Example:               formatexamples(examples),
+		Args:                  cobra.ExactArgs(2),
+		Run:                   transfer,
+		DisableFlagsInUseLine: true,
+	}
+	cmd.Flags().String("server", "", "Specify server `alias` where the repository resides. See also 'gin servers'.")
+	return cmd
+}
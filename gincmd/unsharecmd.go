@@ -0,0 +1,69 @@
+package gincmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	ginclient "github.com/G-Node/gin-cli/ginclient"
+	"github.com/G-Node/gin-cli/ginclient/config"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+func unshare(cmd *cobra.Command, args []string) {
+	flags := cmd.Flags()
+	jsonout, _ := flags.GetBool("json")
+	srvalias, _ := flags.GetString("server")
+
+	conf := config.Read()
+	if srvalias == "" {
+		srvalias = conf.DefaultServer
+	}
+	repostr, username := args[0], args[1]
+	if !isValidRepoPath(repostr) {
+		Die(fmt.Sprintf("Invalid repository path '%s'. Full repository name should be the owner's username followed by the repository name, separated by a '/'.", repostr))
+	}
+
+	gincl := ginclient.New(srvalias)
+	requirelogin(cmd, gincl, !jsonout)
+
+	if !jsonout {
+		fmt.Printf(":: Revoking access to '%s' for user '%s' ", repostr, username)
+	}
+	err := gincl.RemoveCollaborator(repostr, username)
+	if !jsonout && err != nil {
+		fmt.Println()
+	}
+	CheckError(err)
+
+	if jsonout {
+		j, _ := json.Marshal(shareResult{Repository: repostr, User: username, Permission: "none"})
+		fmt.Println(string(j))
+		return
+	}
+	fmt.Fprintln(color.Output, green("OK"))
+}
+
+// UnshareCmd sets up the 'unshare' subcommand
+func UnshareCmd() *cobra.Command {
+	description := "Revoke a user's access to a repository on the GIN server. The user is removed from the repository's collaborators. Note that this does not affect access the user may have through an organisation team or a public repository."
+	args := map[string]string{
+		"<repopath>": "The repository path must be specified on the command line. A repository path is the owner's username, followed by a \"/\" and the repository name.",
+		"<username>": "The name of the user whose access should be revoked.",
+	}
+	examples := map[string]string{
+		"Remove user 'bob' from the collaborators of 'alice/example'": "$ gin unshare alice/example bob",
+	}
+	var cmd = &cobra.Command{
+		Use:                   "unshare [--json] <repopath> <username>",
+		Short:                 "Revoke a user's access to a repository",
+		Long:                  formatdesc(description, args),
+		Example:               formatexamples(examples),
+		Args:                  cobra.ExactArgs(2),
+		Run:                   unshare,
+		DisableFlagsInUseLine: true,
+	}
+	cmd.Flags().Bool("json", false, jsonHelpMsg)
+	cmd.Flags().String("server", "", "Specify server `alias` where the repository resides. See also 'gin servers'.")
+	return cmd
+}
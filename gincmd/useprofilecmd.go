@@ -0,0 +1,116 @@
+package gincmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	ginclient "github.com/G-Node/gin-cli/ginclient"
+	"github.com/G-Node/gin-cli/ginclient/config"
+	"github.com/G-Node/gin-cli/gincmd/ginerrors"
+	"github.com/G-Node/gin-cli/git"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+type profileInfo struct {
+	Name   string   `json:"name"`
+	Active bool     `json:"active"`
+	Logins []string `json:"logins"`
+}
+
+func useProfile(cmd *cobra.Command, args []string) {
+	flags := cmd.Flags()
+	unset, _ := flags.GetBool("unset")
+	jsonout, _ := flags.GetBool("json")
+	if unset && len(args) > 0 {
+		usageDie(cmd)
+	}
+
+	if unset || len(args) > 0 {
+		if git.Checkwd() == git.NotRepository {
+			Die(ginerrors.NotInRepo)
+		}
+		if unset {
+			CheckErrorMsg(ginclient.UnsetRepoProfile(), "the repository is not bound to a profile")
+			config.SetRepoProfile("")
+			fmt.Println(":: Repository profile binding removed")
+		} else {
+			name := args[0]
+			CheckError(ginclient.SetRepoProfile(name))
+			CheckError(config.SetRepoProfile(name))
+			fmt.Fprintf(color.Output, ":: Repository bound to profile %s\n", cyan(name))
+			if logins, _ := ginclient.ProfileLogins(name); len(logins) == 0 {
+				fmt.Printf("   The profile has no logins yet. Use 'gin login --profile %s' to log in.\n", name)
+			}
+		}
+	}
+
+	active, source := config.ActiveProfile()
+	profiles, err := config.ListProfiles()
+	CheckError(err)
+	infos := make([]profileInfo, len(profiles))
+	for idx, name := range profiles {
+		logins, err := ginclient.ProfileLogins(name)
+		CheckError(err)
+		if logins == nil {
+			logins = []string{}
+		}
+		infos[idx] = profileInfo{Name: name, Active: name == active, Logins: logins}
+	}
+	if jsonout {
+		j, _ := json.Marshal(struct {
+			Active   string        `json:"active"`
+			Source   string        `json:"source"`
+			Profiles []profileInfo `json:"profiles"`
+		}{active, source, infos})
+		fmt.Println(string(j))
+		return
+	}
+	if unset || len(args) > 0 {
+		// the new binding only applies if no flag or environment variable selects a profile
+		if source == "--profile" || source == "GIN_PROFILE" {
+			fmt.Printf("   Note: profile '%s' is currently selected by %s\n", active, source)
+		}
+		return
+	}
+	fmt.Fprintf(color.Output, ":: Active profile: %s (%s)\n", cyan(active), source)
+	fmt.Println(":: Profiles")
+	for _, info := range infos {
+		fmt.Printf("* %s", info.Name)
+		if info.Active {
+			fmt.Fprint(color.Output, green(" [active]"))
+		}
+		fmt.Println()
+		if len(info.Logins) == 0 {
+			fmt.Println("  not logged in")
+		} else {
+			fmt.Printf("  logged into: %s\n", strings.Join(info.Logins, ", "))
+		}
+	}
+}
+
+// UseProfileCmd sets up the 'use-profile' subcommand
+func UseProfileCmd() *cobra.Command {
+	description := `Bind the current repository to a profile, or show the active profile and all profiles.
+
+Profiles hold separate login credentials (tokens and keys), so that one machine can be logged into the same server with different accounts (e.g., a personal account and an account for automated jobs). Log into a profile with 'gin login --profile <name>'; the profile is created on the first login.
+
+The profile used by a command is selected by, in decreasing order of precedence, the global --profile flag, the GIN_PROFILE environment variable, the profile bound to the repository, or the default profile.
+
+With no arguments, this command prints the active profile and the servers each profile is logged into.`
+	args := map[string]string{
+		"<name>": "The profile to use for all commands run in the current repository. Use 'default' to use the default profile regardless of other bindings.",
+	}
+	var cmd = &cobra.Command{
+		Use:                   "use-profile [--json] [--unset | <name>]",
+		Short:                 "Select the login profile for a repository",
+		Long:                  formatdesc(description, args),
+		Args:                  cobra.MaximumNArgs(1),
+		Run:                   useProfile,
+		DisableFlagsInUseLine: true,
+	}
+	cmd.Flags().Bool("unset", false, "Remove the profile binding of the current repository.")
+	cmd.Flags().Bool("json", false, jsonHelpMsg)
+	return cmd
+}
@@ -22,7 +22,7 @@ func UseServerCmd() *cobra.Command {
 	description := `Set the default GIN server for user and repository management commands.
 
 The following commands are affected by this setting:
-create, info, keys, login, logout, repoinfo, repos
+create, info, keys, login, logout, repoinfo, repos, tokens, whoami
 
 This setting can be overridden in each command by using the --server flag.
 
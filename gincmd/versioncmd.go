@@ -151,6 +151,7 @@ func VersionCmd() *cobra.Command {
 	examples := map[string]string{
 		"Show the 50 most recent versions of recordings.nix and prompt for version":                                                "$ gin version -n 50 recordings.nix",
 		"Return the files in the code/ directory to the version with ID 429d51e":                                                   "$ gin version --id 429d51e code/",
+		"Return all files to the version tagged 'submitted'":                                                                       "$ gin version --id submitted",
 		"Retrieve all files from the code/ directory from version with ID 918a06f and copy it to a directory called oldcode/":      "$ gin version --id 918a06f --copy-to oldcode code",
 		"Show the 15 most recent versions of data.zip, prompt for version, and copy the selected version to the current directory": "$ gin version -n 15 --copy-to . data.zip",
 	}
@@ -165,7 +166,7 @@ func VersionCmd() *cobra.Command {
 	}
 	cmd.Flags().Bool("json", false, jsonHelpMsg)
 	cmd.Flags().UintP("max-count", "n", 10, "Maximum `number` of versions to display before prompting. 0 means 'all'.")
-	cmd.Flags().String("id", "", "Commit `ID` (hash) to return to.")
+	cmd.Flags().String("id", "", "Commit `ID` (hash) or tag name to return to. See also 'gin tag'.")
 	cmd.Flags().String("copy-to", "", "Retrieve files from history and copy them to a new `location` instead of overwriting the existing ones. The new files will be placed in the directory specified and will be renamed to include the date and time of their version.")
 	return cmd
 }
@@ -0,0 +1,124 @@
+package gincmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	ginclient "github.com/G-Node/gin-cli/ginclient"
+	"github.com/G-Node/gin-cli/gincmd/ginerrors"
+	"github.com/G-Node/gin-cli/git"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+// hasLocation returns true if the content of the file is in the location with the given name.
+func hasLocation(floc ginclient.FileLocations, name string) bool {
+	for _, loc := range floc.Locations {
+		if loc.Remote == name {
+			return true
+		}
+	}
+	return false
+}
+
+func whereis(cmd *cobra.Command, args []string) {
+	flags := cmd.Flags()
+	jsonout, _ := flags.GetBool("json")
+	missingfrom, _ := flags.GetString("missing-from")
+
+	switch git.Checkwd() {
+	case git.NotRepository:
+		Die(ginerrors.NotInRepo)
+	case git.NotAnnex:
+		Warn(ginerrors.MissingAnnex)
+	case git.UpgradeRequired:
+		annexVersionNotice()
+	}
+
+	if missingfrom != "" && missingfrom != "here" && missingfrom != "web" {
+		remotes, err := git.RemoteShow()
+		CheckError(err)
+		if _, ok := remotes[missingfrom]; !ok {
+			Die(fmt.Sprintf("unknown remote '%s'", missingfrom))
+		}
+	}
+
+	files, err := ginclient.Whereis(args...)
+	CheckError(err)
+	if missingfrom != "" {
+		var missing []ginclient.FileLocations
+		for _, floc := range files {
+			if !hasLocation(floc, missingfrom) {
+				missing = append(missing, floc)
+			}
+		}
+		files = missing
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].File < files[j].File })
+
+	if jsonout {
+		if files == nil {
+			files = []ginclient.FileLocations{}
+		}
+		j, err := json.Marshal(files)
+		CheckError(err)
+		fmt.Println(string(j))
+		return
+	}
+
+	for _, floc := range files {
+		ncopies := len(floc.Locations)
+		var copies string
+		switch ncopies {
+		case 0:
+			copies = red("no copies")
+		case 1:
+			copies = yellow("1 copy")
+		default:
+			copies = green(fmt.Sprintf("%d copies", ncopies))
+		}
+		fmt.Fprintf(color.Output, "%s (%s)\n", floc.File, copies)
+		for _, loc := range floc.Locations {
+			name := loc.Remote
+			if name == "" {
+				name = loc.UUID
+			}
+			trust := loc.Trust
+			if trust != "semitrusted" {
+				// only point out trust levels that differ from the default
+				trust = yellow(trust)
+			}
+			fmt.Fprintf(color.Output, "  %-12s [%s] %s\n", name, trust, loc.Description)
+			for _, u := range loc.URLs {
+				fmt.Printf("  %-12s %s\n", "", u)
+			}
+		}
+	}
+	if missingfrom != "" && len(files) == 0 {
+		fmt.Printf("The content of all files is available from '%s'.\n", missingfrom)
+	}
+}
+
+// WhereisCmd sets up the 'whereis' subcommand
+func WhereisCmd() *cobra.Command {
+	description := "Show where the content of annexed files is stored. For each file, the repositories that hold a copy of its content are listed by the name of the git remote, or 'here' for the local repository and 'web' for web addresses (see 'gin addurl'). Each repository is shown with its description and its trust level (trusted, semitrusted, or untrusted). The web addresses of files added from URLs are listed below the location.\n\nWith --missing-from, only files whose content is not stored in the given remote are listed. This can be used to check which files still need to be uploaded."
+	args := map[string]string{
+		"<filenames>": "One or more directories or files to show. If none are specified, all annexed files under the current directory are shown.",
+	}
+	examples := map[string]string{
+		"List the files whose content has not been uploaded to the default GIN remote": "$ gin whereis --missing-from origin",
+	}
+	var cmd = &cobra.Command{
+		Use:                   "whereis [--missing-from <remote>] [--json] [<filenames>]...",
+		Short:                 "Show where the content of files is stored",
+		Long:                  formatdesc(description, args),
+		Example:               formatexamples(examples),
+		Args:                  cobra.ArbitraryArgs,
+		Run:                   whereis,
+		DisableFlagsInUseLine: true,
+	}
+	cmd.Flags().String("missing-from", "", "Only list files whose content is not stored in the `remote`.")
+	cmd.Flags().Bool("json", false, jsonHelpMsg)
+	return cmd
+}
@@ -0,0 +1,59 @@
+package gincmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	ginclient "github.com/G-Node/gin-cli/ginclient"
+	"github.com/G-Node/gin-cli/ginclient/config"
+	"github.com/spf13/cobra"
+)
+
+func whoami(cmd *cobra.Command, args []string) {
+	flags := cmd.Flags()
+	jsonout, _ := flags.GetBool("json")
+	srvalias, _ := flags.GetString("server")
+
+	conf := config.Read()
+	if srvalias == "" {
+		srvalias = conf.DefaultServer
+	}
+	if _, ok := conf.Servers[srvalias]; !ok {
+		Die(fmt.Sprintf("unknown server alias '%s' (see 'gin servers')", srvalias))
+	}
+	gincl := ginclient.New(srvalias)
+	requirelogin(cmd, gincl, true)
+	if gincl.Username == "" {
+		Die(fmt.Sprintf("not logged into server '%s' (see 'gin login')", srvalias))
+	}
+	profile, _ := config.ActiveProfile()
+
+	if jsonout {
+		j, _ := json.Marshal(struct {
+			Username string `json:"username"`
+			Server   string `json:"server"`
+			Address  string `json:"address"`
+			Profile  string `json:"profile"`
+			EnvToken bool   `json:"env_token"`
+		}{gincl.Username, srvalias, gincl.WebAddress(), profile, gincl.UsesEnvToken()})
+		fmt.Println(string(j))
+		return
+	}
+	fmt.Printf("%s (server %s [%s], profile %s)\n", gincl.Username, srvalias, gincl.WebAddress(), profile)
+}
+
+// WhoamiCmd sets up the 'whoami' subcommand
+func WhoamiCmd() *cobra.Command {
+	description := fmt.Sprintf("Print the name of the user logged into the default server, or the server specified with --server, with the active profile. The login token is checked with the server. The command fails if no user is logged in or the token is no longer valid.\n\nWith the %s environment variable set, the user of that token is printed for the default server.\n\nUse 'gin auth status' to show the login status on all configured servers.", ginclient.TokenEnvVar)
+	var cmd = &cobra.Command{
+		Use:                   "whoami [--server <alias>] [--json]",
+		Short:                 "Print the name of the logged in user",
+		Long:                  formatdesc(description, nil),
+		Args:                  cobra.NoArgs,
+		Run:                   whoami,
+		DisableFlagsInUseLine: true,
+	}
+	cmd.Flags().String("server", "", "Specify server `alias`. See also 'gin servers'.")
+	cmd.Flags().Bool("json", false, jsonHelpMsg)
+	return cmd
+}
@@ -3,7 +3,9 @@ package git
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
@@ -87,6 +89,28 @@ type AnnexWhereisRes struct {
 	Err error `json:"err"`
 }
 
+// WebUUID is the UUID git-annex uses for the web special remote, which holds the URLs of content added by URL.
+const WebUUID = "00000000-0000-0000-0000-000000000001"
+
+// URLs returns all the URLs from which the content of the file can be downloaded.
+func (wi AnnexWhereisRes) URLs() []string {
+	var urls []string
+	for _, loc := range wi.Whereis {
+		urls = append(urls, loc.URLs...)
+	}
+	return urls
+}
+
+// AnnexMetadataRes holds the metadata of an annexed file as returned by a "git annex metadata" command
+type AnnexMetadataRes struct {
+	File    string              `json:"file"`
+	Key     string              `json:"key"`
+	Success bool                `json:"success"`
+	Fields  map[string][]string `json:"fields"`
+	Errors  []string            `json:"error-messages"`
+	Err     error               `json:"err"`
+}
+
 // AnnexStatusRes for getting the (annex) status of individual files
 type AnnexStatusRes struct {
 	Status string `json:"status"`
@@ -94,26 +118,29 @@ type AnnexStatusRes struct {
 	Err    error  `json:"err"`
 }
 
+// AnnexRepository identifies a repository in the annex information returned by AnnexInfo.
+type AnnexRepository struct {
+	Description string `json:"description"`
+	Here        bool   `json:"here"`
+	UUID        string `json:"uuid"`
+}
+
 // AnnexInfoRes holds the information returned by AnnexInfo
 type AnnexInfoRes struct {
-	TransfersInProgress             []interface{} `json:"transfers in progress"`
-	LocalAnnexKeys                  int           `json:"local annex keys"`
-	AvailableLocalDiskSpace         string        `json:"available local disk space"`
-	AnnexedFilesInWorkingTree       int           `json:"annexed files in working tree"`
-	File                            interface{}   `json:"file"`
-	TrustedRepositories             []interface{} `json:"trusted repositories"`
-	SizeOfAnnexedFilesInWorkingTree string        `json:"size of annexed files in working tree"`
-	LocalAnnexSize                  string        `json:"local annex size"`
-	Command                         string        `json:"command"`
-	UntrustedRepositories           []interface{} `json:"untrusted repositories"`
-	SemitrustedRepositories         []struct {
-		Description string `json:"description"`
-		Here        bool   `json:"here"`
-		UUID        string `json:"uuid"`
-	} `json:"semitrusted repositories"`
-	Success         bool   `json:"success"`
-	BloomFilterSize string `json:"bloom filter size"`
-	BackendUsage    struct {
+	TransfersInProgress             []interface{}     `json:"transfers in progress"`
+	LocalAnnexKeys                  int               `json:"local annex keys"`
+	AvailableLocalDiskSpace         string            `json:"available local disk space"`
+	AnnexedFilesInWorkingTree       int               `json:"annexed files in working tree"`
+	File                            interface{}       `json:"file"`
+	TrustedRepositories             []AnnexRepository `json:"trusted repositories"`
+	SizeOfAnnexedFilesInWorkingTree string            `json:"size of annexed files in working tree"`
+	LocalAnnexSize                  string            `json:"local annex size"`
+	Command                         string            `json:"command"`
+	UntrustedRepositories           []AnnexRepository `json:"untrusted repositories"`
+	SemitrustedRepositories         []AnnexRepository `json:"semitrusted repositories"`
+	Success                         bool              `json:"success"`
+	BloomFilterSize                 string            `json:"bloom filter size"`
+	BackendUsage                    struct {
 		SHA256E int `json:"SHA256E"`
 		WORM    int `json:"WORM"`
 	} `json:"backend usage"`
@@ -597,6 +624,7 @@ func AnnexWhereis(paths []string, wichan chan<- AnnexWhereisRes) {
 			// Empty line output. Ignore
 			continue
 		}
+		info = AnnexWhereisRes{} // clear existing result
 		jsonerr := json.Unmarshal([]byte(line), &info)
 		info.Err = jsonerr
 		wichan <- info
@@ -604,6 +632,85 @@ func AnnexWhereis(paths []string, wichan chan<- AnnexWhereisRes) {
 	return
 }
 
+// AnnexMetadata changes and returns the metadata of annexed files.
+// Each element of setexprs is a metadata change expression ("field=value" sets a value, "field+=value" adds a value, "field-=value" removes a value).
+// All values of each field in removefields are removed.
+// If no changes are specified, the metadata is returned unchanged.
+// The output channel 'metachan' is closed when this function returns.
+// (git annex metadata)
+func AnnexMetadata(paths, setexprs, removefields []string, metachan chan<- AnnexMetadataRes) {
+	defer close(metachan)
+	cmdargs := []string{"metadata", "--json", "--json-error-messages"}
+	for _, expr := range setexprs {
+		cmdargs = append(cmdargs, fmt.Sprintf("--set=%s", expr))
+	}
+	for _, field := range removefields {
+		cmdargs = append(cmdargs, fmt.Sprintf("--remove=%s", field))
+	}
+	cmdargs = append(cmdargs, paths...)
+	cmd := AnnexCommand(cmdargs...)
+	err := cmd.Start()
+	if err != nil {
+		log.Write("Error during AnnexMetadata")
+		metachan <- AnnexMetadataRes{Err: fmt.Errorf("Failed to run git-annex metadata: %s", err)}
+		return
+	}
+
+	var line string
+	var rerr error
+	for rerr = nil; rerr == nil; line, rerr = cmd.OutReader.ReadString('\n') {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 {
+			// Empty line output. Ignore
+			continue
+		}
+		var meta AnnexMetadataRes
+		if jsonerr := json.Unmarshal([]byte(line), &meta); jsonerr != nil {
+			meta.Err = jsonerr
+		} else if !meta.Success {
+			meta.Err = fmt.Errorf("failed: %s", strings.Join(meta.Errors, "; "))
+		}
+		metachan <- meta
+	}
+	if cmd.Wait() != nil {
+		var stderr, errline []byte
+		for rerr = nil; rerr == nil; errline, rerr = cmd.ErrReader.ReadBytes('\000') {
+			stderr = append(stderr, errline...)
+		}
+		log.Write("Error during AnnexMetadata")
+		logstd(nil, stderr)
+	}
+}
+
+// AnnexView switches to a view branch that contains only the files that match the metadata expressions ("field=glob"), in directories named after their metadata values.
+// (git annex view)
+func AnnexView(exprs []string) error {
+	fn := fmt.Sprintf("AnnexView(%s)", strings.Join(exprs, ", "))
+	cmdargs := append([]string{"view"}, exprs...)
+	cmd := AnnexCommand(cmdargs...)
+	stdout, stderr, err := cmd.OutputError()
+	if err != nil {
+		log.Write("Error during AnnexView")
+		logstd(stdout, stderr)
+		return giterror{Origin: fn, UError: string(stderr)}
+	}
+	return nil
+}
+
+// AnnexVPop leaves the current view branch and switches back to the previous view or the original branch.
+// (git annex vpop)
+func AnnexVPop() error {
+	fn := "AnnexVPop()"
+	cmd := AnnexCommand("vpop")
+	stdout, stderr, err := cmd.OutputError()
+	if err != nil {
+		log.Write("Error during AnnexVPop")
+		logstd(stdout, stderr)
+		return giterror{Origin: fn, UError: string(stderr)}
+	}
+	return nil
+}
+
 // AnnexStatus returns the status of a file or files in a directory
 // The output channel 'statuschan' is closed when this function returns.
 // (git annex status)
@@ -666,6 +773,53 @@ func AnnexInfo() (AnnexInfoRes, error) {
 	return info, err
 }
 
+// AnnexTrustLevels returns the trust level ("trusted", "semitrusted", or "untrusted") of each repository known to the annex, by UUID.
+func AnnexTrustLevels() (map[string]string, error) {
+	info, err := AnnexInfo()
+	if err != nil {
+		return nil, err
+	}
+	levels := make(map[string]string)
+	for level, repos := range map[string][]AnnexRepository{
+		"trusted":     info.TrustedRepositories,
+		"semitrusted": info.SemitrustedRepositories,
+		"untrusted":   info.UntrustedRepositories,
+	} {
+		for _, repo := range repos {
+			levels[repo.UUID] = level
+		}
+	}
+	return levels, nil
+}
+
+// AnnexRemoteUUIDs returns the names of the git remotes, by the annex UUID of the repository they refer to.
+// Remotes without an annex UUID (e.g., remotes that have not been synchronised yet) are not included.
+// (git config --get-regexp remote.*.annex-uuid)
+func AnnexRemoteUUIDs() (map[string]string, error) {
+	fn := "AnnexRemoteUUIDs()"
+	cmd := Command("config", "--get-regexp", `^remote\..*\.annex-uuid$`)
+	stdout, stderr, err := cmd.OutputError()
+	remotes := make(map[string]string)
+	if err != nil {
+		if len(stdout) == 0 && len(stderr) == 0 {
+			// no matches
+			return remotes, nil
+		}
+		log.Write("Error during config get-regexp")
+		logstd(stdout, stderr)
+		return nil, giterror{UError: string(stderr), Origin: fn}
+	}
+	for _, line := range strings.Split(string(stdout), "\n") {
+		parts := strings.Fields(line)
+		if len(parts) != 2 {
+			continue
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(parts[0], "remote."), ".annex-uuid")
+		remotes[parts[1]] = name
+	}
+	return remotes, nil
+}
+
 // AnnexLock locks the specified files and directory contents if they are annexed.
 // If an unlocked file has modifications, it wont be locked and an error will be returned for that file.
 // The status channel 'lockchan' is closed when this function returns.
@@ -851,6 +1005,42 @@ func AnnexFind(paths []string) (map[string]AnnexFindRes, error) {
 	return items, nil
 }
 
+// AnnexFindMatching returns the annexed files specified by paths that match all the given annex matching options (e.g., "--largerthan=1G" or "--metadata=subject=s01").
+// Unlike AnnexFind, files are listed whether their content is available locally or not, unless a matching option specifies otherwise.
+// (git annex find)
+func AnnexFindMatching(paths, matching []string) ([]AnnexFindRes, error) {
+	cmdargs := []string{"find", "--json"}
+	if len(matching) == 0 {
+		// without matching options, only files with local content are listed
+		matching = []string{"--include=*"}
+	}
+	cmdargs = append(cmdargs, matching...)
+	cmdargs = append(cmdargs, paths...)
+	cmd := AnnexCommand(cmdargs...)
+	stdout, stderr, err := cmd.OutputError()
+	if err != nil {
+		logstd(stdout, stderr)
+		return nil, fmt.Errorf(string(stderr))
+	}
+
+	var items []AnnexFindRes
+	for _, line := range bytes.Split(stdout, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			// Empty line output. Ignore
+			continue
+		}
+		var afr AnnexFindRes
+		if err := json.Unmarshal(line, &afr); err != nil {
+			log.Write("Could not parse 'git annex find' output")
+			log.Write(string(line))
+			continue
+		}
+		items = append(items, afr)
+	}
+	return items, nil
+}
+
 // AnnexFromKey creates an Annex placeholder file at a given location with a specific key.
 // The creation is forced, so there is no guarantee that the key refers to valid repository content, nor that the content is still available in any of the remotes.
 // The location where the file is to be created must be available (no directories are created).
@@ -898,10 +1088,39 @@ func AnnexFsck(paths []string) error {
 	return nil
 }
 
+// Lines that enclose the annex.largefiles rules written by 'gin rules' in the .gitattributes file in the root of the repository.
+const (
+	AnnexRulesBlockStart = "# gin annex rules (managed with 'gin rules'; later rules take precedence)"
+	AnnexRulesBlockEnd   = "# end of gin annex rules"
+)
+
+// hasLargefilesAttributes returns true if the .gitattributes file in the root of the repository contains the annex rules block written by 'gin rules'.
+// Other annex.largefiles attributes are not considered, since they may only apply to a few paths.
+func hasLargefilesAttributes() bool {
+	reporoot, err := FindRepoRoot(".")
+	if err != nil {
+		return false
+	}
+	data, err := ioutil.ReadFile(filepath.Join(reporoot, ".gitattributes"))
+	if err != nil {
+		return false
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line == AnnexRulesBlockStart {
+			return true
+		}
+	}
+	return false
+}
+
 // build exclusion argument list
 // files < annex.minsize or matching exclusion extensions will not be annexed and
 // will instead be handled by git
+// If the repository defines its own rules with 'gin rules', no argument is returned, since it would override them.
 func annexExclArgs() string {
+	if hasLargefilesAttributes() {
+		return ""
+	}
 	var expbuilder strings.Builder
 	config := config.Read()
 	if config.Annex.MinSize != "" {
@@ -1030,6 +1249,87 @@ func setAnnexMetadataName(path string) {
 	return
 }
 
+// AnnexAddURL registers the content found at each URL in the annex under the file path at the same position in filepaths.
+// The mode controls how content is handled:
+// "fast" checks that each URL exists but does not download the content,
+// "relaxed" does not access the URLs at all,
+// any other value downloads the content.
+// The status channel 'addchan' is closed when this function returns.
+// (git annex addurl)
+func AnnexAddURL(urls, filepaths []string, mode string, addchan chan<- RepoFileStatus) {
+	defer close(addchan)
+	if len(urls) == 0 {
+		log.Write("No URLs to add to annex. Nothing to do.")
+		return
+	}
+	if len(urls) != len(filepaths) {
+		addchan <- RepoFileStatus{Err: fmt.Errorf("number of URLs and file paths do not match")}
+		return
+	}
+	cmdargs := []string{"addurl", "--batch", "--with-files", "--json", "--json-error-messages"}
+	switch mode {
+	case "fast":
+		cmdargs = append(cmdargs, "--fast")
+	case "relaxed":
+		cmdargs = append(cmdargs, "--relaxed")
+	}
+	var input strings.Builder
+	for idx, url := range urls {
+		// one "url filename" pair per line
+		fmt.Fprintf(&input, "%s %s\n", url, filepaths[idx])
+	}
+	cmd := AnnexCommand(cmdargs...)
+	cmd.Stdin = strings.NewReader(input.String())
+	err := cmd.Start()
+	if err != nil {
+		addchan <- RepoFileStatus{Err: err}
+		return
+	}
+
+	var outline []byte
+	var rerr error
+	var filenames []string
+	for rerr = nil; rerr == nil; outline, rerr = cmd.OutReader.ReadBytes('\n') {
+		if len(outline) == 0 {
+			// Empty line output. Ignore
+			continue
+		}
+		var addresult annexAction
+		err := json.Unmarshal(outline, &addresult)
+		if err != nil || addresult.Command == "" {
+			// Couldn't parse output
+			log.Write("Could not parse 'git annex addurl' output")
+			log.Write(string(outline))
+			continue
+		}
+		status := RepoFileStatus{FileName: addresult.File, State: "Adding URL", RawInput: strings.Join(cmd.Args, " "), RawOutput: string(outline)}
+		if addresult.Success {
+			log.Write("%s added to annex from URL", addresult.File)
+			filenames = append(filenames, addresult.File)
+		} else {
+			log.Write("Error adding URL for %s", addresult.File)
+			errmsg := "failed"
+			if len(addresult.Errors) > 0 {
+				errmsg = strings.Join(addresult.Errors, "; ")
+			}
+			status.Err = errors.New(errmsg)
+		}
+		status.Progress = progcomplete
+		addchan <- status
+	}
+	var stderr, errline []byte
+	if cmd.Wait() != nil {
+		for rerr = nil; rerr == nil; errline, rerr = cmd.ErrReader.ReadBytes('\000') {
+			stderr = append(stderr, errline...)
+		}
+		log.Write("Error during AnnexAddURL")
+		logstd(nil, stderr)
+	}
+	for _, fname := range filenames {
+		setAnnexMetadataName(fname)
+	}
+}
+
 // GetAnnexVersion returns the version string of the system's git-annex.
 func GetAnnexVersion() (string, error) {
 	cmd := AnnexCommand("version", "--raw")
@@ -1061,14 +1361,12 @@ func AnnexCommand(args ...string) shell.Cmd {
 	cmdargs := []string{"annex"}
 	cmdargs = append(cmdargs, args...)
 	cmd := shell.Command(gitbin, cmdargs...)
-	env := os.Environ()
-	cmd.Env = env
+	cmd.Env = commandEnv()
 	if gitannexpath != "" {
 		syspath := os.Getenv("PATH")
 		syspath += string(os.PathListSeparator) + gitannexpath
 		cmd.Env = append(cmd.Env, syspath)
 	}
-	cmd.Env = append(cmd.Env, sshEnv())
 	cmd.Env = append(cmd.Env, "GIT_ANNEX_USE_GIT_SSH=1")
 	workingdir, _ := filepath.Abs(".")
 	log.Write("Running shell command (Dir: %s): %s", workingdir, strings.Join(cmd.Args, " "))
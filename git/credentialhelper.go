@@ -0,0 +1,90 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/G-Node/gin-cli/ginclient/config"
+	"github.com/G-Node/gin-cli/ginclient/log"
+)
+
+// Configuration of the client's credential helper ('gin credential-helper') for servers that use the HTTPS transport.
+// The helper is configured through the environment of each git and git-annex command, so that the user's git configuration is not modified.
+
+// CredentialHelperCmd is the name of the client command that implements the git credential helper protocol.
+const CredentialHelperCmd = "credential-helper"
+
+// sqQuote quotes a string in single quotes for the shell, which is also the quoting of the GIT_CONFIG_PARAMETERS environment variable.
+func sqQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// credentialHelper returns the credential helper command line for git, which runs the current executable with the active profile and configuration directory.
+func credentialHelper() (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	confpath, err := config.Path(false)
+	if err != nil {
+		return "", err
+	}
+	profile, _ := config.ActiveProfile()
+	fixpath := func(p string) string {
+		// git runs helpers through a POSIX shell, also on Windows
+		return sqQuote(strings.Replace(p, `\`, "/", -1))
+	}
+	return fmt.Sprintf("!%s --config-dir %s --profile %s %s", fixpath(exe), fixpath(confpath), sqQuote(profile), CredentialHelperCmd), nil
+}
+
+// credentialEnv returns the value that should be set for the GIT_CONFIG_PARAMETERS environment variable
+// in order to use the client's credential helper for all servers that use the HTTPS transport.
+// Other credential helpers are disabled for these servers.
+// An empty string is returned if no server uses the HTTPS transport.
+func credentialEnv() string {
+	conf := config.Read()
+	var addresses []string
+	for _, srvcfg := range conf.Servers {
+		if srvcfg.HTTPSTransport() {
+			addresses = append(addresses, srvcfg.Web.AddressStr())
+		}
+	}
+	if len(addresses) == 0 {
+		return ""
+	}
+	helper, err := credentialHelper()
+	if err != nil {
+		log.Write("Failed to set up credential helper: %s", err)
+		return ""
+	}
+	sort.Strings(addresses)
+	var params []string
+	for _, address := range addresses {
+		key := fmt.Sprintf("credential.%s.helper", address)
+		// each parameter is quoted as a whole, which all git versions understand
+		// an empty value resets the list of helpers
+		params = append(params, sqQuote(key+"="), sqQuote(key+"="+helper))
+	}
+	paramstr := strings.Join(params, " ")
+	existing := os.Getenv("GIT_CONFIG_PARAMETERS")
+	switch {
+	case strings.Contains(existing, paramstr):
+		// inherited from a parent client process (e.g., when running as the credential helper)
+		paramstr = existing
+	case existing != "":
+		paramstr = existing + " " + paramstr
+	}
+	return "GIT_CONFIG_PARAMETERS=" + paramstr
+}
+
+// commandEnv returns the environment for git and git-annex commands: the current environment with the SSH command and the credential helper configuration.
+func commandEnv() []string {
+	env := append(os.Environ(), sshEnv())
+	if credenv := credentialEnv(); credenv != "" {
+		log.Write("env %s", credenv)
+		env = append(env, credenv)
+	}
+	return env
+}
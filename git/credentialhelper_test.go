@@ -0,0 +1,74 @@
+package git
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/G-Node/gin-cli/ginclient/config"
+)
+
+func TestCredentialEnv(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "git-credentialenv-test-")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %s", err)
+	}
+	defer cleanupdir(tmpdir)
+	// characters that are special in double quotes must survive the shell
+	confdir := filepath.Join(tmpdir, "conf $HOME `id` 'q'")
+	config.SetConfigDir(confdir)
+	defer config.SetConfigDir("")
+
+	if env := credentialEnv(); env != "" {
+		t.Errorf("Credential helper configured without HTTPS servers: %s", env)
+	}
+	srvcfg := config.ServerCfg{
+		Web: config.WebCfg{Protocol: "https", Host: "gin.example.org", Port: 443},
+		Git: config.GitCfg{Host: "gin.example.org", Port: 22, User: "git", Transport: config.TransportHTTPS},
+	}
+	if err = config.AddServerConf("httpstest", srvcfg); err != nil {
+		t.Fatalf("Failed to configure test server: %s", err)
+	}
+
+	os.Unsetenv("GIT_CONFIG_PARAMETERS")
+	env := credentialEnv()
+	if !strings.HasPrefix(env, "GIT_CONFIG_PARAMETERS=") {
+		t.Fatalf("Unexpected credential environment: %s", env)
+	}
+	helper, err := credentialHelper()
+	if err != nil {
+		t.Fatalf("Failed to get credential helper command: %s", err)
+	}
+
+	// git reads the helper list for the server: an empty value to reset it, followed by the client's helper
+	cmd := exec.Command("git", "config", "--get-all", "credential.https://gin.example.org:443.helper")
+	cmd.Env = append(os.Environ(), env)
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("git failed to read the credential helper configuration: %s", err)
+	}
+	if string(out) != "\n"+helper+"\n" {
+		t.Errorf("Unexpected helper configuration: %q (expected %q)", string(out), "\n"+helper+"\n")
+	}
+
+	// parameters inherited from a parent process are not repeated
+	os.Setenv("GIT_CONFIG_PARAMETERS", strings.TrimPrefix(env, "GIT_CONFIG_PARAMETERS="))
+	defer os.Unsetenv("GIT_CONFIG_PARAMETERS")
+	if inherited := credentialEnv(); inherited != env {
+		t.Errorf("Inherited parameters changed: %s", inherited)
+	}
+
+	// git runs the helper through the shell, which must see the paths as single arguments
+	exe, _ := os.Executable()
+	out, err = exec.Command("sh", "-c", "printf '%s\\n' "+strings.TrimPrefix(helper, "!")).Output()
+	if err != nil {
+		t.Fatalf("Shell failed to parse the helper command: %s", err)
+	}
+	expected := []string{exe, "--config-dir", confdir, "--profile", config.DefaultProfile, CredentialHelperCmd}
+	if args := strings.Split(strings.TrimSuffix(string(out), "\n"), "\n"); strings.Join(args, "|") != strings.Join(expected, "|") {
+		t.Errorf("Unexpected helper arguments: %q (expected %q)", args, expected)
+	}
+}
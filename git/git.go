@@ -78,6 +78,14 @@ type DiffStat struct {
 	ModifiedFiles []string
 }
 
+// Tag describes a tag in the repository.
+type Tag struct {
+	Name    string    `json:"name"`
+	Hash    string    `json:"hash"`
+	Date    time.Time `json:"date"`
+	Message string    `json:"message"`
+}
+
 // Object contains the information for a tree or blob object in git
 type Object struct {
 	Name string
@@ -482,6 +490,25 @@ func RemoteRemove(name string) error {
 	return nil
 }
 
+// RemoteSetURL changes the URL of the remote named name.
+// (git remote set-url)
+func RemoteSetURL(name, url string) error {
+	fn := fmt.Sprintf("RemoteSetURL(%s, %s)", name, url)
+	cmd := Command("remote", "set-url", name, url)
+	stdout, stderr, err := cmd.OutputError()
+	if err != nil {
+		sstderr := string(stderr)
+		gerr := giterror{UError: sstderr, Origin: fn}
+		log.Write("Error during remote set-url command")
+		logstd(stdout, stderr)
+		if strings.Contains(sstderr, "No such remote") {
+			gerr.Description = fmt.Sprintf("remote with name '%s' does not exist", name)
+		}
+		return gerr
+	}
+	return nil
+}
+
 // BranchSetUpstream sets the default upstream remote for the current branch.
 // (git branch --set-upstream-to=)
 func BranchSetUpstream(name string) error {
@@ -497,6 +524,43 @@ func BranchSetUpstream(name string) error {
 	return nil
 }
 
+// CurrentBranch returns the name of the branch that is checked out.
+// (git symbolic-ref --short HEAD)
+func CurrentBranch() (string, error) {
+	fn := "CurrentBranch()"
+	cmd := Command("symbolic-ref", "--short", "HEAD")
+	stdout, stderr, err := cmd.OutputError()
+	if err != nil {
+		gerr := giterror{UError: string(stderr), Origin: fn, Description: "no branch is checked out"}
+		log.Write("Error during symbolic-ref")
+		logstd(stdout, stderr)
+		return "", gerr
+	}
+	return strings.TrimSpace(string(stdout)), nil
+}
+
+// CheckAttr returns the value of a git attribute for a path.
+// The value is "unspecified" if the attribute is not set for the path.
+// (git check-attr)
+func CheckAttr(attr, path string) (string, error) {
+	fn := fmt.Sprintf("CheckAttr(%s, %s)", attr, path)
+	cmd := Command("check-attr", attr, "--", path)
+	stdout, stderr, err := cmd.OutputError()
+	if err != nil {
+		log.Write("Error during check-attr")
+		logstd(stdout, stderr)
+		return "", giterror{UError: string(stderr), Origin: fn}
+	}
+	// output format: <path>: <attribute>: <value>
+	sstdout := strings.TrimSpace(string(stdout))
+	sep := fmt.Sprintf(": %s: ", attr)
+	idx := strings.LastIndex(sstdout, sep)
+	if idx < 0 {
+		return "", giterror{UError: sstdout, Origin: fn, Description: "unexpected check-attr output"}
+	}
+	return sstdout[idx+len(sep):], nil
+}
+
 // LsRemote performs a git ls-remote of a specific remote.
 // The argument can be a name or a URL.
 // (git ls-remote)
@@ -694,6 +758,54 @@ func LsFiles(args []string, lschan chan<- string) {
 	return
 }
 
+// LogChangedFiles returns the files under the current directory that were changed in commits made after the given date, relative to the current directory.
+// The date can be in any format that git understands (e.g., "2019-06-01" or "2 weeks ago").
+// (git log --since --name-only)
+func LogChangedFiles(since string, paths []string) ([]string, error) {
+	fn := fmt.Sprintf("LogChangedFiles(%s)", since)
+	cmdargs := []string{"log", fmt.Sprintf("--since=%s", since), "--name-only", "--format=", "--relative", "-z", "--"}
+	cmdargs = append(cmdargs, paths...)
+	cmd := Command(cmdargs...)
+	stdout, stderr, err := cmd.OutputError()
+	if err != nil {
+		log.Write("Error during git log")
+		logstd(stdout, stderr)
+		return nil, giterror{UError: string(stderr), Origin: fn}
+	}
+	var files []string
+	seen := make(map[string]bool)
+	for _, fname := range strings.Split(string(stdout), "\000") {
+		// only strip the newlines that may separate commits; file names can start or end with spaces
+		fname = strings.Trim(fname, "\n")
+		if fname == "" || seen[fname] {
+			continue
+		}
+		seen[fname] = true
+		files = append(files, fname)
+	}
+	return files, nil
+}
+
+// StagedFiles returns the paths of the files with changes in the index, relative to the root of the repository.
+// (git diff --cached --name-only)
+func StagedFiles() ([]string, error) {
+	fn := "StagedFiles()"
+	cmd := Command("diff", "--cached", "--name-only", "--no-renames", "-z")
+	stdout, stderr, err := cmd.OutputError()
+	if err != nil {
+		log.Write("Error during git diff")
+		logstd(stdout, stderr)
+		return nil, giterror{UError: string(stderr), Origin: fn}
+	}
+	var files []string
+	for _, fname := range strings.Split(string(stdout), "\000") {
+		if fname != "" {
+			files = append(files, fname)
+		}
+	}
+	return files, nil
+}
+
 // DescribeIndexShort returns a string which represents a condensed form of the git (annex) index.
 // It is constructed using the result of 'git annex status'.
 // The description is composed of the file count for each status: added, modified, deleted
@@ -893,6 +1005,132 @@ func LogDiffStat(count uint, paths []string, showdeletes bool) (map[string]DiffS
 	return stats, nil
 }
 
+// uploadTagsKey is the git configuration key that lists the tags created with TagCreate, separated by spaces.
+// Only these tags are uploaded by PushTags.
+const uploadTagsKey = "gin.uploadtags"
+
+// TagCreate creates an annotated tag with the given name and message at the current commit (HEAD).
+// If the message is empty, the tag name is used as the message.
+// The tag is recorded for upload with PushTags.
+// (git tag --annotate)
+func TagCreate(name, message string) error {
+	fn := fmt.Sprintf("TagCreate(%s)", name)
+	if message == "" {
+		message = name
+	}
+	cmd := Command("tag", "--annotate", "--message", message, name)
+	stdout, stderr, err := cmd.OutputError()
+	if err != nil {
+		sstderr := string(stderr)
+		gerr := giterror{UError: sstderr, Origin: fn}
+		log.Write("Error during tag command")
+		logstd(stdout, stderr)
+		if strings.Contains(sstderr, "already exists") {
+			gerr.Description = fmt.Sprintf("tag '%s' already exists", name)
+		} else if strings.Contains(sstderr, "not a valid tag name") {
+			gerr.Description = fmt.Sprintf("'%s' is not a valid tag name", name)
+		} else if strings.Contains(sstderr, "Failed to resolve 'HEAD'") {
+			gerr.Description = "cannot create a tag in a repository without commits"
+		}
+		return gerr
+	}
+	uploadtags, _ := ConfigGet(uploadTagsKey)
+	if err = ConfigSet(uploadTagsKey, strings.TrimSpace(uploadtags+" "+name)); err != nil {
+		log.Write("Failed to record tag for upload: %s", err)
+	}
+	return nil
+}
+
+// TagList returns the tags of the repository, sorted by date from newest to oldest.
+// (git for-each-ref refs/tags)
+func TagList() ([]Tag, error) {
+	fn := "TagList()"
+	// %(*objectname) is the commit an annotated tag points to; %(objectname) is used for lightweight tags
+	format := "%(refname:short)%00%(*objectname)%00%(objectname)%00%(creatordate:iso-strict)%00%(contents:subject)"
+	cmd := Command("for-each-ref", "--sort=-creatordate", fmt.Sprintf("--format=%s", format), "refs/tags")
+	stdout, stderr, err := cmd.OutputError()
+	if err != nil {
+		gerr := giterror{UError: string(stderr), Origin: fn}
+		log.Write("Error during for-each-ref command")
+		logstd(stdout, stderr)
+		return nil, gerr
+	}
+	var tags []Tag
+	for _, line := range strings.Split(string(stdout), "\n") {
+		if len(strings.TrimSpace(line)) == 0 {
+			continue
+		}
+		parts := strings.SplitN(line, "\000", 5)
+		if len(parts) != 5 {
+			log.Write("Unexpected output: %s", line)
+			continue
+		}
+		tag := Tag{Name: parts[0], Hash: parts[1], Message: parts[4]}
+		if tag.Hash == "" {
+			tag.Hash = parts[2]
+		}
+		tag.Date, _ = time.Parse(time.RFC3339, parts[3])
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+// PushTags uploads the tags created with TagCreate to a remote.
+// Tags that have been deleted since are skipped.
+// (git push <remote> refs/tags/<name>...)
+func PushTags(remote string) error {
+	fn := fmt.Sprintf("PushTags(%s)", remote)
+	uploadtags, err := ConfigGet(uploadTagsKey)
+	if err != nil || uploadtags == "" {
+		return nil
+	}
+	tags, err := TagList()
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		existing[tag.Name] = true
+	}
+	cmdargs := []string{"push", remote}
+	for _, name := range strings.Fields(uploadtags) {
+		if existing[name] {
+			cmdargs = append(cmdargs, "refs/tags/"+name)
+		}
+	}
+	if len(cmdargs) == 2 {
+		return nil
+	}
+	cmd := Command(cmdargs...)
+	stdout, stderr, err := cmd.OutputError()
+	if err != nil {
+		log.Write("Error during push of tags")
+		logstd(stdout, stderr)
+		return giterror{UError: string(stderr), Origin: fn, Description: fmt.Sprintf("failed to upload tags to '%s'", remote)}
+	}
+	return nil
+}
+
+// CheckoutRef checks out a specific commit, tag, or branch, updating HEAD and the working tree.
+// When checking out a tag or commit, the repository is left in a detached HEAD state.
+// (git checkout <ref>)
+func CheckoutRef(ref string) error {
+	fn := fmt.Sprintf("CheckoutRef(%s)", ref)
+	cmd := Command("checkout", ref)
+	stdout, stderr, err := cmd.OutputError()
+	if err != nil {
+		sstderr := string(stderr)
+		gerr := giterror{UError: sstderr, Origin: fn}
+		log.Write("Error during checkout")
+		logstd(stdout, stderr)
+		if strings.Contains(sstderr, "did not match any") {
+			gerr.Description = fmt.Sprintf("'%s' does not match a known version ID or name", ref)
+		}
+		return gerr
+	}
+	return nil
+}
+
 // Checkout performs a git checkout of a specific commit.
 // Individual files or directories may be specified, otherwise the entire tree is checked out.
 func Checkout(hash string, paths []string) error {
@@ -1131,8 +1369,7 @@ func Command(args ...string) shell.Cmd {
 	gitbin := config.Bin.Git
 	cmd := shell.Command(gitbin)
 	cmd.Args = append(cmd.Args, args...)
-	env := os.Environ()
-	cmd.Env = append(env, sshEnv())
+	cmd.Env = commandEnv()
 	workingdir, _ := filepath.Abs(".")
 	log.Write("Running shell command (Dir: %s): %s", workingdir, strings.Join(cmd.Args, " "))
 	return cmd
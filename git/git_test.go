@@ -5,6 +5,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -68,3 +69,107 @@ func TestInit(t *testing.T) {
 		t.Fatalf("Expected bare repository: %s", bare)
 	}
 }
+
+func TestPushTags(t *testing.T) {
+	tmpremote, _ := ioutil.TempDir("", "git-pushtags-remote-")
+	defer cleanupdir(tmpremote)
+	os.Chdir(tmpremote)
+	if err := Init(true); err != nil {
+		t.Fatalf("Failed to initialise remote repository: %s", err.Error())
+	}
+
+	tmpgitdir, _ := ioutil.TempDir("", "git-pushtags-test-")
+	defer cleanupdir(tmpgitdir)
+	os.Chdir(tmpgitdir)
+	if err := Init(false); err != nil {
+		t.Fatalf("Failed to initialise repository: %s", err.Error())
+	}
+	ConfigSet("user.email", "testuser@example.com")
+	cmd := Command("commit", "--allow-empty", "-m", "initial")
+	if _, stderr, err := cmd.OutputError(); err != nil {
+		t.Fatalf("Failed to create commit: %s", string(stderr))
+	}
+	if err := TagCreate("submitted", ""); err != nil {
+		t.Fatalf("Failed to create tag: %s", err.Error())
+	}
+	// tags that were not created with TagCreate are not uploaded
+	cmd = Command("tag", "local")
+	if _, stderr, err := cmd.OutputError(); err != nil {
+		t.Fatalf("Failed to create local tag: %s", string(stderr))
+	}
+	if err := PushTags(tmpremote); err != nil {
+		t.Fatalf("Failed to push tags: %s", err.Error())
+	}
+
+	cmd = Command("ls-remote", "--tags", tmpremote)
+	stdout, _, err := cmd.OutputError()
+	if err != nil {
+		t.Fatalf("Failed to list remote tags: %s", err.Error())
+	}
+	remotetags := string(stdout)
+	if !strings.Contains(remotetags, "refs/tags/submitted") {
+		t.Errorf("Tag 'submitted' not pushed: %s", remotetags)
+	}
+	if strings.Contains(remotetags, "refs/tags/local") {
+		t.Errorf("Tag 'local' pushed: %s", remotetags)
+	}
+}
+
+func TestLogChangedFiles(t *testing.T) {
+	tmpgitdir, _ := ioutil.TempDir("", "git-logchanged-test-")
+	defer cleanupdir(tmpgitdir)
+	os.Chdir(tmpgitdir)
+	if err := Init(false); err != nil {
+		t.Fatalf("Failed to initialise repository: %s", err.Error())
+	}
+	ConfigSet("user.email", "testuser@example.com")
+	fnames := []string{"plain", " leading", "trailing "}
+	for idx, fname := range fnames {
+		ioutil.WriteFile(fname, []byte(fname), 0644)
+		for _, args := range [][]string{{"add", "--", fname}, {"commit", "-m", fmt.Sprintf("commit %d", idx)}} {
+			cmd := Command(args...)
+			if _, stderr, err := cmd.OutputError(); err != nil {
+				t.Fatalf("git %s failed: %s", args[0], string(stderr))
+			}
+		}
+	}
+	files, err := LogChangedFiles("1 day ago", nil)
+	if err != nil {
+		t.Fatalf("Failed to list changed files: %s", err.Error())
+	}
+	changed := make(map[string]bool)
+	for _, fname := range files {
+		changed[fname] = true
+	}
+	if len(files) != len(fnames) {
+		t.Errorf("Expected %d changed files, got %q", len(fnames), files)
+	}
+	for _, fname := range fnames {
+		if !changed[fname] {
+			t.Errorf("File %q missing from changed files %q", fname, files)
+		}
+	}
+}
+
+func TestHasLargefilesAttributes(t *testing.T) {
+	tmpgitdir, _ := ioutil.TempDir("", "git-largefiles-test-")
+	defer cleanupdir(tmpgitdir)
+	os.Chdir(tmpgitdir)
+	if err := Init(false); err != nil {
+		t.Fatalf("Failed to initialise repository: %s", err.Error())
+	}
+	if hasLargefilesAttributes() {
+		t.Errorf("Repository without .gitattributes reported to have annex rules")
+	}
+	// annex.largefiles attributes outside the gin rules block only apply to the matching paths
+	attrs := "*.png binary\nraw/** annex.largefiles=anything\n"
+	ioutil.WriteFile(".gitattributes", []byte(attrs), 0644)
+	if hasLargefilesAttributes() {
+		t.Errorf("annex.largefiles attribute outside the rules block reported as annex rules")
+	}
+	attrs += strings.Join([]string{AnnexRulesBlockStart, "* annex.largefiles=largerthan=10M", AnnexRulesBlockEnd}, "\n") + "\n"
+	ioutil.WriteFile(".gitattributes", []byte(attrs), 0644)
+	if !hasLargefilesAttributes() {
+		t.Errorf("Annex rules block not detected")
+	}
+}
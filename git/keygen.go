@@ -1,26 +1,41 @@
 package git
 
 import (
+	"bytes"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/pem"
 	"fmt"
+	"io/ioutil"
 	"net"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/G-Node/gin-cli/ginclient/config"
 	"github.com/G-Node/gin-cli/ginclient/log"
+	"golang.org/x/crypto/ed25519"
 	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
 	"golang.org/x/crypto/ssh/knownhosts"
 )
 
+// Key types for MakeKeyPair
+const (
+	KeyTypeEd25519 = "ed25519"
+	KeyTypeRSA     = "rsa"
+)
+
 // KeyPair simply holds a private-public key pair as strings, with no extra information.
+// The private key string is encrypted if the pair was created or loaded with a passphrase.
 type KeyPair struct {
 	Private string
 	Public  string
+	// unencrypted private key, for adding to an agent
+	key interface{}
 }
 
 // KeyFile holds the absolute path and filename of the current logged in user's key file.
@@ -30,36 +45,190 @@ type KeyFile struct {
 	Active   bool
 }
 
-// MakeKeyPair generates and returns a private-public key pair.
-func MakeKeyPair() (*KeyPair, error) {
-	log.Write("Creating key pair")
-	privkey, err := rsa.GenerateKey(rand.Reader, 2048) // TODO: Key size as parameter
+// MakeKeyPair generates and returns a private-public key pair of the given type (KeyTypeEd25519 or KeyTypeRSA).
+// The size in bits only applies to RSA keys.
+// If passphrase is not empty, the private key is encrypted with it.
+func MakeKeyPair(keytype string, bits int, passphrase string) (*KeyPair, error) {
+	log.Write("Creating %s key pair", keytype)
+	var privStr string
+	var pubkey ssh.PublicKey
+	var privkey interface{}
+	switch keytype {
+	case KeyTypeEd25519:
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("Error generating key pair: %s", err)
+		}
+		privBytes, err := marshalEd25519PrivateKey(priv, "", passphrase)
+		if err != nil {
+			return nil, err
+		}
+		privStr = string(privBytes)
+		if pubkey, err = ssh.NewPublicKey(pub); err != nil {
+			return nil, err
+		}
+		privkey = &priv
+	case KeyTypeRSA:
+		rsakey, err := rsa.GenerateKey(rand.Reader, bits)
+		if err != nil {
+			return nil, fmt.Errorf("Error generating key pair: %s", err)
+		}
+		// Private key to string
+		privkeyDer := x509.MarshalPKCS1PrivateKey(rsakey)
+		privBlk := &pem.Block{
+			Type:    "RSA PRIVATE KEY",
+			Headers: nil,
+			Bytes:   privkeyDer,
+		}
+		if passphrase != "" {
+			// legacy PEM encryption, which OpenSSH reads for RSA keys
+			privBlk, err = x509.EncryptPEMBlock(rand.Reader, privBlk.Type, privkeyDer, []byte(passphrase), x509.PEMCipherAES256)
+			if err != nil {
+				return nil, err
+			}
+		}
+		privStr = string(pem.EncodeToMemory(privBlk))
+		if pubkey, err = ssh.NewPublicKey(&rsakey.PublicKey); err != nil {
+			return nil, err
+		}
+		privkey = rsakey
+	default:
+		return nil, fmt.Errorf("unsupported key type '%s'", keytype)
+	}
+
+	// Public key to string
+	pubStr := string(ssh.MarshalAuthorizedKey(pubkey))
+
+	return &KeyPair{privStr, pubStr, privkey}, nil
+}
+
+// ParseKeyPair reads a private key created by MakeKeyPair (or an unencrypted key in any format supported by ssh) and returns the key pair.
+// The passphrase is required for encrypted keys; ErrPassphraseRequired is returned if it is empty.
+func ParseKeyPair(private []byte, passphrase string) (*KeyPair, error) {
+	blk, _ := pem.Decode(private)
+	if blk == nil {
+		return nil, fmt.Errorf("invalid private key")
+	}
+	var privkey interface{}
+	var err error
+	switch {
+	case blk.Type == opensshPEMType:
+		var edkey ed25519.PrivateKey
+		if edkey, err = parseEd25519PrivateKey(blk.Bytes, passphrase); err == nil {
+			privkey = &edkey
+		}
+	case x509.IsEncryptedPEMBlock(blk):
+		if passphrase == "" {
+			return nil, ErrPassphraseRequired
+		}
+		der, derr := x509.DecryptPEMBlock(blk, []byte(passphrase))
+		if derr != nil {
+			return nil, fmt.Errorf("failed to decrypt private key: wrong passphrase")
+		}
+		privkey, err = x509.ParsePKCS1PrivateKey(der)
+	default:
+		privkey, err = ssh.ParseRawPrivateKey(private)
+	}
+	if err != nil {
+		return nil, err
+	}
+	signer, err := ssh.NewSignerFromKey(privkey)
+	if err != nil {
+		return nil, err
+	}
+	pubStr := string(ssh.MarshalAuthorizedKey(signer.PublicKey()))
+	return &KeyPair{string(private), pubStr, privkey}, nil
+}
+
+// KeyEncrypted returns true if the private key is protected by a passphrase.
+func KeyEncrypted(private []byte) bool {
+	_, err := ParseKeyPair(private, "")
+	return err == ErrPassphraseRequired
+}
+
+// AgentAvailable returns true if an SSH agent is running and reachable through the SSH_AUTH_SOCK environment variable.
+func AgentAvailable() bool {
+	sock := os.Getenv("SSH_AUTH_SOCK")
+	if sock == "" {
+		return false
+	}
+	conn, err := net.Dial("unix", sock)
 	if err != nil {
-		return nil, fmt.Errorf("Error generating key pair: %s", err)
+		return false
 	}
+	conn.Close()
+	return true
+}
 
-	// Private key to string
-	privkeyDer := x509.MarshalPKCS1PrivateKey(privkey)
-	privBlk := pem.Block{
-		Type:    "RSA PRIVATE KEY",
-		Headers: nil,
-		Bytes:   privkeyDer,
+// AddToAgent adds the private key to the SSH agent reachable through the SSH_AUTH_SOCK environment variable.
+// The key is removed from the agent after lifetime seconds, or kept until the agent exits if lifetime is 0.
+func (kp *KeyPair) AddToAgent(comment string, lifetime uint32) error {
+	if kp.key == nil {
+		return fmt.Errorf("private key not available")
+	}
+	sock := os.Getenv("SSH_AUTH_SOCK")
+	if sock == "" {
+		return fmt.Errorf("no SSH agent running (SSH_AUTH_SOCK is not set)")
 	}
-	privStr := string(pem.EncodeToMemory(&privBlk))
+	conn, err := net.Dial("unix", sock)
+	if err != nil {
+		return fmt.Errorf("failed to connect to SSH agent: %s", err)
+	}
+	defer conn.Close()
+	log.Write("Adding key to SSH agent: %s", comment)
+	return agent.NewClient(conn).Add(agent.AddedKey{PrivateKey: kp.key, Comment: comment, LifetimeSecs: lifetime})
+}
 
-	// Public key to string
-	pubkey, err := ssh.NewPublicKey(&privkey.PublicKey)
+// AgentPublicKeys returns the public keys held by the SSH agent reachable through the SSH_AUTH_SOCK environment variable, in the format of the authorized_keys file.
+func AgentPublicKeys() ([]string, error) {
+	sock := os.Getenv("SSH_AUTH_SOCK")
+	if sock == "" {
+		return nil, fmt.Errorf("no SSH agent running (SSH_AUTH_SOCK is not set)")
+	}
+	conn, err := net.Dial("unix", sock)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to SSH agent: %s", err)
+	}
+	defer conn.Close()
+	agentkeys, err := agent.NewClient(conn).List()
 	if err != nil {
 		return nil, err
 	}
-	pubStr := string(ssh.MarshalAuthorizedKey(pubkey))
+	pubkeys := make([]string, len(agentkeys))
+	for idx, key := range agentkeys {
+		pubkeys[idx] = string(ssh.MarshalAuthorizedKey(key))
+	}
+	return pubkeys, nil
+}
+
+// PublicKeyFromFile returns the public key of an identity file, in the format of the authorized_keys file.
+// The key is read from the file with the .pub extension next to the given file if there is one, from the file itself if it is a public key, or derived from an unencrypted private key.
+func PublicKeyFromFile(path string) (string, error) {
+	for _, fname := range []string{path + ".pub", path} {
+		data, err := ioutil.ReadFile(fname)
+		if err != nil {
+			continue
+		}
+		if pubkey, _, _, _, perr := ssh.ParseAuthorizedKey(data); perr == nil {
+			return string(ssh.MarshalAuthorizedKey(pubkey)), nil
+		}
+		if keyPair, perr := ParseKeyPair(data, ""); perr == nil {
+			return keyPair.Public, nil
+		}
+	}
+	return "", fmt.Errorf("could not read public key for identity file %s", path)
+}
 
-	return &KeyPair{privStr, pubStr}, nil
+// SamePublicKey returns true if the two public keys in the format of the authorized_keys file have the same type and data.
+// Comments are ignored.
+func SamePublicKey(a, b string) bool {
+	afields, bfields := strings.Fields(a), strings.Fields(b)
+	return len(afields) >= 2 && len(bfields) >= 2 && afields[0] == bfields[0] && afields[1] == bfields[1]
 }
 
-// PrivKeyPath returns a map with the full path for all the currently available private key files indexed by the server alias for each key.
+// PrivKeyPath returns a map with the full path for all the currently available private key files of the active profile indexed by the server alias for each key.
 func PrivKeyPath() map[string]string {
-	configpath, err := config.Path(false)
+	configpath, err := config.CredentialsPath(false)
 	if err != nil {
 		log.Write("Error getting user's config path. Can't load key file.")
 		log.Write(err.Error())
@@ -91,6 +260,7 @@ func GetHostKey(gitconf config.GitCfg) (hostkeystr, fingerprint string, err erro
 	sshcon := ssh.ClientConfig{
 		User:            gitconf.User,
 		HostKeyCallback: keycb,
+		Timeout:         30 * time.Second,
 	}
 	_, derr := ssh.Dial("tcp", fmt.Sprintf("%s:%d", gitconf.Host, gitconf.Port), &sshcon)
 	if derr != nil && !strings.Contains(derr.Error(), "unable to authenticate") {
@@ -100,6 +270,35 @@ func GetHostKey(gitconf config.GitCfg) (hostkeystr, fingerprint string, err erro
 	return
 }
 
+// Host key states returned by CheckHostKey
+const (
+	HostKeyOK      = "ok"
+	HostKeyMissing = "missing"
+	HostKeyChanged = "changed"
+)
+
+// CheckHostKey compares the host key configured for a git server with the key presented by the server.
+// It returns HostKeyMissing if no valid host key is configured, HostKeyChanged if the keys differ, and HostKeyOK otherwise, along with the fingerprint of the server's key.
+// An error is returned if the server cannot be reached.
+func CheckHostKey(gitconf config.GitCfg) (status, fingerprint string, err error) {
+	hostkeystr, fingerprint, err := GetHostKey(gitconf)
+	if err != nil {
+		return "", "", err
+	}
+	_, _, serverkey, _, _, err := ssh.ParseKnownHosts([]byte(hostkeystr))
+	if err != nil {
+		return "", fingerprint, err
+	}
+	_, _, configkey, _, _, perr := ssh.ParseKnownHosts([]byte(gitconf.HostKey))
+	switch {
+	case perr != nil:
+		return HostKeyMissing, fingerprint, nil
+	case string(configkey.Marshal()) != string(serverkey.Marshal()):
+		return HostKeyChanged, fingerprint, nil
+	}
+	return HostKeyOK, fingerprint, nil
+}
+
 // hostkeypath returns the full path for the location of the gin host key file.
 func hostkeypath() string {
 	configpath, _ := config.Path(false) // Error can only occur when attempting to create directory
@@ -142,9 +341,85 @@ func GetKnownHosts() (string, error) {
 	return hkpath, err
 }
 
+// SessionKeysOnly disables the user's own keys (identity files and SSH agent) for git commands, so that only the session keys are offered to the servers.
+var SessionKeysOnly = false
+
+// sshConfigPath returns the full path for the location of the SSH configuration file that selects the user's own keys for each server.
+func sshConfigPath() string {
+	configpath, _ := config.Path(false) // Error can only occur when attempting to create directory
+	return filepath.Join(configpath, "ssh_config")
+}
+
+// sshHostConfig returns an SSH configuration that offers the user's own keys only to the git servers they are configured for:
+// the identity files of each server and, for servers with agent use enabled, all keys held by the SSH agent.
+// Other hosts only get the keys given on the command line.
+// The user and system SSH configuration files, which are not read when a configuration file is specified, are included at the end.
+func sshHostConfig(servers map[string]config.ServerCfg) string {
+	type hostKeys struct {
+		files []string
+		agent bool
+	}
+	hosts := make(map[string]*hostKeys)
+	var hostnames []string
+	for _, srvcfg := range servers {
+		host := srvcfg.Git.Host
+		if host == "" {
+			continue
+		}
+		if _, ok := hosts[host]; !ok {
+			hosts[host] = &hostKeys{}
+			hostnames = append(hostnames, host)
+		}
+		for _, k := range srvcfg.Git.IdentityFiles {
+			if k, err := config.ExpandHome(k); err == nil && pathExists(k) {
+				hosts[host].files = append(hosts[host].files, k)
+			}
+		}
+		// servers with the same host share the keys
+		hosts[host].agent = hosts[host].agent || srvcfg.Git.UseAgent
+	}
+	sort.Strings(hostnames)
+
+	var conf strings.Builder
+	conf.WriteString("# SSH options for git servers, written by the GIN client from its configuration. Changes are overwritten.\n")
+	for _, host := range hostnames {
+		keys := hosts[host]
+		if !keys.agent && len(keys.files) == 0 {
+			continue
+		}
+		fmt.Fprintf(&conf, "Host %s\n", host)
+		if keys.agent {
+			// offer all keys of the agent, not only those matching the identity files
+			conf.WriteString("\tIdentitiesOnly no\n")
+		}
+		sort.Strings(keys.files)
+		for _, k := range keys.files {
+			fmt.Fprintf(&conf, "\tIdentityFile \"%s\"\n", filepath.ToSlash(k))
+		}
+	}
+	conf.WriteString("Host *\n\tIdentitiesOnly yes\n")
+	conf.WriteString("Match all\n\tInclude ~/.ssh/config /etc/ssh/ssh_config\n")
+	return conf.String()
+}
+
+// writeSSHConfig writes the SSH configuration for the configured servers (see sshHostConfig) to the config directory, if it changed, and returns its path.
+func writeSSHConfig() (string, error) {
+	if _, err := config.Path(true); err != nil {
+		log.Write("Failed to create config directory for ssh_config")
+		return "", err
+	}
+	confpath := sshConfigPath()
+	sshconf := []byte(sshHostConfig(config.Read().Servers))
+	if existing, err := ioutil.ReadFile(confpath); err == nil && bytes.Equal(existing, sshconf) {
+		return confpath, nil
+	}
+	return confpath, ioutil.WriteFile(confpath, sshconf, 0600)
+}
+
 // sshEnv returns the value that should be set for the GIT_SSH_COMMAND environment variable
 // in order to use the user's private keys.
-// The returned string contains all available private keys.
+// The returned string contains all available private keys of the active profile.
+// The user's own keys are selected per server through an SSH configuration file, unless SessionKeysOnly is set.
 func sshEnv() string {
 	// Windows git seems to require Unix paths for the SSH command -- this is dirty but works
 	fixpathsep := func(p string) string {
@@ -152,22 +427,29 @@ func sshEnv() string {
 		p = strings.Replace(p, " ", "\\ ", -1)
 		return p
 	}
-	config := config.Read()
-	sshbin := fixpathsep(config.Bin.SSH)
+	conf := config.Read()
+	sshbin := fixpathsep(conf.Bin.SSH)
 	keys := PrivKeyPath()
-	keyargs := make([]string, len(keys))
-	idx := 0
+	keyargs := make([]string, 0, len(keys))
 	for _, k := range keys {
-		keyargs[idx] = fmt.Sprintf("-i %s", fixpathsep(k))
-		idx++
+		keyargs = append(keyargs, fmt.Sprintf("-i %s", fixpathsep(k)))
 	}
 	keystr := strings.Join(keyargs, " ")
+	// options given on the command line take precedence over the configuration file
+	keyoptstr := "-o IdentitiesOnly=yes"
+	if !SessionKeysOnly {
+		if sshconfpath, err := writeSSHConfig(); err == nil {
+			keyoptstr = fmt.Sprintf("-F %s", fixpathsep(sshconfpath))
+		} else {
+			log.Write("Failed to write SSH configuration; the user's own keys are not used: %s", err)
+		}
+	}
 	hostkeyfile, err := GetKnownHosts()
 	var hfoptstr string
 	if err == nil {
 		hfoptstr = fmt.Sprintf("-o 'UserKnownHostsFile=\"%s\"'", hostkeyfile)
 	}
-	gitSSHCmd := fmt.Sprintf("GIT_SSH_COMMAND=%s %s -o IdentitiesOnly=yes -o StrictHostKeyChecking=yes %s", sshbin, keystr, hfoptstr)
+	gitSSHCmd := fmt.Sprintf("GIT_SSH_COMMAND=%s %s %s -o StrictHostKeyChecking=yes %s", sshbin, keystr, keyoptstr, hfoptstr)
 	log.Write("env %s", gitSSHCmd)
 	return gitSSHCmd
 }
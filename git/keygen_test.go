@@ -0,0 +1,182 @@
+package git
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/G-Node/gin-cli/ginclient/config"
+	"golang.org/x/crypto/ed25519"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+func TestKeyPairRoundTrip(t *testing.T) {
+	for _, keytype := range []string{KeyTypeEd25519, KeyTypeRSA} {
+		for _, passphrase := range []string{"", "correct horse"} {
+			keyPair, err := MakeKeyPair(keytype, 2048, passphrase)
+			if err != nil {
+				t.Fatalf("[%s] key creation failed: %s", keytype, err)
+			}
+			private := []byte(keyPair.Private)
+			if encrypted := KeyEncrypted(private); encrypted != (passphrase != "") {
+				t.Errorf("[%s] KeyEncrypted returned %t for passphrase %q", keytype, encrypted, passphrase)
+			}
+			parsed, err := ParseKeyPair(private, passphrase)
+			if err != nil {
+				t.Fatalf("[%s] failed to read key: %s", keytype, err)
+			}
+			if parsed.Public != keyPair.Public {
+				t.Errorf("[%s] public key mismatch: %s != %s", keytype, parsed.Public, keyPair.Public)
+			}
+			if passphrase == "" {
+				continue
+			}
+			if _, err = ParseKeyPair(private, ""); err != ErrPassphraseRequired {
+				t.Errorf("[%s] expected ErrPassphraseRequired, got %v", keytype, err)
+			}
+			if _, err = ParseKeyPair(private, "wrong"); err == nil {
+				t.Errorf("[%s] key decrypted with wrong passphrase", keytype)
+			}
+		}
+	}
+}
+
+func TestCheckHostKey(t *testing.T) {
+	newSigner := func() ssh.Signer {
+		_, private, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatalf("key creation failed: %s", err)
+		}
+		signer, err := ssh.NewSignerFromKey(private)
+		if err != nil {
+			t.Fatalf("failed to create signer: %s", err)
+		}
+		return signer
+	}
+	hostkey := newSigner()
+	srvconf := &ssh.ServerConfig{
+		PasswordCallback: func(ssh.ConnMetadata, []byte) (*ssh.Permissions, error) {
+			return nil, fmt.Errorf("denied")
+		},
+	}
+	srvconf.AddHostKey(hostkey)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to start SSH server: %s", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			// authentication always fails; the client only needs the host key
+			go ssh.NewServerConn(conn, srvconf)
+		}
+	}()
+
+	addr := listener.Addr().(*net.TCPAddr)
+	gitconf := config.GitCfg{User: "git", Host: "127.0.0.1", Port: uint16(addr.Port)}
+	cases := map[string]string{
+		HostKeyOK:      knownhosts.Line([]string{"127.0.0.1"}, hostkey.PublicKey()),
+		HostKeyChanged: knownhosts.Line([]string{"127.0.0.1"}, newSigner().PublicKey()),
+		HostKeyMissing: "",
+	}
+	for expected, configured := range cases {
+		gitconf.HostKey = configured
+		status, fingerprint, err := CheckHostKey(gitconf)
+		if err != nil {
+			t.Fatalf("host key check failed: %s", err)
+		}
+		if status != expected {
+			t.Errorf("expected host key status %q, got %q", expected, status)
+		}
+		if fingerprint != ssh.FingerprintSHA256(hostkey.PublicKey()) {
+			t.Errorf("wrong fingerprint: %s", fingerprint)
+		}
+	}
+}
+
+func TestSSHHostConfig(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "git-sshconfig-test-")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %s", err)
+	}
+	defer cleanupdir(tmpdir)
+	keyA := filepath.Join(tmpdir, "id_a")
+	keyB := filepath.Join(tmpdir, "id_b")
+	for _, k := range []string{keyA, keyB, filepath.Join(tmpdir, "session.key")} {
+		ioutil.WriteFile(k, []byte("key"), 0600)
+	}
+	servers := map[string]config.ServerCfg{
+		"agent":    {Git: config.GitCfg{Host: "agent.example.org", UseAgent: true, IdentityFiles: []string{keyA}}},
+		"files":    {Git: config.GitCfg{Host: "files.example.org", IdentityFiles: []string{keyB, filepath.Join(tmpdir, "missing")}}},
+		"sessions": {Git: config.GitCfg{Host: "session.example.org"}},
+	}
+	sshconf := sshHostConfig(servers)
+	if strings.Contains(sshconf, "missing") {
+		t.Errorf("Missing identity file included in SSH configuration:\n%s", sshconf)
+	}
+	if strings.Contains(sshconf, "Host session.example.org") {
+		t.Errorf("Server without user keys included in SSH configuration:\n%s", sshconf)
+	}
+
+	sshbin, err := exec.LookPath("ssh")
+	if err != nil {
+		t.Skip("ssh not found")
+	}
+	confpath := filepath.Join(tmpdir, "ssh_config")
+	ioutil.WriteFile(confpath, []byte(sshconf), 0600)
+	cases := map[string]struct {
+		identitiesOnly string
+		files          []string
+	}{
+		"agent.example.org":   {"no", []string{keyA}},
+		"files.example.org":   {"yes", []string{keyB}},
+		"session.example.org": {"yes", nil},
+		"other.example.org":   {"yes", nil},
+	}
+	for host, expected := range cases {
+		// -G prints the configuration for the host without connecting
+		out, err := exec.Command(sshbin, "-G", "-F", confpath, "-i", filepath.Join(tmpdir, "session.key"), host).Output()
+		if err != nil {
+			t.Fatalf("ssh -G failed for %s: %s", host, err)
+		}
+		var identitiesOnly string
+		files := make(map[string]bool)
+		for _, line := range strings.Split(string(out), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) != 2 {
+				continue
+			}
+			switch fields[0] {
+			case "identitiesonly":
+				identitiesOnly = fields[1]
+			case "identityfile":
+				files[fields[1]] = true
+			}
+		}
+		if identitiesOnly != expected.identitiesOnly {
+			t.Errorf("[%s] expected IdentitiesOnly %s, got %s", host, expected.identitiesOnly, identitiesOnly)
+		}
+		for _, k := range []string{keyA, keyB} {
+			offered, shouldOffer := files[k], false
+			for _, ek := range expected.files {
+				shouldOffer = shouldOffer || ek == k
+			}
+			if offered != shouldOffer {
+				t.Errorf("[%s] identity file %s offered: %t, expected %t", host, k, offered, shouldOffer)
+			}
+		}
+		if !files[filepath.Join(tmpdir, "session.key")] {
+			t.Errorf("[%s] session key not offered", host)
+		}
+	}
+}
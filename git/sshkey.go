@@ -0,0 +1,221 @@
+package git
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/binary"
+	"encoding/pem"
+	"fmt"
+
+	"golang.org/x/crypto/blowfish"
+	"golang.org/x/crypto/ed25519"
+	"golang.org/x/crypto/ssh"
+)
+
+// Encoding of Ed25519 private keys in the OpenSSH private key format (openssh-key-v1), with optional passphrase encryption (aes256-ctr with a bcrypt key derivation), as written by ssh-keygen.
+
+const (
+	opensshMagic     = "openssh-key-v1\x00"
+	opensshPEMType   = "OPENSSH PRIVATE KEY"
+	opensshCipher    = "aes256-ctr"
+	opensshKDF       = "bcrypt"
+	opensshKDFRounds = 16
+)
+
+// ErrPassphraseRequired is returned when reading an encrypted private key without a passphrase.
+var ErrPassphraseRequired = fmt.Errorf("the private key is protected by a passphrase")
+
+// opensshKey is the outer structure of an openssh-key-v1 key.
+type opensshKey struct {
+	CipherName   string
+	KdfName      string
+	KdfOpts      string
+	NumKeys      uint32
+	PubKey       []byte
+	PrivKeyBlock []byte
+}
+
+type opensshKDFOpts struct {
+	Salt   []byte
+	Rounds uint32
+}
+
+// opensshEd25519 is the private section of an Ed25519 key (without the trailing padding).
+type opensshEd25519 struct {
+	Check1  uint32
+	Check2  uint32
+	Keytype string
+	Pub     []byte
+	Priv    []byte
+	Comment string
+	Pad     []byte `ssh:"rest"`
+}
+
+// marshalEd25519PrivateKey encodes an Ed25519 private key in the OpenSSH format, encrypting it if passphrase is not empty.
+func marshalEd25519PrivateKey(key ed25519.PrivateKey, comment, passphrase string) ([]byte, error) {
+	pubkey, err := ssh.NewPublicKey(key.Public())
+	if err != nil {
+		return nil, err
+	}
+	var check [4]byte
+	if _, err = rand.Read(check[:]); err != nil {
+		return nil, err
+	}
+	checkint := binary.BigEndian.Uint32(check[:])
+	priv := opensshEd25519{
+		Check1:  checkint,
+		Check2:  checkint,
+		Keytype: ssh.KeyAlgoED25519,
+		Pub:     []byte(key.Public().(ed25519.PublicKey)),
+		Priv:    []byte(key),
+		Comment: comment,
+	}
+	outer := opensshKey{CipherName: "none", KdfName: "none", NumKeys: 1, PubKey: pubkey.Marshal()}
+	blocksize := 8
+	var salt []byte
+	if passphrase != "" {
+		salt = make([]byte, 16)
+		if _, err = rand.Read(salt); err != nil {
+			return nil, err
+		}
+		outer.CipherName = opensshCipher
+		outer.KdfName = opensshKDF
+		outer.KdfOpts = string(ssh.Marshal(opensshKDFOpts{Salt: salt, Rounds: opensshKDFRounds}))
+		blocksize = aes.BlockSize
+	}
+	block := ssh.Marshal(priv)
+	for idx := 1; len(block)%blocksize != 0; idx++ {
+		block = append(block, byte(idx))
+	}
+	if passphrase != "" {
+		stream, err := opensshStream(passphrase, salt, opensshKDFRounds)
+		if err != nil {
+			return nil, err
+		}
+		stream.XORKeyStream(block, block)
+	}
+	outer.PrivKeyBlock = block
+	data := append([]byte(opensshMagic), ssh.Marshal(outer)...)
+	return pem.EncodeToMemory(&pem.Block{Type: opensshPEMType, Bytes: data}), nil
+}
+
+// parseEd25519PrivateKey decodes an Ed25519 private key in the OpenSSH format, decrypting it with the passphrase if necessary.
+func parseEd25519PrivateKey(data []byte, passphrase string) (ed25519.PrivateKey, error) {
+	if !bytes.HasPrefix(data, []byte(opensshMagic)) {
+		return nil, fmt.Errorf("invalid OpenSSH private key")
+	}
+	var outer opensshKey
+	if err := ssh.Unmarshal(data[len(opensshMagic):], &outer); err != nil {
+		return nil, err
+	}
+	block := append([]byte(nil), outer.PrivKeyBlock...)
+	switch {
+	case outer.CipherName == "none" && outer.KdfName == "none":
+	case outer.CipherName == opensshCipher && outer.KdfName == opensshKDF:
+		if passphrase == "" {
+			return nil, ErrPassphraseRequired
+		}
+		var opts opensshKDFOpts
+		if err := ssh.Unmarshal([]byte(outer.KdfOpts), &opts); err != nil {
+			return nil, err
+		}
+		if len(block)%aes.BlockSize != 0 {
+			return nil, fmt.Errorf("invalid encrypted private key")
+		}
+		stream, err := opensshStream(passphrase, opts.Salt, int(opts.Rounds))
+		if err != nil {
+			return nil, err
+		}
+		stream.XORKeyStream(block, block)
+	default:
+		return nil, fmt.Errorf("unsupported private key encryption %s/%s", outer.CipherName, outer.KdfName)
+	}
+	var priv opensshEd25519
+	if err := ssh.Unmarshal(block, &priv); err != nil || priv.Check1 != priv.Check2 {
+		return nil, fmt.Errorf("failed to decrypt private key: wrong passphrase")
+	}
+	if priv.Keytype != ssh.KeyAlgoED25519 || len(priv.Priv) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("unsupported private key type %s", priv.Keytype)
+	}
+	return ed25519.PrivateKey(priv.Priv), nil
+}
+
+// opensshStream returns the aes256-ctr stream for encrypting a private key with a passphrase.
+func opensshStream(passphrase string, salt []byte, rounds int) (cipher.Stream, error) {
+	keyiv, err := bcryptPBKDF([]byte(passphrase), salt, rounds, 32+aes.BlockSize)
+	if err != nil {
+		return nil, err
+	}
+	blk, err := aes.NewCipher(keyiv[:32])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewCTR(blk, keyiv[32:]), nil
+}
+
+// bcryptPBKDF derives a key from a password using the bcrypt_pbkdf function of OpenBSD, which OpenSSH uses for encrypting private keys.
+func bcryptPBKDF(password, salt []byte, rounds, keylen int) ([]byte, error) {
+	const blocksize = 32
+	if rounds < 1 || len(password) == 0 || len(salt) == 0 || keylen > 1024 {
+		return nil, fmt.Errorf("invalid key derivation parameters")
+	}
+	nblocks := (keylen + blocksize - 1) / blocksize
+	key := make([]byte, nblocks*blocksize)
+
+	h := sha512.New()
+	h.Write(password)
+	shapass := h.Sum(nil)
+
+	shasalt := make([]byte, 0, sha512.Size)
+	cnt, tmp := make([]byte, 4), make([]byte, blocksize)
+	for blk := 1; blk <= nblocks; blk++ {
+		h.Reset()
+		h.Write(salt)
+		binary.BigEndian.PutUint32(cnt, uint32(blk))
+		h.Write(cnt)
+		if err := bcryptHash(tmp, shapass, h.Sum(shasalt)); err != nil {
+			return nil, err
+		}
+		out := make([]byte, blocksize)
+		copy(out, tmp)
+		for i := 2; i <= rounds; i++ {
+			h.Reset()
+			h.Write(tmp)
+			if err := bcryptHash(tmp, shapass, h.Sum(shasalt)); err != nil {
+				return nil, err
+			}
+			for j := range out {
+				out[j] ^= tmp[j]
+			}
+		}
+		for i, v := range out {
+			key[i*nblocks+(blk-1)] = v
+		}
+	}
+	return key[:keylen], nil
+}
+
+func bcryptHash(out, shapass, shasalt []byte) error {
+	c, err := blowfish.NewSaltedCipher(shapass, shasalt)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < 64; i++ {
+		blowfish.ExpandKey(shasalt, c)
+		blowfish.ExpandKey(shapass, c)
+	}
+	copy(out, "OxychromaticBlowfishSwatDynamite")
+	for i := 0; i < 32; i += 8 {
+		for j := 0; j < 64; j++ {
+			c.Encrypt(out[i:i+8], out[i:i+8])
+		}
+	}
+	// swap bytes due to different endianness
+	for i := 0; i < 32; i += 4 {
+		out[i+3], out[i+2], out[i+1], out[i] = out[i], out[i+1], out[i+2], out[i+3]
+	}
+	return nil
+}
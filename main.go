@@ -26,6 +26,9 @@ func init() {
 	verinfo.Build = build
 	verinfo.Commit = commit
 
+	// the configuration directory determines which git and git-annex binaries are used
+	gincmd.PreParseConfigDir(os.Args[1:])
+
 	gitVer, err := git.GetGitVersion()
 	if err != nil {
 		gitVer = err.Error()
@@ -37,10 +40,10 @@ func init() {
 		annexVer = err.Error()
 	}
 	verinfo.Annex = annexVer
-	if err != nil {
-		fmt.Println("Failed to initialise log file")
+	// stdout is reserved for command output (e.g., the credential helper responses to git)
+	if err = log.Init(); err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to initialise log file")
 	}
-	err = log.Init()
 	log.Write("VERSION: %s", verinfo.String())
 }
 
@@ -0,0 +1,448 @@
+package web
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/gob"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/G-Node/gin-cli/ginclient/config"
+	"github.com/G-Node/gin-cli/ginclient/log"
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Credential stores hold the login tokens of the client, separately for each profile and server.
+// The store is selected with the credentials.store configuration key:
+// "keyring" uses the system keyring (Secret Service on Linux through secret-tool, the keychain on macOS),
+// "encrypted-file" uses files encrypted with a passphrase,
+// "file" uses unencrypted files (the original storage format),
+// and "auto" (the default) uses the keyring if it is available and unencrypted files otherwise.
+
+// Credential store names
+const (
+	AutoStore          = "auto"
+	KeyringStore       = "keyring"
+	EncryptedFileStore = "encrypted-file"
+	FileStore          = "file"
+)
+
+// PassphraseEnvVar is the environment variable that holds the passphrase for the encrypted file store.
+const PassphraseEnvVar = "GIN_CREDENTIAL_PASSPHRASE"
+
+const keyringService = "gin-cli"
+
+// errNoToken is returned by credential stores that do not hold a token for a server.
+var errNoToken = fmt.Errorf("no token stored")
+
+// PassphrasePrompt is used by the encrypted file store to ask for the passphrase when the PassphraseEnvVar environment variable is not set.
+// If it is nil, the environment variable is required.
+var PassphrasePrompt func(prompt string) (string, error)
+
+// passphrase entered during the current invocation
+var cachedPassphrase string
+
+// CredentialStore is a storage backend for login tokens.
+type CredentialStore interface {
+	// Name returns the name of the store as used in the configuration.
+	Name() string
+	// Available returns an error if the store cannot be used on this system.
+	Available() error
+	// Location describes where the token for a server and profile is stored.
+	Location(profile, srvalias string) string
+	// Has returns true if the store holds a token for the server and profile.
+	Has(profile, srvalias string) bool
+	Load(profile, srvalias string, ut *UserToken) error
+	Store(profile, srvalias string, ut UserToken) error
+	Delete(profile, srvalias string) error
+}
+
+// CredentialStores returns all credential stores, in order of preference.
+func CredentialStores() []CredentialStore {
+	return []CredentialStore{keyringCredStore{}, encryptedFileCredStore{}, fileCredStore{}}
+}
+
+// GetCredentialStore returns the credential store with the given name.
+// For "auto", the keyring store is returned if it is available and the file store otherwise.
+func GetCredentialStore(name string) (CredentialStore, error) {
+	if name == AutoStore || name == "" {
+		if err := (keyringCredStore{}).Available(); err != nil {
+			log.Write("Keyring not available (%s): using file credential store", err)
+			return fileCredStore{}, nil
+		}
+		return keyringCredStore{}, nil
+	}
+	for _, store := range CredentialStores() {
+		if store.Name() == name {
+			if err := store.Available(); err != nil {
+				return nil, fmt.Errorf("credential store '%s' is not available: %s", name, err)
+			}
+			return store, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown credential store '%s'", name)
+}
+
+// SelectedCredentialStore returns the credential store selected in the configuration.
+func SelectedCredentialStore() (CredentialStore, error) {
+	return GetCredentialStore(config.Read().Credentials.Store)
+}
+
+// TokenStores returns the names of the credential stores that hold a token for the server and profile.
+func TokenStores(profile, srvalias string) []string {
+	var names []string
+	for _, store := range CredentialStores() {
+		if store.Available() == nil && store.Has(profile, srvalias) {
+			names = append(names, store.Name())
+		}
+	}
+	return names
+}
+
+// MigrateToken moves the token for a server and profile from one credential store to another.
+// It returns false if the source store does not hold a token.
+func MigrateToken(profile, srvalias string, from, to CredentialStore) (bool, error) {
+	if from.Name() == to.Name() || from.Available() != nil || !from.Has(profile, srvalias) {
+		return false, nil
+	}
+	var ut UserToken
+	if err := from.Load(profile, srvalias, &ut); err != nil {
+		return false, err
+	}
+	if err := to.Store(profile, srvalias, ut); err != nil {
+		return false, err
+	}
+	if err := from.Delete(profile, srvalias); err != nil {
+		return false, err
+	}
+	log.Write("Migrated token [server %s, profile %s] from %s to %s", srvalias, profile, from.Name(), to.Name())
+	return true, nil
+}
+
+// File store //
+
+type fileCredStore struct{}
+
+func (fileCredStore) Name() string {
+	return FileStore
+}
+
+func (fileCredStore) Available() error {
+	return nil
+}
+
+func (fileCredStore) Location(profile, srvalias string) string {
+	path, _ := config.ProfilePath(profile, false)
+	return filepath.Join(path, fmt.Sprintf("%s.token", srvalias))
+}
+
+func (s fileCredStore) Has(profile, srvalias string) bool {
+	_, err := os.Stat(s.Location(profile, srvalias))
+	return err == nil
+}
+
+func (s fileCredStore) Load(profile, srvalias string, ut *UserToken) error {
+	file, err := os.Open(s.Location(profile, srvalias))
+	if os.IsNotExist(err) {
+		return errNoToken
+	} else if err != nil {
+		return err
+	}
+	defer closeFile(file)
+	return gob.NewDecoder(file).Decode(ut)
+}
+
+func (s fileCredStore) Store(profile, srvalias string, ut UserToken) error {
+	if _, err := config.ProfilePath(profile, true); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(s.Location(profile, srvalias), os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer closeFile(file)
+	return gob.NewEncoder(file).Encode(ut)
+}
+
+func (s fileCredStore) Delete(profile, srvalias string) error {
+	return os.Remove(s.Location(profile, srvalias))
+}
+
+// Encrypted file store //
+
+type encryptedFileCredStore struct{}
+
+// encryptedToken is the content of an encrypted token file.
+type encryptedToken struct {
+	Version int
+	Salt    []byte
+	Nonce   []byte
+	Data    []byte
+}
+
+func (encryptedFileCredStore) Name() string {
+	return EncryptedFileStore
+}
+
+func (encryptedFileCredStore) Available() error {
+	return nil
+}
+
+func (encryptedFileCredStore) Location(profile, srvalias string) string {
+	path, _ := config.ProfilePath(profile, false)
+	return filepath.Join(path, fmt.Sprintf("%s.token.enc", srvalias))
+}
+
+func (s encryptedFileCredStore) Has(profile, srvalias string) bool {
+	_, err := os.Stat(s.Location(profile, srvalias))
+	return err == nil
+}
+
+// getPassphrase returns the passphrase from the environment, the passphrase entered earlier in the same invocation, or prompts for it.
+// When confirm is true, the passphrase is prompted for twice.
+func getPassphrase(confirm bool) (string, error) {
+	if passphrase := os.Getenv(PassphraseEnvVar); passphrase != "" {
+		return passphrase, nil
+	}
+	if cachedPassphrase != "" {
+		return cachedPassphrase, nil
+	}
+	if PassphrasePrompt == nil {
+		return "", fmt.Errorf("no passphrase for the encrypted credential store: set the %s environment variable", PassphraseEnvVar)
+	}
+	passphrase, err := PassphrasePrompt("Credential store passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if passphrase == "" {
+		return "", fmt.Errorf("no passphrase provided")
+	}
+	if confirm {
+		repeat, err := PassphrasePrompt("Repeat passphrase: ")
+		if err != nil {
+			return "", err
+		}
+		if repeat != passphrase {
+			return "", fmt.Errorf("passphrases do not match")
+		}
+	}
+	cachedPassphrase = passphrase
+	return passphrase, nil
+}
+
+func deriveKey(passphrase string, salt []byte) (*[32]byte, error) {
+	keydata, err := scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, err
+	}
+	var key [32]byte
+	copy(key[:], keydata)
+	return &key, nil
+}
+
+func (s encryptedFileCredStore) Load(profile, srvalias string, ut *UserToken) error {
+	data, err := ioutil.ReadFile(s.Location(profile, srvalias))
+	if os.IsNotExist(err) {
+		return errNoToken
+	} else if err != nil {
+		return err
+	}
+	var enc encryptedToken
+	if err = json.Unmarshal(data, &enc); err != nil || len(enc.Nonce) != 24 {
+		return fmt.Errorf("invalid encrypted token file")
+	}
+	passphrase, err := getPassphrase(false)
+	if err != nil {
+		return err
+	}
+	key, err := deriveKey(passphrase, enc.Salt)
+	if err != nil {
+		return err
+	}
+	var nonce [24]byte
+	copy(nonce[:], enc.Nonce)
+	plain, ok := secretbox.Open(nil, enc.Data, &nonce, key)
+	if !ok {
+		cachedPassphrase = ""
+		return fmt.Errorf("failed to decrypt token: wrong passphrase or corrupted file")
+	}
+	return json.Unmarshal(plain, ut)
+}
+
+func (s encryptedFileCredStore) Store(profile, srvalias string, ut UserToken) error {
+	if _, err := config.ProfilePath(profile, true); err != nil {
+		return err
+	}
+	passphrase, err := getPassphrase(!s.Has(profile, srvalias))
+	if err != nil {
+		return err
+	}
+	enc := encryptedToken{Version: 1, Salt: make([]byte, 16), Nonce: make([]byte, 24)}
+	if _, err = rand.Read(enc.Salt); err != nil {
+		return err
+	}
+	if _, err = rand.Read(enc.Nonce); err != nil {
+		return err
+	}
+	key, err := deriveKey(passphrase, enc.Salt)
+	if err != nil {
+		return err
+	}
+	plain, err := json.Marshal(ut)
+	if err != nil {
+		return err
+	}
+	var nonce [24]byte
+	copy(nonce[:], enc.Nonce)
+	enc.Data = secretbox.Seal(nil, plain, &nonce, key)
+	data, err := json.Marshal(enc)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(s.Location(profile, srvalias), data, 0600)
+}
+
+func (s encryptedFileCredStore) Delete(profile, srvalias string) error {
+	return os.Remove(s.Location(profile, srvalias))
+}
+
+// Keyring store //
+
+// keyringCredStore stores tokens in the system keyring using the secret-tool (Linux Secret Service) or security (macOS keychain) command line tools.
+type keyringCredStore struct{}
+
+func (keyringCredStore) Name() string {
+	return KeyringStore
+}
+
+func (keyringCredStore) tool() string {
+	if runtime.GOOS == "darwin" {
+		return "security"
+	}
+	return "secret-tool"
+}
+
+func (s keyringCredStore) Available() error {
+	if runtime.GOOS == "windows" {
+		return fmt.Errorf("not supported on Windows")
+	}
+	if _, err := exec.LookPath(s.tool()); err != nil {
+		return fmt.Errorf("%s not found", s.tool())
+	}
+	if runtime.GOOS != "darwin" && os.Getenv("DBUS_SESSION_BUS_ADDRESS") == "" {
+		return fmt.Errorf("no session bus for the Secret Service")
+	}
+	return nil
+}
+
+func keyringAccount(profile, srvalias string) string {
+	return fmt.Sprintf("%s/%s", profile, srvalias)
+}
+
+// attributes returns the secret-tool attributes that identify a token.
+func keyringAttributes(profile, srvalias string) []string {
+	return []string{"service", keyringService, "profile", profile, "server", srvalias}
+}
+
+func (s keyringCredStore) Location(profile, srvalias string) string {
+	if runtime.GOOS == "darwin" {
+		return fmt.Sprintf("keychain (service %s, account %s)", keyringService, keyringAccount(profile, srvalias))
+	}
+	return fmt.Sprintf("Secret Service (%s)", strings.Join(keyringAttributes(profile, srvalias), " "))
+}
+
+// securityQuote quotes a string for a command line in the interactive mode of the security tool.
+func securityQuote(str string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(str) + `"`
+}
+
+// run runs the keyring tool with the given input and returns its output.
+func (s keyringCredStore) run(input string, args ...string) (string, error) {
+	cmd := exec.Command(s.tool(), args...)
+	cmd.Stdin = strings.NewReader(input)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if err != nil {
+		log.Write("%s failed: %s: %s", s.tool(), err, stderr.String())
+	}
+	return stdout.String(), err
+}
+
+func (s keyringCredStore) lookup(profile, srvalias string) (string, error) {
+	var args []string
+	if runtime.GOOS == "darwin" {
+		args = []string{"find-generic-password", "-s", keyringService, "-a", keyringAccount(profile, srvalias), "-w"}
+	} else {
+		args = append([]string{"lookup"}, keyringAttributes(profile, srvalias)...)
+	}
+	out, err := s.run("", args...)
+	out = strings.TrimSpace(out)
+	if err != nil || out == "" {
+		// both tools exit with an error if the item does not exist
+		return "", errNoToken
+	}
+	return out, nil
+}
+
+func (s keyringCredStore) Has(profile, srvalias string) bool {
+	_, err := s.lookup(profile, srvalias)
+	return err == nil
+}
+
+func (s keyringCredStore) Load(profile, srvalias string, ut *UserToken) error {
+	secret, err := s.lookup(profile, srvalias)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(secret), ut)
+}
+
+func (s keyringCredStore) Store(profile, srvalias string, ut UserToken) error {
+	secret, err := json.Marshal(ut)
+	if err != nil {
+		return err
+	}
+	if runtime.GOOS == "darwin" {
+		// the security tool only accepts the password as an argument, which would expose it in the process list, or interactively;
+		// the command is read from standard input instead, with the password in hexadecimal form to avoid quoting it
+		account := keyringAccount(profile, srvalias)
+		command := strings.Join([]string{"add-generic-password", "-U", "-s", securityQuote(keyringService), "-a", securityQuote(account), "-l", securityQuote(fmt.Sprintf("GIN token (%s)", account)), "-X", hex.EncodeToString(secret)}, " ")
+		if _, err = s.run(command+"\n", "-i"); err == nil {
+			// interactive mode does not report failed commands in its exit status
+			if stored, lerr := s.lookup(profile, srvalias); lerr != nil || stored != string(secret) {
+				err = fmt.Errorf("token not found in keychain after storing it")
+				log.Write("%s", err)
+			}
+		}
+	} else {
+		label := fmt.Sprintf("--label=GIN token for %s (profile %s)", srvalias, profile)
+		_, err = s.run(string(secret), append([]string{"store", label}, keyringAttributes(profile, srvalias)...)...)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to store token in the keyring")
+	}
+	return nil
+}
+
+func (s keyringCredStore) Delete(profile, srvalias string) error {
+	var err error
+	if runtime.GOOS == "darwin" {
+		_, err = s.run("", "delete-generic-password", "-s", keyringService, "-a", keyringAccount(profile, srvalias))
+	} else {
+		_, err = s.run("", append([]string{"clear"}, keyringAttributes(profile, srvalias)...)...)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete token from the keyring")
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package web
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCredentialStores(t *testing.T) {
+	confdir, err := ioutil.TempDir("", "gin-credentials-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(confdir)
+	os.Setenv("GIN_CONFIG_DIR", confdir)
+	defer os.Unsetenv("GIN_CONFIG_DIR")
+	os.Setenv(PassphraseEnvVar, "correct horse")
+	defer os.Unsetenv(PassphraseEnvVar)
+
+	token := UserToken{Username: "alice", Token: "0123456789abcdef"}
+	plain, enc := fileCredStore{}, encryptedFileCredStore{}
+	if err := plain.Store("default", "gin", token); err != nil {
+		t.Fatalf("failed to store token file: %v", err)
+	}
+	if !plain.Has("default", "gin") || plain.Has("bot", "gin") {
+		t.Fatal("token file not stored for the right profile")
+	}
+
+	ok, err := MigrateToken("default", "gin", plain, enc)
+	if err != nil || !ok {
+		t.Fatalf("migration failed: %v", err)
+	}
+	if plain.Has("default", "gin") {
+		t.Error("token file not removed after migration")
+	}
+	data, err := ioutil.ReadFile(enc.Location("default", "gin"))
+	if err != nil {
+		t.Fatalf("encrypted token file missing: %v", err)
+	}
+	if strings.Contains(string(data), token.Token) {
+		t.Error("token stored in plain text")
+	}
+
+	var loaded UserToken
+	if err := enc.Load("default", "gin", &loaded); err != nil {
+		t.Fatalf("failed to load encrypted token: %v", err)
+	}
+	if loaded != token {
+		t.Errorf("wrong token loaded: %+v", loaded)
+	}
+
+	os.Setenv(PassphraseEnvVar, "wrong")
+	if err := enc.Load("default", "gin", &loaded); err == nil {
+		t.Error("expected error for wrong passphrase")
+	}
+}
+
+func TestSecurityQuote(t *testing.T) {
+	cases := map[string]string{
+		"gin-cli":         `"gin-cli"`,
+		"default/gin":     `"default/gin"`,
+		"GIN token (a b)": `"GIN token (a b)"`,
+		`say "hi"`:        `"say \"hi\""`,
+		`C:\path`:         `"C:\\path"`,
+		"$HOME `whoami`":  "\"$HOME `whoami`\"",
+	}
+	for str, expected := range cases {
+		if quoted := securityQuote(str); quoted != expected {
+			t.Errorf("securityQuote(%q): expected %s, got %s", str, expected, quoted)
+		}
+	}
+}
@@ -7,7 +7,6 @@ package web
 
 import (
 	"bytes"
-	"encoding/gob"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -15,8 +14,8 @@ import (
 	"net/url"
 	"os"
 	"path"
-	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/G-Node/gin-cli/ginclient/config"
 	"github.com/G-Node/gin-cli/ginclient/log"
@@ -31,6 +30,8 @@ type weberror = shell.Error
 type UserToken struct {
 	Username string
 	Token    string
+	// Stored is the time the token was first stored (on login). It is zero for tokens stored by earlier versions.
+	Stored time.Time
 }
 
 // Client struct for making requests
@@ -115,6 +116,58 @@ func (cl *Client) Post(address string, data interface{}) (*http.Response, error)
 	return resp, err
 }
 
+// Put sends a PUT request to address with the provided data.
+// The address is appended to the client host, so it should be specified without a host prefix.
+func (cl *Client) Put(address string, data interface{}) (*http.Response, error) {
+	fn := fmt.Sprintf("Put(%s, <data>)", address)
+	datajson, err := json.Marshal(data)
+	if err != nil {
+		return nil, weberror{UError: err.Error(), Origin: fn}
+	}
+	requrl := urlJoin(cl.Host, address)
+	req, err := http.NewRequest("PUT", requrl, bytes.NewReader(datajson))
+	if err != nil {
+		return nil, weberror{UError: err.Error(), Origin: fn}
+	}
+	req.Header.Set("content-type", "application/json")
+	if cl.Token != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("token %s", cl.Token))
+		log.Write("Added token to PUT")
+	}
+	log.Write("Performing PUT: %s", req.URL)
+	resp, err := cl.web.Do(req)
+	if err != nil {
+		err = weberror{UError: err.Error(), Origin: fn, Description: parseServerError(err)}
+	}
+	return resp, err
+}
+
+// Patch sends a PATCH request to address with the provided data.
+// The address is appended to the client host, so it should be specified without a host prefix.
+func (cl *Client) Patch(address string, data interface{}) (*http.Response, error) {
+	fn := fmt.Sprintf("Patch(%s, <data>)", address)
+	datajson, err := json.Marshal(data)
+	if err != nil {
+		return nil, weberror{UError: err.Error(), Origin: fn}
+	}
+	requrl := urlJoin(cl.Host, address)
+	req, err := http.NewRequest("PATCH", requrl, bytes.NewReader(datajson))
+	if err != nil {
+		return nil, weberror{UError: err.Error(), Origin: fn}
+	}
+	req.Header.Set("content-type", "application/json")
+	if cl.Token != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("token %s", cl.Token))
+		log.Write("Added token to PATCH")
+	}
+	log.Write("Performing PATCH: %s", req.URL)
+	resp, err := cl.web.Do(req)
+	if err != nil {
+		err = weberror{UError: err.Error(), Origin: fn, Description: parseServerError(err)}
+	}
+	return resp, err
+}
+
 // GetBasicAuth sends a GET request to address.
 // The username and password are used to perform Basic authentication.
 func (cl *Client) GetBasicAuth(address, username, password string) (*http.Response, error) {
@@ -183,68 +236,68 @@ func New(host string) *Client {
 	return &Client{Host: host, web: &http.Client{}}
 }
 
-// LoadToken reads the username and auth token from the token file and sets the
+// LoadToken reads the username and auth token of the active profile from the selected credential store and sets the
 // values in the struct.
+// Tokens found in other credential stores (e.g., unencrypted token files of earlier versions) are migrated to the selected store.
 func (ut *UserToken) LoadToken(srvalias string) error {
 	fn := fmt.Sprintf("LoadToken(%s)", srvalias)
 	if ut.Username != "" && ut.Token != "" {
 		return nil
 	}
-	path, _ := config.Path(false) // Error can only occur when create=True
-	filename := fmt.Sprintf("%s.token", srvalias)
-	filepath := filepath.Join(path, filename)
-	log.Write("Loading token [server %s] %s", srvalias, filepath)
-	file, err := os.Open(filepath)
+	store, err := SelectedCredentialStore()
 	if err != nil {
-		log.Write("Failed to load")
 		return weberror{UError: err.Error(), Origin: fn, Description: "failed to load user token"}
 	}
-	defer closeFile(file)
-
-	decoder := gob.NewDecoder(file)
-	err = decoder.Decode(ut)
+	profile, _ := config.ActiveProfile()
+	log.Write("Loading token [server %s, profile %s] %s", srvalias, profile, store.Location(profile, srvalias))
+	// token files of earlier versions
+	if _, merr := MigrateToken(profile, srvalias, fileCredStore{}, store); merr != nil {
+		log.Write("Failed to migrate token file: %s", merr)
+	}
+	err = store.Load(profile, srvalias, ut)
 	if err != nil {
-		log.Write("Failed to parse")
-		return weberror{UError: err.Error(), Origin: fn, Description: "failed to parse user token"}
+		log.Write("Failed to load")
+		return weberror{UError: err.Error(), Origin: fn, Description: "failed to load user token"}
 	}
 	return nil
 }
 
-// StoreToken saves the username and auth token to the token file.
+// StoreToken saves the username and auth token of the active profile to the selected credential store.
 func (ut *UserToken) StoreToken(srvalias string) error {
 	fn := fmt.Sprintf("StoreToken(%s)", srvalias)
-	path, err := config.Path(true)
+	store, err := SelectedCredentialStore()
 	if err != nil {
-		return weberror{UError: err.Error(), Origin: fn}
+		return weberror{UError: err.Error(), Origin: fn, Description: "failed to store token"}
 	}
-	filename := fmt.Sprintf("%s.token", srvalias)
-	filepath := filepath.Join(path, filename)
-	log.Write("Saving token [server %s] %s", srvalias, filepath)
-	file, err := os.Create(filepath)
-	if err != nil {
-		log.Write("Failed to create token file %s", filepath)
-		return weberror{UError: err.Error(), Origin: fn, Description: fmt.Sprintf("failed to create token file %s", filepath)}
+	profile, _ := config.ActiveProfile()
+	log.Write("Saving token [server %s, profile %s] %s", srvalias, profile, store.Location(profile, srvalias))
+	if ut.Stored.IsZero() {
+		ut.Stored = time.Now().UTC()
 	}
-	defer closeFile(file)
-
-	encoder := gob.NewEncoder(file)
-	err = encoder.Encode(ut)
+	err = store.Store(profile, srvalias, *ut)
 	if err != nil {
-		log.Write("Failed to write token to file %s", filepath)
-		return weberror{UError: err.Error(), Origin: fn, Description: "failed to store token"}
+		log.Write("Failed to store token")
+		return weberror{UError: err.Error(), Origin: fn, Description: fmt.Sprintf("failed to store token in %s", store.Location(profile, srvalias))}
 	}
 	log.Write("Saved")
 	return nil
 }
 
-// DeleteToken deletes the token file if it exists (for finalising a logout).
+// DeleteToken deletes the token of the active profile from all credential stores that hold it (for finalising a logout).
 func DeleteToken(srvalias string) error {
-	path, _ := config.Path(false) // Error can only occur when create=True
-	filename := fmt.Sprintf("%s.token", srvalias)
-	tokenpath := filepath.Join(path, filename)
-	err := os.Remove(tokenpath)
-	if err != nil {
-		return weberror{UError: err.Error(), Origin: "DeleteToken()", Description: "could not delete token"}
+	profile, _ := config.ActiveProfile()
+	deleted := false
+	for _, store := range CredentialStores() {
+		if store.Available() != nil || !store.Has(profile, srvalias) {
+			continue
+		}
+		if err := store.Delete(profile, srvalias); err != nil {
+			return weberror{UError: err.Error(), Origin: "DeleteToken()", Description: "could not delete token"}
+		}
+		deleted = true
+	}
+	if !deleted {
+		return weberror{UError: errNoToken.Error(), Origin: "DeleteToken()", Description: "could not delete token"}
 	}
 	log.Write("Token deleted")
 	return nil